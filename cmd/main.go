@@ -34,6 +34,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -89,6 +90,19 @@ const (
 //+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=debuggingconfigurations,verbs=get;list;watch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterconfigurations,verbs=get;list;watch
 
+// Add RBAC to read the kubeconfig Secrets used to access managed clusters. Secrets are never cached.
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Add RBAC to read the Roles/ClusterRoles referenced by RoleRequests. ConfigMaps are never cached.
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+
+// Add RBAC to store profile change requests
+//+kubebuilder:rbac:groups="",namespace=projectsveltos,resources=configmaps,verbs=get;list;create;update
+
+// Add RBAC to act on behalf of the calling user (for instance to patch cluster labels)
+//+kubebuilder:rbac:groups="",resources=users;groups;serviceaccounts,verbs=impersonate
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=uids;userextras,verbs=impersonate
+
 func main() {
 	scheme, err := controller.InitScheme()
 	if err != nil {
@@ -114,6 +128,13 @@ func main() {
 		Cache: cache.Options{
 			SyncPeriod: &syncPeriod,
 		},
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// Read those directly from the API server. Caching would keep every Secret/ConfigMap
+				// of the management cluster in memory.
+				DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
+			},
+		},
 		PprofBindAddress: profilerAddress,
 	}
 
@@ -139,8 +160,21 @@ func main() {
 
 	startSveltosClusterController(mgr)
 	startClusterSummaryController(mgr)
+	startClusterReportController(mgr)
 	startClusterProfileController(mgr)
 	startProfileController(mgr)
+	startResourceSummaryController(mgr)
+	startEventSourceController(mgr)
+	startEventTriggerController(mgr)
+	startEventReportController(mgr)
+	startClassifierController(mgr)
+	startClassifierReportController(mgr)
+	startClusterHealthCheckController(mgr)
+	startHealthCheckController(mgr)
+	startHealthCheckReportController(mgr)
+	startRoleRequestController(mgr)
+	startClusterSetController(mgr)
+	startSetController(mgr)
 	//+kubebuilder:scaffold:builder
 
 	setupChecks(mgr)
@@ -336,6 +370,23 @@ func getClusterSummaryReconciler(mgr manager.Manager) *controller.ClusterSummary
 	}
 }
 
+func startClusterReportController(mgr manager.Manager) {
+	clusterReportReconciler := getClusterReportReconciler(mgr)
+	err := clusterReportReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterReport")
+		os.Exit(1)
+	}
+}
+
+func getClusterReportReconciler(mgr manager.Manager) *controller.ClusterReportReconciler {
+	return &controller.ClusterReportReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
 func startClusterProfileController(mgr manager.Manager) {
 	clusterProfileReconciler := getClusterProfileReconciler(mgr)
 	err := clusterProfileReconciler.SetupWithManager(mgr)
@@ -369,3 +420,207 @@ func getProfileReconciler(mgr manager.Manager) *controller.ProfileReconciler {
 		ConcurrentReconciles: concurrentReconciles,
 	}
 }
+
+func startResourceSummaryController(mgr manager.Manager) {
+	resourceSummaryReconciler := getResourceSummaryReconciler(mgr)
+	err := resourceSummaryReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ResourceSummary")
+		os.Exit(1)
+	}
+}
+
+func getResourceSummaryReconciler(mgr manager.Manager) *controller.ResourceSummaryReconciler {
+	return &controller.ResourceSummaryReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startEventSourceController(mgr manager.Manager) {
+	eventSourceReconciler := getEventSourceReconciler(mgr)
+	err := eventSourceReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EventSource")
+		os.Exit(1)
+	}
+}
+
+func getEventSourceReconciler(mgr manager.Manager) *controller.EventSourceReconciler {
+	return &controller.EventSourceReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startEventTriggerController(mgr manager.Manager) {
+	eventTriggerReconciler := getEventTriggerReconciler(mgr)
+	err := eventTriggerReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EventTrigger")
+		os.Exit(1)
+	}
+}
+
+func getEventTriggerReconciler(mgr manager.Manager) *controller.EventTriggerReconciler {
+	return &controller.EventTriggerReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startEventReportController(mgr manager.Manager) {
+	eventReportReconciler := getEventReportReconciler(mgr)
+	err := eventReportReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EventReport")
+		os.Exit(1)
+	}
+}
+
+func getEventReportReconciler(mgr manager.Manager) *controller.EventReportReconciler {
+	return &controller.EventReportReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startClassifierController(mgr manager.Manager) {
+	classifierReconciler := getClassifierReconciler(mgr)
+	err := classifierReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Classifier")
+		os.Exit(1)
+	}
+}
+
+func getClassifierReconciler(mgr manager.Manager) *controller.ClassifierReconciler {
+	return &controller.ClassifierReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startClassifierReportController(mgr manager.Manager) {
+	classifierReportReconciler := getClassifierReportReconciler(mgr)
+	err := classifierReportReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClassifierReport")
+		os.Exit(1)
+	}
+}
+
+func getClassifierReportReconciler(mgr manager.Manager) *controller.ClassifierReportReconciler {
+	return &controller.ClassifierReportReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startClusterHealthCheckController(mgr manager.Manager) {
+	clusterHealthCheckReconciler := getClusterHealthCheckReconciler(mgr)
+	err := clusterHealthCheckReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterHealthCheck")
+		os.Exit(1)
+	}
+}
+
+func getClusterHealthCheckReconciler(mgr manager.Manager) *controller.ClusterHealthCheckReconciler {
+	return &controller.ClusterHealthCheckReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startHealthCheckController(mgr manager.Manager) {
+	healthCheckReconciler := getHealthCheckReconciler(mgr)
+	err := healthCheckReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HealthCheck")
+		os.Exit(1)
+	}
+}
+
+func getHealthCheckReconciler(mgr manager.Manager) *controller.HealthCheckReconciler {
+	return &controller.HealthCheckReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startHealthCheckReportController(mgr manager.Manager) {
+	healthCheckReportReconciler := getHealthCheckReportReconciler(mgr)
+	err := healthCheckReportReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HealthCheckReport")
+		os.Exit(1)
+	}
+}
+
+func getHealthCheckReportReconciler(mgr manager.Manager) *controller.HealthCheckReportReconciler {
+	return &controller.HealthCheckReportReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startRoleRequestController(mgr manager.Manager) {
+	roleRequestReconciler := getRoleRequestReconciler(mgr)
+	err := roleRequestReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RoleRequest")
+		os.Exit(1)
+	}
+}
+
+func getRoleRequestReconciler(mgr manager.Manager) *controller.RoleRequestReconciler {
+	return &controller.RoleRequestReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startClusterSetController(mgr manager.Manager) {
+	clusterSetReconciler := getClusterSetReconciler(mgr)
+	err := clusterSetReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterSet")
+		os.Exit(1)
+	}
+}
+
+func getClusterSetReconciler(mgr manager.Manager) *controller.ClusterSetReconciler {
+	return &controller.ClusterSetReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
+
+func startSetController(mgr manager.Manager) {
+	setReconciler := getSetReconciler(mgr)
+	err := setReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Set")
+		os.Exit(1)
+	}
+}
+
+func getSetReconciler(mgr manager.Manager) *controller.SetReconciler {
+	return &controller.SetReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		ConcurrentReconciles: concurrentReconciles,
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// ClassifierReconciler reconciles a Classifier object
+type ClassifierReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=classifiers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=classifiers/status,verbs=get;list;watch
+
+func (r *ClassifierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	classifier := &libsveltosv1beta1.Classifier{}
+	if err := r.Get(ctx, req.NamespacedName, classifier); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeClassifier(req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch Classifier")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch Classifier %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted Classifier
+	if !classifier.DeletionTimestamp.IsZero() {
+		r.removeClassifier(classifier.Name, logger)
+	} else {
+		// Handle non-deleted Classifier
+		r.reconcileNormal(classifier, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *ClassifierReconciler) removeClassifier(classifierName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling Classifier delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveClassifier(classifierName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *ClassifierReconciler) reconcileNormal(classifier *libsveltosv1beta1.Classifier, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new Classifier")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddClassifier(classifier)
+
+	logger.V(logs.LogInfo).Info("Reconciling new Classifier success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClassifierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.Classifier{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
@@ -18,16 +18,26 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	"github.com/projectsveltos/ui-backend/internal/server"
@@ -38,10 +48,23 @@ type ClusterReconciler struct {
 	client.Client
 	Scheme               *runtime.Scheme
 	ConcurrentReconciles int
+
+	// controller and cache are used to watch control plane kinds. Those are known only
+	// once a Cluster referencing them is reconciled.
+	controller controller.Controller
+	cache      cache.Cache
+
+	// watchedControlPlanes contains the control plane GroupKinds already watched
+	watchedControlPlanes sync.Map
 }
 
-//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=*,verbs=get;list;watch
 
 func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := ctrl.LoggerFrom(ctx)
@@ -67,7 +90,7 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		r.removeCAPICluster(cluster.Namespace, cluster.Name, logger)
 	} else {
 		// Handle non-deleted cluster
-		r.reconcileNormal(cluster, logger)
+		r.reconcileNormal(ctx, cluster, logger)
 	}
 
 	return reconcile.Result{}, nil
@@ -83,22 +106,152 @@ func (r *ClusterReconciler) removeCAPICluster(clusterNamespace, clusterName stri
 	logger.V(logs.LogInfo).Info("Reconcile delete success")
 }
 
-func (r *ClusterReconciler) reconcileNormal(cluster *clusterv1.Cluster, logger logr.Logger) {
+func (r *ClusterReconciler) reconcileNormal(ctx context.Context, cluster *clusterv1.Cluster, logger logr.Logger) {
 	logger.V(logs.LogInfo).Info("Reconciling Cluster normal")
 
 	manager := server.GetManagerInstance()
 
-	manager.AddCAPICluster(cluster)
+	manager.AddCAPICluster(cluster, r.getCAPIClusterResources(ctx, cluster, logger))
 
 	logger.V(logs.LogInfo).Info("Reconcile normal success")
 }
 
+// getCAPIClusterResources collects control plane, MachineDeployments and MachinePools of a cluster.
+// Failures are logged and the corresponding information is simply not reported.
+func (r *ClusterReconciler) getCAPIClusterResources(ctx context.Context, cluster *clusterv1.Cluster,
+	logger logr.Logger) *server.CAPIClusterResources {
+
+	resources := &server.CAPIClusterResources{}
+
+	if ref := cluster.Spec.ControlPlaneRef; ref != nil {
+		r.watchControlPlane(ref.GroupVersionKind(), logger)
+
+		controlPlane := &unstructured.Unstructured{}
+		controlPlane.SetAPIVersion(ref.APIVersion)
+		controlPlane.SetKind(ref.Kind)
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = cluster.Namespace
+		}
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, controlPlane)
+		if err != nil {
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to get control plane %s %s/%s: %v",
+				ref.Kind, namespace, ref.Name, err))
+		} else {
+			resources.ControlPlane = controlPlane
+		}
+	}
+
+	listOptions := []client.ListOption{
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: cluster.Name},
+	}
+
+	machineDeployments := &clusterv1.MachineDeploymentList{}
+	if err := r.List(ctx, machineDeployments, listOptions...); err != nil {
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to list MachineDeployments: %v", err))
+	} else {
+		resources.MachineDeployments = machineDeployments.Items
+	}
+
+	machinePools := &expv1.MachinePoolList{}
+	if err := r.List(ctx, machinePools, listOptions...); err != nil {
+		// NoMatch error means MachinePool is not installed
+		if !meta.IsNoMatchError(err) {
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to list MachinePools: %v", err))
+		}
+	} else {
+		resources.MachinePools = machinePools.Items
+	}
+
+	return resources
+}
+
+// watchControlPlane starts watching the control plane kind, if not watched already, so that
+// control plane changes are reported without waiting for the Cluster to change
+func (r *ClusterReconciler) watchControlPlane(gvk schema.GroupVersionKind, logger logr.Logger) {
+	if r.controller == nil {
+		return
+	}
+
+	if _, watched := r.watchedControlPlanes.LoadOrStore(gvk.GroupKind(), true); watched {
+		return
+	}
+
+	controlPlane := &unstructured.Unstructured{}
+	controlPlane.SetGroupVersionKind(gvk)
+	err := r.controller.Watch(source.Kind[client.Object](r.cache, controlPlane,
+		handler.EnqueueRequestsFromMapFunc(r.requeueClusterForControlPlane)))
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to watch %s: %v", gvk.String(), err))
+		r.watchedControlPlanes.Delete(gvk.GroupKind())
+	}
+}
+
+// requeueClusterForControlPlane returns the Cluster owning a control plane
+func (r *ClusterReconciler) requeueClusterForControlPlane(
+	ctx context.Context, o client.Object,
+) []reconcile.Request {
+
+	for _, ownerRef := range o.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+		if err != nil {
+			continue
+		}
+		if ownerRef.Kind == clusterv1.ClusterKind && gv.Group == clusterv1.GroupVersion.Group {
+			return []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: ownerRef.Name}},
+			}
+		}
+	}
+
+	return r.requeueClusterForMachineResource(ctx, o)
+}
+
+// requeueClusterForMachineResource returns the Cluster a MachineDeployment/MachinePool belongs to
+func (r *ClusterReconciler) requeueClusterForMachineResource(
+	ctx context.Context, o client.Object,
+) []reconcile.Request {
+
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: clusterName}},
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&clusterv1.Cluster{}).
+		Watches(&clusterv1.MachineDeployment{},
+			handler.EnqueueRequestsFromMapFunc(r.requeueClusterForMachineResource))
+
+	// MachinePool is an experimental CAPI feature and its CRD might not be installed
+	_, err := mgr.GetRESTMapper().RESTMapping(expv1.GroupVersion.WithKind("MachinePool").GroupKind(),
+		expv1.GroupVersion.Version)
+	if err != nil {
+		if !meta.IsNoMatchError(err) {
+			return err
+		}
+	} else {
+		builder = builder.Watches(&expv1.MachinePool{},
+			handler.EnqueueRequestsFromMapFunc(r.requeueClusterForMachineResource))
+	}
+
+	c, err := builder.
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
 		}).
-		Complete(r)
+		Build(r)
+	if err != nil {
+		return err
+	}
+
+	r.controller = c
+	r.cache = mgr.GetCache()
+	return nil
 }
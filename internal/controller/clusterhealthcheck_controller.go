@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// ClusterHealthCheckReconciler reconciles a ClusterHealthCheck object
+type ClusterHealthCheckReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=clusterhealthchecks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=clusterhealthchecks/status,verbs=get;list;watch
+
+func (r *ClusterHealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	clusterHealthCheck := &libsveltosv1beta1.ClusterHealthCheck{}
+	if err := r.Get(ctx, req.NamespacedName, clusterHealthCheck); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeClusterHealthCheck(req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch ClusterHealthCheck")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch ClusterHealthCheck %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted ClusterHealthCheck
+	if !clusterHealthCheck.DeletionTimestamp.IsZero() {
+		r.removeClusterHealthCheck(clusterHealthCheck.Name, logger)
+	} else {
+		// Handle non-deleted ClusterHealthCheck
+		r.reconcileNormal(clusterHealthCheck, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *ClusterHealthCheckReconciler) removeClusterHealthCheck(clusterHealthCheckName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling ClusterHealthCheck delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveClusterHealthCheck(clusterHealthCheckName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *ClusterHealthCheckReconciler) reconcileNormal(clusterHealthCheck *libsveltosv1beta1.ClusterHealthCheck, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new ClusterHealthCheck")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddClusterHealthCheck(clusterHealthCheck)
+
+	logger.V(logs.LogInfo).Info("Reconciling new ClusterHealthCheck success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterHealthCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.ClusterHealthCheck{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
@@ -42,7 +42,7 @@ type ClusterProfileReconciler struct {
 	ConcurrentReconciles int
 }
 
-//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles,verbs=get;list;patch;watch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles/status,verbs=get;list;watch
 
 func (r *ClusterProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -111,7 +111,9 @@ func (r *ClusterProfileReconciler) reconcileNormal(clusterProfile *configv1beta1
 		})
 	}
 
-	manager.AddProfile(profileRef, clusterProfile.Spec.ClusterSelector, clusterProfile.Spec.Tier, dependencies)
+	manager.AddProfile(profileRef, clusterProfile.Spec.ClusterSelector, clusterProfile.Spec.Tier, dependencies,
+		server.GetProfileProvenance(clusterProfile))
+	manager.AddProfileRevision(profileRef, clusterProfile.Generation, &clusterProfile.Spec, clusterProfile.ManagedFields)
 
 	logger.V(logs.LogInfo).Info("Reconcile normal success")
 }
@@ -0,0 +1,109 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// ClusterReportReconciler reconciles a ClusterReport object. ClusterReports are created for
+// ClusterProfiles/Profiles in DryRun mode and list the changes a deployment would make.
+type ClusterReportReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports/status,verbs=get;list;watch
+
+func (r *ClusterReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	clusterReport := &configv1beta1.ClusterReport{}
+	if err := r.Get(ctx, req.NamespacedName, clusterReport); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeClusterReport(req.Namespace, req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch ClusterReport")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch ClusterReport %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted ClusterReport
+	if !clusterReport.DeletionTimestamp.IsZero() {
+		r.removeClusterReport(clusterReport.Namespace, clusterReport.Name, logger)
+	} else {
+		// Handle non-deleted ClusterReport
+		r.reconcileNormal(clusterReport, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *ClusterReportReconciler) removeClusterReport(clusterReportNamespace, clusterReportName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling ClusterReport delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveClusterReport(clusterReportNamespace, clusterReportName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *ClusterReportReconciler) reconcileNormal(clusterReport *configv1beta1.ClusterReport, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new ClusterReport")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddClusterReport(clusterReport)
+
+	logger.V(logs.LogInfo).Info("Reconciling new ClusterReport success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&configv1beta1.ClusterReport{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
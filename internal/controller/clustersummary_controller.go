@@ -23,7 +23,7 @@ type ClusterSummaryReconciler struct {
 	ConcurrentReconciles int
 }
 
-//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;patch;watch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries/status,verbs=get;list;watch
 
 func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -61,6 +61,7 @@ func (r *ClusterSummaryReconciler) removeClusterSummary(clusterSummaryNamespace,
 	manager := server.GetManagerInstance()
 
 	manager.RemoveClusterProfileStatus(clusterSummaryNamespace, clusterSummaryName)
+	manager.RemoveResourceSummary(clusterSummaryNamespace, clusterSummaryName)
 
 	logger.V(logs.LogInfo).Info("Reconcile delete success")
 }
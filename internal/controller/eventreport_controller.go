@@ -0,0 +1,109 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// EventReportReconciler reconciles an EventReport object. EventReports are collected by
+// sveltos-agent and list the resources matching an EventSource in a managed cluster.
+type EventReportReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=eventreports,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=eventreports/status,verbs=get;list;watch
+
+func (r *EventReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	eventReport := &libsveltosv1beta1.EventReport{}
+	if err := r.Get(ctx, req.NamespacedName, eventReport); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeEventReport(req.Namespace, req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch EventReport")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch EventReport %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted EventReport
+	if !eventReport.DeletionTimestamp.IsZero() {
+		r.removeEventReport(eventReport.Namespace, eventReport.Name, logger)
+	} else {
+		// Handle non-deleted EventReport
+		r.reconcileNormal(eventReport, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *EventReportReconciler) removeEventReport(eventReportNamespace, eventReportName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling EventReport delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveEventReport(eventReportNamespace, eventReportName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *EventReportReconciler) reconcileNormal(eventReport *libsveltosv1beta1.EventReport, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new EventReport")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddEventReport(eventReport)
+
+	logger.V(logs.LogInfo).Info("Reconciling new EventReport success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EventReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.EventReport{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
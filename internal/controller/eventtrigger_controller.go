@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// EventTriggerReconciler reconciles an EventTrigger object
+type EventTriggerReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=eventtriggers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=eventtriggers/status,verbs=get;list;watch
+
+func (r *EventTriggerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	eventTrigger := &eventv1beta1.EventTrigger{}
+	if err := r.Get(ctx, req.NamespacedName, eventTrigger); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeEventTrigger(req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch EventTrigger")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch EventTrigger %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted EventTrigger
+	if !eventTrigger.DeletionTimestamp.IsZero() {
+		r.removeEventTrigger(eventTrigger.Name, logger)
+	} else {
+		// Handle non-deleted EventTrigger
+		r.reconcileNormal(eventTrigger, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *EventTriggerReconciler) removeEventTrigger(eventTriggerName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling EventTrigger delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveEventTrigger(eventTriggerName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *EventTriggerReconciler) reconcileNormal(eventTrigger *eventv1beta1.EventTrigger, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new EventTrigger")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddEventTrigger(eventTrigger)
+
+	logger.V(logs.LogInfo).Info("Reconciling new EventTrigger success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EventTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&eventv1beta1.EventTrigger{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
@@ -42,7 +42,7 @@ type ProfileReconciler struct {
 	ConcurrentReconciles int
 }
 
-//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles,verbs=get;list;patch;watch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles/status,verbs=get;list;watch
 
 func (r *ProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -113,7 +113,9 @@ func (r *ProfileReconciler) reconcileNormal(profile *configv1beta1.Profile, logg
 		})
 	}
 
-	manager.AddProfile(profileRef, profile.Spec.ClusterSelector, profile.Spec.Tier, dependencies)
+	manager.AddProfile(profileRef, profile.Spec.ClusterSelector, profile.Spec.Tier, dependencies,
+		server.GetProfileProvenance(profile))
+	manager.AddProfileRevision(profileRef, profile.Generation, &profile.Spec, profile.ManagedFields)
 
 	logger.V(logs.LogInfo).Info("Reconcile normal success")
 }
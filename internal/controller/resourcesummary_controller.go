@@ -0,0 +1,114 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// ResourceSummaryReconciler reconciles ResourceSummary instances present in the management
+// cluster. Those are created when drift-detection-manager runs in the management cluster (agentless mode).
+type ResourceSummaryReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=resourcesummaries,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=resourcesummaries/status,verbs=get;list;watch
+
+func (r *ResourceSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	resourceSummary := &libsveltosv1beta1.ResourceSummary{}
+	if err := r.Get(ctx, req.NamespacedName, resourceSummary); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeResourceSummary(req.Namespace, req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch ResourceSummary")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch ResourceSummary %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted ResourceSummary
+	if !resourceSummary.DeletionTimestamp.IsZero() {
+		r.removeResourceSummary(resourceSummary.Namespace, resourceSummary.Name, logger)
+	} else {
+		// Handle non-deleted ResourceSummary
+		r.reconcileNormal(resourceSummary, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *ResourceSummaryReconciler) removeResourceSummary(resourceSummaryNamespace, resourceSummaryName string,
+	logger logr.Logger) {
+
+	logger.V(logs.LogInfo).Info("Reconciling ResourceSummary delete")
+
+	manager := server.GetManagerInstance()
+
+	// In the management cluster, ResourceSummary has same namespace/name of the ClusterSummary
+	manager.RemoveResourceSummary(resourceSummaryNamespace, resourceSummaryName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *ResourceSummaryReconciler) reconcileNormal(resourceSummary *libsveltosv1beta1.ResourceSummary,
+	logger logr.Logger) {
+
+	logger.V(logs.LogInfo).Info("Reconciling ResourceSummary")
+
+	manager := server.GetManagerInstance()
+
+	manager.UpdateResourceSummary(resourceSummary)
+
+	logger.V(logs.LogInfo).Info("Reconciling ResourceSummary success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ResourceSummaryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.ResourceSummary{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
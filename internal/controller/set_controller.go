@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+// SetReconciler reconciles a Set object
+type SetReconciler struct {
+	client.Client
+	Scheme               *runtime.Scheme
+	ConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=sets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=sets/status,verbs=get;list;watch
+
+func (r *SetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	set := &libsveltosv1beta1.Set{}
+	if err := r.Get(ctx, req.NamespacedName, set); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.removeSet(req.Namespace, req.Name, logger)
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch Set")
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"Failed to fetch Set %s",
+			req.NamespacedName,
+		)
+	}
+
+	// Handle deleted Set
+	if !set.DeletionTimestamp.IsZero() {
+		r.removeSet(set.Namespace, set.Name, logger)
+	} else {
+		// Handle non-deleted Set
+		r.reconcileNormal(set, logger)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *SetReconciler) removeSet(setNamespace, setName string, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling Set delete")
+
+	manager := server.GetManagerInstance()
+
+	manager.RemoveSet(setNamespace, setName)
+
+	logger.V(logs.LogInfo).Info("Reconcile delete success")
+}
+
+func (r *SetReconciler) reconcileNormal(set *libsveltosv1beta1.Set, logger logr.Logger) {
+	logger.V(logs.LogInfo).Info("Reconciling new Set")
+
+	manager := server.GetManagerInstance()
+
+	manager.AddSet(set)
+
+	logger.V(logs.LogInfo).Info("Reconciling new Set success")
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.Set{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.ConcurrentReconciles,
+		}).
+		Build(r)
+	if err != nil {
+		return errors.Wrap(err, "error creating controller")
+	}
+
+	return nil
+}
@@ -27,10 +27,12 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
@@ -62,6 +64,9 @@ func setupScheme() (*runtime.Scheme, error) {
 	if err := clusterv1.AddToScheme(s); err != nil {
 		return nil, err
 	}
+	if err := expv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
 	if err := clientgoscheme.AddToScheme(s); err != nil {
 		return nil, err
 	}
@@ -74,6 +79,9 @@ func setupScheme() (*runtime.Scheme, error) {
 	if err := configv1beta1.AddToScheme(s); err != nil {
 		return nil, err
 	}
+	if err := eventv1beta1.AddToScheme(s); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
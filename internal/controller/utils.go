@@ -21,8 +21,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
@@ -34,6 +36,9 @@ func InitScheme() (*runtime.Scheme, error) {
 	if err := clusterv1.AddToScheme(s); err != nil {
 		return nil, err
 	}
+	if err := expv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
 	if err := libsveltosv1beta1.AddToScheme(s); err != nil {
 		return nil, err
 	}
@@ -43,6 +48,9 @@ func InitScheme() (*runtime.Scheme, error) {
 	if err := apiextensionsv1.AddToScheme(s); err != nil {
 		return nil, err
 	}
+	if err := eventv1beta1.AddToScheme(s); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
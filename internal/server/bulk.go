@@ -0,0 +1,424 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+type BulkAction string
+
+const (
+	BulkActionPause  = BulkAction("pause")
+	BulkActionResume = BulkAction("resume")
+	BulkActionLabel  = BulkAction("label")
+	BulkActionResync = BulkAction("resync")
+)
+
+type BulkClusterStatus string
+
+const (
+	BulkClusterStatusPending   = BulkClusterStatus("Pending")
+	BulkClusterStatusWouldRun  = BulkClusterStatus("WouldRun")
+	BulkClusterStatusSkipped   = BulkClusterStatus("Skipped")
+	BulkClusterStatusSucceeded = BulkClusterStatus("Succeeded")
+	BulkClusterStatusFailed    = BulkClusterStatus("Failed")
+)
+
+type BulkJobState string
+
+const (
+	BulkJobStateRunning   = BulkJobState("Running")
+	BulkJobStateCompleted = BulkJobState("Completed")
+	// BulkJobStatePreviewed is the state of dry-run previews. Nothing runs.
+	BulkJobStatePreviewed = BulkJobState("Previewed")
+)
+
+const (
+	// bulkConcurrency is the maximum number of clusters a bulk job acts on at the same time
+	bulkConcurrency = 5
+
+	// bulkClusterTimeout bounds the time spent acting on a single cluster
+	bulkClusterTimeout = 30 * time.Second
+
+	// bulkJobRetention is how long completed jobs can still be polled
+	bulkJobRetention = time.Hour
+)
+
+// BulkOperationRequest is the body of a bulk operation request
+type BulkOperationRequest struct {
+	Action BulkAction `json:"action"`
+
+	// Reason is required by the pause action and optional for resume
+	Reason string `json:"reason,omitempty"`
+
+	// PauseClusterAPI must be true for the pause action to act on ClusterAPI powered clusters
+	PauseClusterAPI bool `json:"pauseClusterAPI,omitempty"`
+
+	// Labels is required by the label action. A nil value removes the label.
+	Labels map[string]*string `json:"labels,omitempty"`
+
+	// DryRun, when true, only previews the clusters the action would run on
+	DryRun bool `json:"dryRun"`
+}
+
+type BulkClusterResult struct {
+	Namespace   string                        `json:"namespace"`
+	Name        string                        `json:"name"`
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
+	Status      BulkClusterStatus             `json:"status"`
+
+	// Message explains why the cluster was skipped or the action failed
+	Message string `json:"message,omitempty"`
+
+	// Profiles contains, for the resync action, the outcome for each profile deployed on the cluster
+	Profiles []BulkProfileResult `json:"profiles,omitempty"`
+}
+
+type BulkProfileResult struct {
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Status    BulkClusterStatus `json:"status"`
+
+	// Message explains why the profile was skipped or the resync failed
+	Message string `json:"message,omitempty"`
+}
+
+type BulkJob struct {
+	// ID is not set for dry-run previews
+	ID     string       `json:"id,omitempty"`
+	Action BulkAction   `json:"action"`
+	DryRun bool         `json:"dryRun"`
+	State  BulkJobState `json:"state"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	WouldRun  int `json:"wouldRun,omitempty"`
+	Skipped   int `json:"skipped"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	Results []BulkClusterResult `json:"results"`
+
+	// user is the only one allowed to poll the job
+	user string
+}
+
+// validateBulkOperationRequest verifies all arguments needed by the action are present
+func validateBulkOperationRequest(request *BulkOperationRequest) error {
+	switch request.Action {
+	case BulkActionPause:
+		if request.Reason == "" {
+			return errors.New("reason is required")
+		}
+	case BulkActionLabel:
+		if len(request.Labels) == 0 {
+			return errors.New("no label to patch")
+		}
+	case BulkActionResume, BulkActionResync:
+	default:
+		return fmt.Errorf("supported actions are %q, %q, %q and %q",
+			BulkActionPause, BulkActionResume, BulkActionLabel, BulkActionResync)
+	}
+
+	return nil
+}
+
+// newBulkJob returns a job acting on clusters. Clusters for which canModify returns false (or fails)
+// are skipped. For dry-run requests the job is a completed preview where the clusters the action
+// would run on are reported as WouldRun.
+func newBulkJob(request *BulkOperationRequest, clusters []corev1.ObjectReference, user string, now time.Time,
+	canModify func(cluster *corev1.ObjectReference) (bool, error)) *BulkJob {
+
+	status := BulkClusterStatusPending
+	if request.DryRun {
+		status = BulkClusterStatusWouldRun
+	}
+
+	job := &BulkJob{
+		Action:    request.Action,
+		DryRun:    request.DryRun,
+		State:     BulkJobStateRunning,
+		CreatedAt: now,
+		Total:     len(clusters),
+		Results:   make([]BulkClusterResult, len(clusters)),
+		user:      user,
+	}
+
+	for i := range clusters {
+		job.Results[i] = BulkClusterResult{
+			Namespace:   clusters[i].Namespace,
+			Name:        clusters[i].Name,
+			ClusterType: clusterproxy.GetClusterType(&clusters[i]),
+			Status:      status,
+		}
+
+		allowed, err := canModify(&clusters[i])
+		if err != nil {
+			job.Results[i].Status = BulkClusterStatusSkipped
+			job.Results[i].Message = fmt.Sprintf("failed to verify permissions: %v", err)
+		} else if !allowed {
+			job.Results[i].Status = BulkClusterStatusSkipped
+			job.Results[i].Message = "no permissions to modify this cluster"
+		}
+	}
+
+	job.updateCounters()
+	if request.DryRun {
+		job.State = BulkJobStatePreviewed
+		job.CompletedAt = &now
+	} else if job.Pending == 0 {
+		job.complete(now)
+	}
+
+	return job
+}
+
+func (j *BulkJob) updateCounters() {
+	j.Pending, j.WouldRun, j.Skipped, j.Succeeded, j.Failed = 0, 0, 0, 0, 0
+	for i := range j.Results {
+		switch j.Results[i].Status {
+		case BulkClusterStatusPending:
+			j.Pending++
+		case BulkClusterStatusWouldRun:
+			j.WouldRun++
+		case BulkClusterStatusSkipped:
+			j.Skipped++
+		case BulkClusterStatusSucceeded:
+			j.Succeeded++
+		case BulkClusterStatusFailed:
+			j.Failed++
+		}
+	}
+}
+
+func (j *BulkJob) complete(now time.Time) {
+	j.State = BulkJobStateCompleted
+	j.CompletedAt = &now
+}
+
+// deepCopy returns a copy of the job which can be accessed without holding bulkJobMux
+func (j *BulkJob) deepCopy() *BulkJob {
+	result := *j
+	result.Results = make([]BulkClusterResult, len(j.Results))
+	copy(result.Results, j.Results)
+	for i := range result.Results {
+		if j.Results[i].Profiles != nil {
+			result.Results[i].Profiles = make([]BulkProfileResult, len(j.Results[i].Profiles))
+			copy(result.Results[i].Profiles, j.Results[i].Profiles)
+		}
+	}
+	if j.CompletedAt != nil {
+		completedAt := *j.CompletedAt
+		result.CompletedAt = &completedAt
+	}
+	return &result
+}
+
+// startBulkJob assigns an ID to job, stores it and runs action on all its pending clusters in
+// the background. Returns a copy of the stored job.
+func (m *instance) startBulkJob(job *BulkJob, concurrency int,
+	action func(ctx context.Context, cluster *BulkClusterResult) error) *BulkJob {
+
+	id := make([]byte, 16)
+	// crypto/rand Read never returns an error
+	_, _ = rand.Read(id)
+	job.ID = hex.EncodeToString(id)
+
+	m.bulkJobMux.Lock()
+	m.removeExpiredBulkJobs(time.Now())
+	m.bulkJobs[job.ID] = job
+	result := job.deepCopy()
+	m.bulkJobMux.Unlock()
+
+	if job.State != BulkJobStateCompleted {
+		go m.runBulkJob(job, concurrency, action)
+	}
+
+	return result
+}
+
+// runBulkJob runs action on all pending clusters, at most concurrency at a time
+func (m *instance) runBulkJob(job *BulkJob, concurrency int,
+	action func(ctx context.Context, cluster *BulkClusterResult) error) {
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range job.Results {
+		// Goroutines started below concurrently update other entries
+		m.bulkJobMux.RLock()
+		cluster := job.Results[i]
+		m.bulkJobMux.RUnlock()
+
+		if cluster.Status != BulkClusterStatusPending {
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, cluster BulkClusterResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), bulkClusterTimeout)
+			defer cancel()
+
+			err := action(ctx, &cluster)
+
+			m.bulkJobMux.Lock()
+			defer m.bulkJobMux.Unlock()
+			job.Results[i].Profiles = cluster.Profiles
+			if err != nil {
+				job.Results[i].Status = BulkClusterStatusFailed
+				job.Results[i].Message = err.Error()
+			} else {
+				job.Results[i].Status = BulkClusterStatusSucceeded
+			}
+			job.updateCounters()
+		}(i, cluster)
+	}
+
+	wg.Wait()
+
+	m.bulkJobMux.Lock()
+	defer m.bulkJobMux.Unlock()
+	job.complete(time.Now())
+}
+
+// getBulkJob returns a copy of the job. Jobs are only visible to the user who created them.
+func (m *instance) getBulkJob(id, user string) *BulkJob {
+	m.bulkJobMux.RLock()
+	defer m.bulkJobMux.RUnlock()
+
+	job, ok := m.bulkJobs[id]
+	if !ok || job.user != user {
+		return nil
+	}
+
+	return job.deepCopy()
+}
+
+// removeExpiredBulkJobs removes jobs completed more than bulkJobRetention ago. Must be called
+// holding bulkJobMux.
+func (m *instance) removeExpiredBulkJobs(now time.Time) {
+	for id, job := range m.bulkJobs {
+		if job.CompletedAt != nil && now.Sub(*job.CompletedAt) > bulkJobRetention {
+			delete(m.bulkJobs, id)
+		}
+	}
+}
+
+// runBulkAction runs the requested action on a single cluster. Label changes are applied
+// impersonating the user.
+func (m *instance) runBulkAction(ctx context.Context, request *BulkOperationRequest, cluster *BulkClusterResult,
+	userInfo *authenticationv1.UserInfo, impersonatingClient client.Client) error {
+
+	switch request.Action {
+	case BulkActionPause, BulkActionResume:
+		err := validateClusterAPIPause(cluster.ClusterType, request.Action == BulkActionPause, request.PauseClusterAPI)
+		if err != nil {
+			return err
+		}
+		return m.setClusterPaused(ctx, cluster.Namespace, cluster.Name, cluster.ClusterType,
+			request.Action == BulkActionPause, userInfo.Username, request.Reason)
+	case BulkActionLabel:
+		_, err := patchClusterLabels(ctx, impersonatingClient, cluster.Namespace, cluster.Name, cluster.ClusterType,
+			&LabelPatch{Labels: request.Labels}, nil)
+		return err
+	case BulkActionResync:
+		return m.resyncClusterProfiles(ctx, cluster, userInfo)
+	default:
+		return fmt.Errorf("unsupported action %q", request.Action)
+	}
+}
+
+// resyncClusterProfiles requests a redeploy of every profile deployed on the cluster the user
+// can update. The outcome for each profile is reported in cluster.Profiles.
+func (m *instance) resyncClusterProfiles(ctx context.Context, cluster *BulkClusterResult,
+	userInfo *authenticationv1.UserInfo) error {
+	clusterSummaries := m.getClusterProfileStatusesWithKeyByCluster(cluster.Namespace, cluster.Name,
+		cluster.ClusterType)
+
+	cluster.Profiles = make([]BulkProfileResult, 0, len(clusterSummaries))
+	var errs []error
+	for clusterSummaryRef, status := range clusterSummaries {
+		profileRef := &corev1.ObjectReference{
+			Kind:       status.ProfileType,
+			APIVersion: configv1beta1.GroupVersion.String(),
+			Name:       status.ProfileName,
+		}
+		if status.ProfileType == configv1beta1.ProfileKind {
+			profileRef.Namespace = status.Namespace
+		}
+
+		result := BulkProfileResult{
+			Kind:      profileRef.Kind,
+			Namespace: profileRef.Namespace,
+			Name:      profileRef.Name,
+			Status:    BulkClusterStatusSucceeded,
+		}
+
+		canUpdate, err := m.canUpdateProfile(profileRef, userInfo)
+		switch {
+		case err != nil:
+			result.Status = BulkClusterStatusSkipped
+			result.Message = fmt.Sprintf("failed to verify permissions: %v", err)
+		case !canUpdate:
+			result.Status = BulkClusterStatusSkipped
+			result.Message = "user is not allowed to update the profile"
+		default:
+			if err := requestRedeploy(ctx, m.client, &clusterSummaryRef, userInfo.Username, time.Now()); err != nil {
+				result.Status = BulkClusterStatusFailed
+				result.Message = err.Error()
+				errs = append(errs, fmt.Errorf("%s %s: %w", profileRef.Kind, profileRef.Name, err))
+			}
+		}
+		cluster.Profiles = append(cluster.Profiles, result)
+	}
+
+	sort.Slice(cluster.Profiles, func(i, j int) bool {
+		if cluster.Profiles[i].Kind != cluster.Profiles[j].Kind {
+			return cluster.Profiles[i].Kind < cluster.Profiles[j].Kind
+		}
+		if cluster.Profiles[i].Namespace != cluster.Profiles[j].Namespace {
+			return cluster.Profiles[i].Namespace < cluster.Profiles[j].Namespace
+		}
+		return cluster.Profiles[i].Name < cluster.Profiles[j].Name
+	})
+
+	return errors.Join(errs...)
+}
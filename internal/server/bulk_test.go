@@ -0,0 +1,201 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Bulk operations", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+	})
+
+	It("validateBulkOperationRequest verifies action arguments", func() {
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionPause})).ToNot(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionPause, Reason: randomString()})).To(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionLabel})).ToNot(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionLabel, Labels: map[string]*string{randomString(): nil}})).To(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionResume})).To(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkActionResync})).To(Succeed())
+		Expect(server.ValidateBulkOperationRequest(&server.BulkOperationRequest{
+			Action: server.BulkAction(randomString())})).ToNot(Succeed())
+	})
+
+	It("newBulkJob skips clusters user cannot modify", func() {
+		allowed := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		forbidden := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		failed := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+
+		request := &server.BulkOperationRequest{Action: server.BulkActionResync}
+		job := server.NewBulkJob(request, []corev1.ObjectReference{allowed, forbidden, failed}, randomString(),
+			time.Now(), func(cluster *corev1.ObjectReference) (bool, error) {
+				switch cluster.Name {
+				case allowed.Name:
+					return true, nil
+				case forbidden.Name:
+					return false, nil
+				default:
+					return false, errors.New(randomString())
+				}
+			})
+
+		Expect(job.ID).To(BeEmpty())
+		Expect(job.State).To(Equal(server.BulkJobStateRunning))
+		Expect(job.Total).To(Equal(3))
+		Expect(job.Pending).To(Equal(1))
+		Expect(job.Skipped).To(Equal(2))
+		Expect(job.Results[0].Status).To(Equal(server.BulkClusterStatusPending))
+		Expect(job.Results[0].ClusterType).To(Equal(libsveltosv1beta1.ClusterTypeSveltos))
+		Expect(job.Results[1].Status).To(Equal(server.BulkClusterStatusSkipped))
+		Expect(job.Results[2].Status).To(Equal(server.BulkClusterStatusSkipped))
+
+		// No cluster can be modified
+		job = server.NewBulkJob(request, []corev1.ObjectReference{forbidden}, randomString(),
+			time.Now(), func(cluster *corev1.ObjectReference) (bool, error) { return false, nil })
+		Expect(job.State).To(Equal(server.BulkJobStateCompleted))
+		Expect(job.CompletedAt).ToNot(BeNil())
+	})
+
+	It("newBulkJob returns a completed preview for dry-run requests", func() {
+		allowed := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		forbidden := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+
+		request := &server.BulkOperationRequest{Action: server.BulkActionResync, DryRun: true}
+		job := server.NewBulkJob(request, []corev1.ObjectReference{allowed, forbidden}, randomString(),
+			time.Now(), func(cluster *corev1.ObjectReference) (bool, error) {
+				return cluster.Name == allowed.Name, nil
+			})
+
+		Expect(job.ID).To(BeEmpty())
+		Expect(job.State).To(Equal(server.BulkJobStatePreviewed))
+		Expect(job.CompletedAt).ToNot(BeNil())
+		Expect(job.Pending).To(BeZero())
+		Expect(job.WouldRun).To(Equal(1))
+		Expect(job.Skipped).To(Equal(1))
+		Expect(job.Results[0].Status).To(Equal(server.BulkClusterStatusWouldRun))
+		Expect(job.Results[1].Status).To(Equal(server.BulkClusterStatusSkipped))
+	})
+
+	It("startBulkJob runs action with bounded concurrency and reports per cluster results", func() {
+		manager := server.NewManagerInstance(nil, scheme, logger)
+
+		const numClusters = 10
+		const concurrency = 3
+		clusters := make([]corev1.ObjectReference, numClusters)
+		for i := range clusters {
+			clusters[i] = corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+				Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		}
+		failingCluster := clusters[0].Name
+
+		user := randomString()
+		job := server.NewBulkJob(&server.BulkOperationRequest{Action: server.BulkActionResync}, clusters, user,
+			time.Now(), func(cluster *corev1.ObjectReference) (bool, error) { return true, nil })
+
+		var running, maxRunning atomic.Int32
+		job = manager.StartBulkJob(job, concurrency, func(ctx context.Context, cluster *server.BulkClusterResult) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if cluster.Name == failingCluster {
+				return errors.New(randomString())
+			}
+			return nil
+		})
+		Expect(job.ID).ToNot(BeEmpty())
+
+		// Jobs are only visible to the user who started them
+		Expect(manager.GetBulkJob(job.ID, randomString())).To(BeNil())
+		Expect(manager.GetBulkJob(randomString(), user)).To(BeNil())
+
+		Eventually(func() bool {
+			current := manager.GetBulkJob(job.ID, user)
+			return current != nil && current.State == server.BulkJobStateCompleted
+		}, time.Minute, 10*time.Millisecond).Should(BeTrue())
+
+		current := manager.GetBulkJob(job.ID, user)
+		Expect(current.Pending).To(Equal(0))
+		Expect(current.Failed).To(Equal(1))
+		Expect(current.Succeeded).To(Equal(numClusters - 1))
+		Expect(current.Results[0].Status).To(Equal(server.BulkClusterStatusFailed))
+		Expect(current.Results[0].Message).ToNot(BeEmpty())
+		Expect(maxRunning.Load()).To(BeNumerically("<=", concurrency))
+	})
+
+	It("runBulkAction labels clusters", func() {
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "staging"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sveltosCluster).Build()
+		manager := server.NewManagerInstance(c, scheme, logger)
+
+		request := &server.BulkOperationRequest{
+			Action: server.BulkActionLabel,
+			Labels: map[string]*string{"env": ptr.To("production")},
+		}
+		cluster := &server.BulkClusterResult{Namespace: sveltosCluster.Namespace, Name: sveltosCluster.Name,
+			ClusterType: libsveltosv1beta1.ClusterTypeSveltos}
+		Expect(manager.RunBulkAction(context.TODO(), request, cluster, randomString(), c)).To(Succeed())
+
+		current := &libsveltosv1beta1.SveltosCluster{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: sveltosCluster.Namespace, Name: sveltosCluster.Name},
+			current)).To(Succeed())
+		Expect(current.Labels).To(HaveKeyWithValue("env", "production"))
+	})
+})
@@ -0,0 +1,668 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	BundleFormatYAML = "yaml"
+	BundleFormatTar  = "tar"
+
+	// redactedAnnotation marks Secrets whose data was removed from the bundle
+	redactedAnnotation = "ui.projectsveltos.io/redacted"
+)
+
+type BundleAction string
+
+const (
+	BundleActionCreate = BundleAction("Create")
+	BundleActionUpdate = BundleAction("Update")
+	BundleActionSkip   = BundleAction("Skip")
+)
+
+// ProfileBundle contains a ClusterProfile/Profile, its dependencies and the ConfigMaps/Secrets
+// referenced by their PolicyRefs. ConfigMaps/Secrets come first, then profiles, with each profile
+// after its dependencies.
+type ProfileBundle struct {
+	Objects []client.Object
+
+	// Warnings lists references which could not be included in the bundle
+	Warnings []string
+}
+
+type BundleObjectResult struct {
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace,omitempty"`
+	Name      string       `json:"name"`
+	Action    BundleAction `json:"action"`
+
+	// Applied is true when the object was created/updated. Redacted Secrets are never applied.
+	Applied bool `json:"applied"`
+}
+
+type BundleImportResult struct {
+	// DryRun is true when the bundle was only validated
+	DryRun bool `json:"dryRun"`
+
+	// Applied is true when all objects were created/updated
+	Applied bool `json:"applied"`
+
+	// Objects lists the action taken (or planned, on dry-run) for each object in the bundle
+	Objects []BundleObjectResult `json:"objects"`
+
+	// ValidationErrors lists why the bundle was rejected. Objects are applied one at a time and there
+	// is no rollback: if an object is rejected while applying, the objects before it are left applied
+	// (see Objects).
+	ValidationErrors []ValidationError `json:"validationErrors,omitempty"`
+
+	// Error is set when applying an object failed. Objects before it are left applied (see Objects).
+	Error string `json:"error,omitempty"`
+}
+
+// exportProfileBundle collects the ClusterProfile/Profile, its DependsOn dependencies (recursively)
+// and the ConfigMaps/Secrets referenced by their PolicyRefs, using client c which impersonates the
+// calling user. PolicyRefs whose namespace or name is a template, or which are not found, are reported
+// as warnings. When redactSecrets is set, Secret data is removed.
+func exportProfileBundle(ctx context.Context, c client.Client, profileRef *corev1.ObjectReference,
+	redactSecrets bool) (*ProfileBundle, error) {
+
+	bundle := &ProfileBundle{}
+	profiles := make([]client.Object, 0)
+	visited := map[corev1.ObjectReference]bool{}
+
+	var visit func(ref *corev1.ObjectReference, isDependency bool) error
+	visit = func(ref *corev1.ObjectReference, isDependency bool) error {
+		if visited[*ref] {
+			return nil
+		}
+		visited[*ref] = true
+
+		profile, spec, err := getProfileObject(ctx, c, ref)
+		if err != nil {
+			if isDependency && apierrors.IsNotFound(err) {
+				bundle.Warnings = append(bundle.Warnings,
+					fmt.Sprintf("dependency %s %s not found", ref.Kind, getBundleObjectName(ref.Namespace, ref.Name)))
+				return nil
+			}
+			return err
+		}
+
+		for i := range spec.DependsOn {
+			dependency := &corev1.ObjectReference{
+				Kind:       ref.Kind,
+				APIVersion: ref.APIVersion,
+				Namespace:  ref.Namespace,
+				Name:       spec.DependsOn[i],
+			}
+			if err := visit(dependency, true); err != nil {
+				return err
+			}
+		}
+
+		profile.GetObjectKind().SetGroupVersionKind(configv1beta1.GroupVersion.WithKind(ref.Kind))
+		profiles = append(profiles, profile)
+		return nil
+	}
+
+	if err := visit(profileRef, false); err != nil {
+		return nil, err
+	}
+
+	referenced := map[corev1.ObjectReference]bool{}
+	for i := range profiles {
+		spec, err := getProfileSpec(profiles[i])
+		if err != nil {
+			return nil, err
+		}
+		for j := range spec.PolicyRefs {
+			ref, warning := getPolicyRefObjectReference(profiles[i], &spec.PolicyRefs[j])
+			if warning != "" {
+				bundle.Warnings = append(bundle.Warnings, warning)
+				continue
+			}
+			if referenced[*ref] {
+				continue
+			}
+			referenced[*ref] = true
+
+			obj, err := getReferencedObject(ctx, c, ref)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("%s %s referenced by %s %s not found",
+						ref.Kind, getBundleObjectName(ref.Namespace, ref.Name),
+						profiles[i].GetObjectKind().GroupVersionKind().Kind, profiles[i].GetName()))
+					continue
+				}
+				return nil, err
+			}
+			if secret, ok := obj.(*corev1.Secret); ok && redactSecrets {
+				redactSecret(secret)
+			}
+			bundle.Objects = append(bundle.Objects, obj)
+		}
+	}
+
+	bundle.Objects = append(bundle.Objects, profiles...)
+	for i := range bundle.Objects {
+		cleanBundleObject(bundle.Objects[i])
+	}
+
+	return bundle, nil
+}
+
+func getBundleObjectName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// getPolicyRefObjectReference returns the ConfigMap/Secret referenced by a PolicyRef. A warning is
+// returned instead if the reference can only be resolved when deploying to a cluster.
+func getPolicyRefObjectReference(profile client.Object, policyRef *configv1beta1.PolicyRef,
+) (*corev1.ObjectReference, string) {
+
+	namespace := policyRef.Namespace
+	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ProfileKind {
+		// Profile can only reference resources in its namespace
+		namespace = profile.GetNamespace()
+	}
+
+	if namespace == "" || strings.Contains(namespace, "{{") || strings.Contains(policyRef.Name, "{{") {
+		return nil, fmt.Sprintf("%s %s referenced by %s %s depends on the cluster and is not included",
+			policyRef.Kind, getBundleObjectName(policyRef.Namespace, policyRef.Name),
+			profile.GetObjectKind().GroupVersionKind().Kind, profile.GetName())
+	}
+
+	return &corev1.ObjectReference{
+		Kind:       policyRef.Kind,
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       policyRef.Name,
+	}, ""
+}
+
+// getReferencedObject returns the ConfigMap/Secret
+func getReferencedObject(ctx context.Context, c client.Client, ref *corev1.ObjectReference) (client.Object, error) {
+	var obj client.Object
+	switch ref.Kind {
+	case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+		obj = &corev1.ConfigMap{}
+	case string(libsveltosv1beta1.SecretReferencedResourceKind):
+		obj = &corev1.Secret{}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", ref.Kind)
+	}
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, obj); err != nil {
+		return nil, err
+	}
+
+	obj.GetObjectKind().SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(ref.Kind))
+	return obj, nil
+}
+
+// redactSecret removes the values of the Secret data, keeping its keys
+func redactSecret(secret *corev1.Secret) {
+	for k := range secret.Data {
+		secret.Data[k] = []byte{}
+	}
+	secret.StringData = nil
+
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[redactedAnnotation] = "true"
+	secret.SetAnnotations(annotations)
+}
+
+// cleanBundleObject removes fields which are set by the API server and the profile status
+func cleanBundleObject(obj client.Object) {
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetGeneration(0)
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetOwnerReferences(nil)
+
+	annotations := obj.GetAnnotations()
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+
+	switch o := obj.(type) {
+	case *configv1beta1.ClusterProfile:
+		o.Status = configv1beta1.Status{}
+	case *configv1beta1.Profile:
+		o.Status = configv1beta1.Status{}
+	}
+}
+
+// encodeProfileBundle returns the bundle as multi-document YAML or as a tar archive with a YAML file
+// per object. Files are prefixed with their position so the bundle order is preserved.
+func encodeProfileBundle(bundle *ProfileBundle, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var tarWriter *tar.Writer
+	if format == BundleFormatTar {
+		tarWriter = tar.NewWriter(&buf)
+	}
+
+	for i := range bundle.Objects {
+		data, err := yaml.Marshal(bundle.Objects[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if tarWriter == nil {
+			buf.WriteString("---\n")
+			buf.Write(data)
+			continue
+		}
+
+		obj := bundle.Objects[i]
+		fileName := fmt.Sprintf("%03d-%s-%s.yaml", i, strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind),
+			strings.ReplaceAll(getBundleObjectName(obj.GetNamespace(), obj.GetName()), "/", "-"))
+		if err := tarWriter.WriteHeader(&tar.Header{Name: fileName, Mode: 0o600, Size: int64(len(data))}); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if tarWriter != nil {
+		if err := tarWriter.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isTarArchive returns true if data starts with a tar header
+func isTarArchive(data []byte) bool {
+	const magicOffset = 257
+	return len(data) > magicOffset+5 && string(data[magicOffset:magicOffset+5]) == "ustar"
+}
+
+// decodeProfileBundle decodes a bundle, either multi-document YAML or a tar archive of YAML files.
+// Supported kinds are ClusterProfile, Profile, ConfigMap and Secret.
+func decodeProfileBundle(data []byte) ([]client.Object, []ValidationError) {
+	documents := make([][]byte, 0)
+	if isTarArchive(data) {
+		tarReader := tar.NewReader(bytes.NewReader(data))
+		for {
+			header, err := tarReader.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, []ValidationError{{Message: err.Error()}}
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, []ValidationError{{Message: err.Error()}}
+			}
+			fileDocuments, err := splitYAMLDocuments(content)
+			if err != nil {
+				return nil, []ValidationError{{Message: fmt.Sprintf("%s: %v", header.Name, err)}}
+			}
+			documents = append(documents, fileDocuments...)
+		}
+	} else {
+		var err error
+		documents, err = splitYAMLDocuments(data)
+		if err != nil {
+			return nil, []ValidationError{{Message: err.Error()}}
+		}
+	}
+
+	if len(documents) == 0 {
+		return nil, []ValidationError{{Message: "bundle is empty"}}
+	}
+
+	objects := make([]client.Object, 0, len(documents))
+	var validationErrors []ValidationError
+	for i := range documents {
+		obj, errs := decodeBundleObject(documents[i])
+		for j := range errs {
+			errs[j].Message = fmt.Sprintf("document %d: %s", i, errs[j].Message)
+		}
+		validationErrors = append(validationErrors, errs...)
+		if obj != nil {
+			objects = append(objects, obj)
+		}
+	}
+
+	if validationErrors != nil {
+		return nil, validationErrors
+	}
+
+	return objects, nil
+}
+
+// splitYAMLDocuments returns the non empty documents of a multi-document YAML
+func splitYAMLDocuments(data []byte) ([][]byte, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+
+	documents := make([][]byte, 0)
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip documents containing only comments or whitespace
+		content := map[string]any{}
+		if err := yaml.Unmarshal(document, &content); err != nil {
+			return nil, err
+		}
+		if len(content) == 0 {
+			continue
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+func decodeBundleObject(data []byte) (client.Object, []ValidationError) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, typeMeta); err != nil {
+		return nil, []ValidationError{{Message: err.Error()}}
+	}
+
+	if typeMeta.Kind == configv1beta1.ClusterProfileKind || typeMeta.Kind == configv1beta1.ProfileKind {
+		return decodeProfile(data)
+	}
+
+	var obj client.Object
+	switch typeMeta.Kind {
+	case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+		obj = &corev1.ConfigMap{}
+	case string(libsveltosv1beta1.SecretReferencedResourceKind):
+		obj = &corev1.Secret{}
+	default:
+		return nil, []ValidationError{{Field: "kind", Type: string(metav1.CauseTypeFieldValueNotSupported),
+			Message: fmt.Sprintf("supported kinds are %q, %q, %q and %q", configv1beta1.ClusterProfileKind,
+				configv1beta1.ProfileKind, libsveltosv1beta1.ConfigMapReferencedResourceKind,
+				libsveltosv1beta1.SecretReferencedResourceKind)}}
+	}
+
+	if typeMeta.APIVersion != corev1.SchemeGroupVersion.String() {
+		return nil, []ValidationError{{Field: "apiVersion", Type: string(metav1.CauseTypeFieldValueNotSupported),
+			Message: fmt.Sprintf("supported apiVersion for %s is %q", typeMeta.Kind, corev1.SchemeGroupVersion.String())}}
+	}
+
+	if err := yaml.UnmarshalStrict(data, obj); err != nil {
+		return nil, []ValidationError{{Message: err.Error()}}
+	}
+
+	var validationErrors []ValidationError
+	if obj.GetName() == "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "metadata.name",
+			Type: string(metav1.CauseTypeFieldValueRequired), Message: "name is required"})
+	}
+	if obj.GetNamespace() == "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "metadata.namespace",
+			Type: string(metav1.CauseTypeFieldValueRequired), Message: fmt.Sprintf("namespace is required for %s",
+				typeMeta.Kind)})
+	}
+
+	if validationErrors != nil {
+		return nil, validationErrors
+	}
+
+	return obj, nil
+}
+
+func getBundleObjectRef(obj client.Object) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		Kind:      obj.GetObjectKind().GroupVersionKind().Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+// validateProfileBundle verifies that DependsOn dependencies and PolicyRefs (unless optional) of all
+// profiles in the bundle are either in the bundle or already exist. Redacted Secrets must already exist.
+func validateProfileBundle(ctx context.Context, c client.Client, objects []client.Object) ([]ValidationError, error) {
+	inBundle := map[corev1.ObjectReference]bool{}
+	for i := range objects {
+		inBundle[getBundleObjectRef(objects[i])] = true
+	}
+
+	exists := func(ref *corev1.ObjectReference) (bool, error) {
+		if inBundle[*ref] {
+			return true, nil
+		}
+
+		var obj client.Object
+		switch ref.Kind {
+		case configv1beta1.ClusterProfileKind, configv1beta1.ProfileKind:
+			_, _, err := getProfileObject(ctx, c, ref)
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return err == nil, err
+		case string(libsveltosv1beta1.SecretReferencedResourceKind):
+			obj = &corev1.Secret{}
+		default:
+			obj = &corev1.ConfigMap{}
+		}
+
+		err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, obj)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	var validationErrors []ValidationError
+	for i := range objects {
+		objRef := getBundleObjectRef(objects[i])
+		objName := fmt.Sprintf("%s %s", objRef.Kind, getBundleObjectName(objRef.Namespace, objRef.Name))
+
+		if objects[i].GetAnnotations()[redactedAnnotation] == "true" {
+			delete(inBundle, objRef)
+			found, err := exists(&objRef)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				validationErrors = append(validationErrors, ValidationError{
+					Message: fmt.Sprintf("%s is redacted and does not exist", objName)})
+			}
+			inBundle[objRef] = true
+			continue
+		}
+
+		spec, err := getProfileSpec(objects[i])
+		if err != nil {
+			// ConfigMap/Secret
+			continue
+		}
+
+		for j := range spec.DependsOn {
+			dependency := &corev1.ObjectReference{Kind: objRef.Kind, Namespace: objRef.Namespace, Name: spec.DependsOn[j]}
+			found, err := exists(dependency)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				validationErrors = append(validationErrors, ValidationError{Field: "spec.dependsOn",
+					Message: fmt.Sprintf("%s depends on %s which is neither in the bundle nor exists", objName,
+						spec.DependsOn[j])})
+			}
+		}
+
+		for j := range spec.PolicyRefs {
+			if spec.PolicyRefs[j].Optional {
+				continue
+			}
+			ref, warning := getPolicyRefObjectReference(objects[i], &spec.PolicyRefs[j])
+			if warning != "" {
+				continue
+			}
+			ref.APIVersion = ""
+			found, err := exists(ref)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				validationErrors = append(validationErrors, ValidationError{Field: "spec.policyRefs",
+					Message: fmt.Sprintf("%s references %s %s which is neither in the bundle nor exists", objName,
+						ref.Kind, getBundleObjectName(ref.Namespace, ref.Name))})
+			}
+		}
+	}
+
+	return validationErrors, nil
+}
+
+// importProfileBundle validates the bundle, runs a server-side dry-run for all objects and then,
+// unless dryRun is set, creates (or updates) them using client c, which impersonates the calling user.
+// Redacted Secrets are never applied.
+// Objects are applied one at a time and there is no rollback. Applying stops at the first failure:
+// the result then reports which objects were applied and, for errors other than validation ones,
+// is returned along with the error.
+func importProfileBundle(ctx context.Context, c client.Client, objects []client.Object, dryRun bool,
+) (*BundleImportResult, error) {
+
+	result := &BundleImportResult{DryRun: dryRun}
+
+	validationErrors, err := validateProfileBundle(ctx, c, objects)
+	if err != nil {
+		return nil, err
+	}
+	if validationErrors != nil {
+		result.ValidationErrors = validationErrors
+		return result, nil
+	}
+
+	result.Objects = make([]BundleObjectResult, len(objects))
+	for i := range objects {
+		objRef := getBundleObjectRef(objects[i])
+		result.Objects[i] = BundleObjectResult{Kind: objRef.Kind, Namespace: objRef.Namespace, Name: objRef.Name}
+	}
+
+	// getObjectValidationErrors returns validation errors in err, if any, prefixed with the object
+	getObjectValidationErrors := func(i int, err error) []ValidationError {
+		errs := getValidationErrors(err)
+		for j := range errs {
+			errs[j].Message = fmt.Sprintf("%s %s: %s", result.Objects[i].Kind,
+				getBundleObjectName(result.Objects[i].Namespace, result.Objects[i].Name), errs[j].Message)
+		}
+		return errs
+	}
+
+	// Dry-run reports all validation errors
+	for i := range objects {
+		action, err := applyBundleObject(ctx, c, objects[i], true)
+		if err != nil {
+			if errs := getObjectValidationErrors(i, err); errs != nil {
+				result.ValidationErrors = append(result.ValidationErrors, errs...)
+				continue
+			}
+			return nil, err
+		}
+		result.Objects[i].Action = action
+	}
+	if result.ValidationErrors != nil || dryRun {
+		return result, nil
+	}
+
+	for i := range objects {
+		action, err := applyBundleObject(ctx, c, objects[i], false)
+		if err != nil {
+			if errs := getObjectValidationErrors(i, err); errs != nil {
+				result.ValidationErrors = errs
+				return result, nil
+			}
+			result.Error = fmt.Sprintf("%s %s: %v", result.Objects[i].Kind,
+				getBundleObjectName(result.Objects[i].Namespace, result.Objects[i].Name), err)
+			return result, err
+		}
+		result.Objects[i].Action = action
+		result.Objects[i].Applied = action != BundleActionSkip
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// applyBundleObject creates obj or, if it already exists, updates it
+func applyBundleObject(ctx context.Context, c client.Client, obj client.Object, dryRun bool,
+) (BundleAction, error) {
+
+	if obj.GetAnnotations()[redactedAnnotation] == "true" {
+		return BundleActionSkip, nil
+	}
+
+	current := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, current)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	desired := obj.DeepCopyObject().(client.Object)
+	if apierrors.IsNotFound(err) {
+		var createOptions []client.CreateOption
+		if dryRun {
+			createOptions = append(createOptions, client.DryRunAll)
+		}
+		return BundleActionCreate, c.Create(ctx, desired, createOptions...)
+	}
+
+	desired.SetResourceVersion(current.GetResourceVersion())
+	var updateOptions []client.UpdateOption
+	if dryRun {
+		updateOptions = append(updateOptions, client.DryRunAll)
+	}
+	return BundleActionUpdate, c.Update(ctx, desired, updateOptions...)
+}
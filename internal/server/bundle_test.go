@@ -0,0 +1,266 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Profile bundles", func() {
+	var namespace string
+	var configMap *corev1.ConfigMap
+	var secret *corev1.Secret
+	var base *configv1beta1.Profile
+	var profile *configv1beta1.Profile
+
+	BeforeEach(func() {
+		namespace = randomString()
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string]string{"policy.yaml": randomString()},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string][]byte{"policy.yaml": []byte(randomString())},
+		}
+
+		base = &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: configv1beta1.Spec{
+				PolicyRefs: []configv1beta1.PolicyRef{
+					{Kind: string(libsveltosv1beta1.SecretReferencedResourceKind), Name: secret.Name},
+				},
+			},
+		}
+
+		profile = &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: configv1beta1.Spec{
+				DependsOn: []string{base.Name},
+				PolicyRefs: []configv1beta1.PolicyRef{
+					{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind), Name: configMap.Name},
+					// Resolved only when deploying to a cluster
+					{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+						Name: "{{ .Cluster.metadata.name }}"},
+					{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind), Name: randomString(),
+						Optional: true},
+				},
+			},
+		}
+	})
+
+	getProfileRef := func() *corev1.ObjectReference {
+		return &corev1.ObjectReference{Kind: configv1beta1.ProfileKind, APIVersion: configv1beta1.GroupVersion.String(),
+			Namespace: profile.Namespace, Name: profile.Name}
+	}
+
+	It("exportProfileBundle collects dependencies and referenced resources", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret, base, profile).Build()
+
+		bundle, err := server.ExportProfileBundle(context.TODO(), c, getProfileRef(), true)
+		Expect(err).To(BeNil())
+		// Templated and missing references
+		Expect(bundle.Warnings).To(HaveLen(2))
+
+		// Referenced resources first, then profiles with dependencies first
+		Expect(bundle.Objects).To(HaveLen(4))
+		Expect(bundle.Objects[0].GetName()).To(Equal(secret.Name))
+		Expect(bundle.Objects[1].GetName()).To(Equal(configMap.Name))
+		Expect(bundle.Objects[2].GetName()).To(Equal(base.Name))
+		Expect(bundle.Objects[3].GetName()).To(Equal(profile.Name))
+
+		for i := range bundle.Objects {
+			Expect(bundle.Objects[i].GetResourceVersion()).To(BeEmpty())
+			Expect(bundle.Objects[i].GetObjectKind().GroupVersionKind().Kind).ToNot(BeEmpty())
+		}
+
+		redacted, ok := bundle.Objects[0].(*corev1.Secret)
+		Expect(ok).To(BeTrue())
+		Expect(redacted.Data["policy.yaml"]).To(BeEmpty())
+		Expect(redacted.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/redacted", "true"))
+
+		// Secret in the cluster is not modified
+		current := &corev1.Secret{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: secret.Name}, current)).To(Succeed())
+		Expect(current.Data).To(Equal(secret.Data))
+
+		_, err = server.ExportProfileBundle(context.TODO(), c,
+			&corev1.ObjectReference{Kind: configv1beta1.ProfileKind, Namespace: namespace, Name: randomString()}, false)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("encodeProfileBundle and decodeProfileBundle support YAML and tar", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret, base, profile).Build()
+
+		bundle, err := server.ExportProfileBundle(context.TODO(), c, getProfileRef(), false)
+		Expect(err).To(BeNil())
+
+		for _, format := range []string{server.BundleFormatYAML, server.BundleFormatTar} {
+			data, err := server.EncodeProfileBundle(bundle, format)
+			Expect(err).To(BeNil())
+
+			objects, validationErrors := server.DecodeProfileBundle(data)
+			Expect(validationErrors).To(BeNil())
+			Expect(objects).To(HaveLen(len(bundle.Objects)))
+			for i := range objects {
+				Expect(objects[i].GetObjectKind().GroupVersionKind()).To(
+					Equal(bundle.Objects[i].GetObjectKind().GroupVersionKind()))
+				Expect(objects[i].GetName()).To(Equal(bundle.Objects[i].GetName()))
+			}
+			decodedSecret, ok := objects[0].(*corev1.Secret)
+			Expect(ok).To(BeTrue())
+			Expect(decodedSecret.Data).To(Equal(secret.Data))
+		}
+
+		_, validationErrors := server.DecodeProfileBundle([]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\n"))
+		Expect(validationErrors).To(HaveLen(1))
+
+		_, validationErrors = server.DecodeProfileBundle([]byte("# empty\n"))
+		Expect(validationErrors).To(HaveLen(1))
+	})
+
+	It("importProfileBundle validates references and dry-runs before applying", func() {
+		source := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret, base, profile).Build()
+
+		bundle, err := server.ExportProfileBundle(context.TODO(), source, getProfileRef(), true)
+		Expect(err).To(BeNil())
+
+		// Redacted Secret does not exist in the destination
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		result, err := server.ImportProfileBundle(context.TODO(), c, bundle.Objects, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(result.ValidationErrors).To(HaveLen(1))
+
+		// Profile depends on a profile which is neither in the bundle nor exists
+		result, err = server.ImportProfileBundle(context.TODO(), c, []client.Object{bundle.Objects[3]}, false)
+		Expect(err).To(BeNil())
+		Expect(result.ValidationErrors).To(HaveLen(2))
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+		result, err = server.ImportProfileBundle(context.TODO(), c, bundle.Objects, true)
+		Expect(err).To(BeNil())
+		Expect(result.ValidationErrors).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(result.Objects).To(HaveLen(4))
+		Expect(result.Objects[0].Action).To(Equal(server.BundleActionSkip))
+		Expect(result.Objects[3].Action).To(Equal(server.BundleActionCreate))
+
+		// Nothing is created on dry-run
+		current := &configv1beta1.Profile{}
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: profile.Name}, current)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		result, err = server.ImportProfileBundle(context.TODO(), c, bundle.Objects, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: profile.Name}, current)).To(Succeed())
+		Expect(current.Spec.DependsOn).To(Equal([]string{base.Name}))
+
+		// Secret is left untouched
+		currentSecret := &corev1.Secret{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: secret.Name},
+			currentSecret)).To(Succeed())
+		Expect(currentSecret.Data).To(Equal(secret.Data))
+
+		// Importing again updates existing objects
+		result, err = server.ImportProfileBundle(context.TODO(), c, bundle.Objects, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.Objects[3].Action).To(Equal(server.BundleActionUpdate))
+	})
+
+	It("importProfileBundle stops at the first object which fails to apply", func() {
+		source := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret, base, profile).Build()
+
+		bundle, err := server.ExportProfileBundle(context.TODO(), source, getProfileRef(), true)
+		Expect(err).To(BeNil())
+
+		// Creating the profile depending on base fails, only when not a dry-run
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				createOptions := &client.CreateOptions{}
+				createOptions.ApplyOptions(opts)
+				if obj.GetName() == base.Name && len(createOptions.DryRun) == 0 {
+					return errors.New("injected error")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+
+		result, err := server.ImportProfileBundle(context.TODO(), c, bundle.Objects, false)
+		Expect(err).ToNot(BeNil())
+		Expect(result).ToNot(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(result.Error).To(ContainSubstring("injected error"))
+		Expect(result.Objects).To(HaveLen(4))
+		// Redacted Secret is skipped, ConfigMap is applied, base fails and profile is never applied
+		Expect(result.Objects[0].Applied).To(BeFalse())
+		Expect(result.Objects[1].Applied).To(BeTrue())
+		Expect(result.Objects[2].Applied).To(BeFalse())
+		Expect(result.Objects[3].Applied).To(BeFalse())
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: configMap.Name},
+			&corev1.ConfigMap{})).To(Succeed())
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: profile.Name},
+			&configv1beta1.Profile{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("getBundleOptionsFromQuery redacts Secrets unless explicitly disabled", func() {
+		gin.SetMode(gin.TestMode)
+		getRedactSecrets := func(url string) (bool, error) {
+			req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
+			Expect(err).To(BeNil())
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = req
+			_, redactSecrets, err := server.GetBundleOptionsFromQuery(c)
+			return redactSecrets, err
+		}
+
+		redactSecrets, err := getRedactSecrets("/profilebundle")
+		Expect(err).To(BeNil())
+		Expect(redactSecrets).To(BeTrue())
+
+		redactSecrets, err = getRedactSecrets("/profilebundle?redactSecrets=false")
+		Expect(err).To(BeNil())
+		Expect(redactSecrets).To(BeFalse())
+
+		_, err = getRedactSecrets("/profilebundle?redactSecrets=maybe")
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,167 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+)
+
+// CAPIClusterInfo contains information specific to ClusterAPI powered clusters
+type CAPIClusterInfo struct {
+	// Phase is the ClusterAPI Cluster phase (Pending, Provisioning, Provisioned, Deleting, Failed, Unknown)
+	Phase string `json:"phase"`
+
+	// Paused is true when Sveltos (and ClusterAPI) stop reconciling the cluster
+	Paused bool `json:"paused"`
+
+	InfrastructureProvider *ProviderRef `json:"infrastructureProvider,omitempty"`
+	ControlPlaneProvider   *ProviderRef `json:"controlPlaneProvider,omitempty"`
+
+	MachineDeployments []MachineDeploymentInfo `json:"machineDeployments"`
+	MachinePools       []MachinePoolInfo       `json:"machinePools"`
+}
+
+// ProviderRef references the infrastructure/control plane object of a ClusterAPI Cluster
+type ProviderRef struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Name       string `json:"name"`
+}
+
+type MachineDeploymentInfo struct {
+	Name              string `json:"name"`
+	Phase             string `json:"phase"`
+	DesiredReplicas   *int32 `json:"desiredReplicas,omitempty"`
+	Replicas          int32  `json:"replicas"`
+	ReadyReplicas     int32  `json:"readyReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+	UpdatedReplicas   int32  `json:"updatedReplicas"`
+}
+
+type MachinePoolInfo struct {
+	Name              string `json:"name"`
+	Phase             string `json:"phase"`
+	DesiredReplicas   *int32 `json:"desiredReplicas,omitempty"`
+	Replicas          int32  `json:"replicas"`
+	ReadyReplicas     int32  `json:"readyReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+}
+
+// CAPIClusterResources contains the ClusterAPI resources related to a Cluster
+type CAPIClusterResources struct {
+	// ControlPlane is the object referenced by Cluster Spec.ControlPlaneRef
+	ControlPlane *unstructured.Unstructured
+
+	MachineDeployments []clusterv1.MachineDeployment
+	MachinePools       []expv1.MachinePool
+}
+
+// getCAPIClusterInfo returns ClusterInfo for a ClusterAPI powered cluster. resources can be nil.
+func getCAPIClusterInfo(cluster *clusterv1.Cluster, resources *CAPIClusterResources) ClusterInfo {
+	info := ClusterInfo{
+		Labels:         cluster.Labels,
+		Version:        getCAPIClusterVersion(cluster, resources),
+		Ready:          cluster.Status.ControlPlaneReady,
+		FailureMessage: examineClusterConditions(cluster),
+		Conditions:     getCAPIClusterConditions(cluster),
+		CAPI: &CAPIClusterInfo{
+			Phase:                  cluster.Status.Phase,
+			Paused:                 cluster.Spec.Paused,
+			InfrastructureProvider: getProviderRef(cluster.Spec.InfrastructureRef),
+			ControlPlaneProvider:   getProviderRef(cluster.Spec.ControlPlaneRef),
+			MachineDeployments:     make([]MachineDeploymentInfo, 0),
+			MachinePools:           make([]MachinePoolInfo, 0),
+		},
+	}
+
+	if resources == nil {
+		return info
+	}
+
+	for i := range resources.MachineDeployments {
+		md := &resources.MachineDeployments[i]
+		info.CAPI.MachineDeployments = append(info.CAPI.MachineDeployments, MachineDeploymentInfo{
+			Name:              md.Name,
+			Phase:             md.Status.Phase,
+			DesiredReplicas:   md.Spec.Replicas,
+			Replicas:          md.Status.Replicas,
+			ReadyReplicas:     md.Status.ReadyReplicas,
+			AvailableReplicas: md.Status.AvailableReplicas,
+			UpdatedReplicas:   md.Status.UpdatedReplicas,
+		})
+	}
+
+	for i := range resources.MachinePools {
+		mp := &resources.MachinePools[i]
+		info.CAPI.MachinePools = append(info.CAPI.MachinePools, MachinePoolInfo{
+			Name:              mp.Name,
+			Phase:             mp.Status.Phase,
+			DesiredReplicas:   mp.Spec.Replicas,
+			Replicas:          mp.Status.Replicas,
+			ReadyReplicas:     mp.Status.ReadyReplicas,
+			AvailableReplicas: mp.Status.AvailableReplicas,
+		})
+	}
+
+	sort.Slice(info.CAPI.MachineDeployments, func(i, j int) bool {
+		return info.CAPI.MachineDeployments[i].Name < info.CAPI.MachineDeployments[j].Name
+	})
+	sort.Slice(info.CAPI.MachinePools, func(i, j int) bool {
+		return info.CAPI.MachinePools[i].Name < info.CAPI.MachinePools[j].Name
+	})
+
+	return info
+}
+
+// getCAPIClusterVersion returns the Kubernetes version reported by the control plane object.
+// Falls back to the version requested by the control plane object and then to the ClusterClass
+// topology version.
+func getCAPIClusterVersion(cluster *clusterv1.Cluster, resources *CAPIClusterResources) string {
+	if resources != nil && resources.ControlPlane != nil {
+		version, found, err := unstructured.NestedString(resources.ControlPlane.Object, "status", "version")
+		if err == nil && found && version != "" {
+			return version
+		}
+		version, found, err = unstructured.NestedString(resources.ControlPlane.Object, "spec", "version")
+		if err == nil && found && version != "" {
+			return version
+		}
+	}
+
+	if cluster.Spec.Topology != nil {
+		return cluster.Spec.Topology.Version
+	}
+
+	return ""
+}
+
+func getProviderRef(ref *corev1.ObjectReference) *ProviderRef {
+	if ref == nil {
+		return nil
+	}
+
+	return &ProviderRef{
+		Kind:       ref.Kind,
+		APIVersion: ref.APIVersion,
+		Name:       ref.Name,
+	}
+}
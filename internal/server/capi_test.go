@@ -0,0 +1,84 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("CAPI", func() {
+	It("getCAPIClusterInfo reports phase, providers, version and machines", func() {
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+			Spec: clusterv1.ClusterSpec{
+				InfrastructureRef: &corev1.ObjectReference{
+					Kind: "DockerCluster", APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1", Name: randomString(),
+				},
+				ControlPlaneRef: &corev1.ObjectReference{
+					Kind: "KubeadmControlPlane", APIVersion: "controlplane.cluster.x-k8s.io/v1beta1", Name: randomString(),
+				},
+			},
+			Status: clusterv1.ClusterStatus{Phase: string(clusterv1.ClusterPhaseProvisioned)},
+		}
+
+		// Without resources and topology, version is unknown
+		info := server.GetCAPIClusterInfo(cluster, nil)
+		Expect(info.Version).To(BeEmpty())
+		Expect(info.CAPI).ToNot(BeNil())
+		Expect(info.CAPI.Phase).To(Equal(string(clusterv1.ClusterPhaseProvisioned)))
+		Expect(info.CAPI.InfrastructureProvider.Kind).To(Equal("DockerCluster"))
+		Expect(info.CAPI.ControlPlaneProvider.Kind).To(Equal("KubeadmControlPlane"))
+
+		controlPlane := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec":   map[string]interface{}{"version": "v1.32.0"},
+			"status": map[string]interface{}{"version": "v1.31.2"},
+		}}
+
+		resources := &server.CAPIClusterResources{
+			ControlPlane: controlPlane,
+			MachineDeployments: []clusterv1.MachineDeployment{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "md-b"},
+					Spec:       clusterv1.MachineDeploymentSpec{Replicas: ptr.To(int32(3))},
+					Status:     clusterv1.MachineDeploymentStatus{Replicas: 3, ReadyReplicas: 2},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "md-a"},
+				},
+			},
+		}
+
+		// Version currently reported by the control plane wins
+		info = server.GetCAPIClusterInfo(cluster, resources)
+		Expect(info.Version).To(Equal("v1.31.2"))
+		Expect(len(info.CAPI.MachineDeployments)).To(Equal(2))
+		Expect(info.CAPI.MachineDeployments[0].Name).To(Equal("md-a"))
+		Expect(info.CAPI.MachineDeployments[1].Name).To(Equal("md-b"))
+		Expect(*info.CAPI.MachineDeployments[1].DesiredReplicas).To(Equal(int32(3)))
+		Expect(info.CAPI.MachineDeployments[1].ReadyReplicas).To(Equal(int32(2)))
+		Expect(len(info.CAPI.MachinePools)).To(Equal(0))
+	})
+})
@@ -0,0 +1,649 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// changeRequestNamespace is the namespace ConfigMaps storing change requests are created in.
+	// The manager client does not cache ConfigMaps, so the store is always read from the API server.
+	changeRequestNamespace = "projectsveltos"
+
+	// changeRequestLabel identifies ConfigMaps storing change requests
+	changeRequestLabel = "ui.projectsveltos.io/change-request"
+
+	// changeRequestKey is the ConfigMap data key containing the change request
+	changeRequestKey = "changeRequest"
+
+	changeRequestNamePrefix = "changerequest-"
+
+	// maxPendingChangeRequests is the maximum number of pending change requests a user can have
+	// for the same ClusterProfile/Profile
+	maxPendingChangeRequests = 5
+
+	// changeRequestRetention is how long applied, rejected and failed change requests are kept
+	changeRequestRetention = 30 * 24 * time.Hour
+)
+
+// changeRequestResource is used to report change request errors
+var changeRequestResource = schema.GroupResource{Group: "ui.projectsveltos.io", Resource: "changerequests"}
+
+type ChangeRequestState string
+
+const (
+	ChangeRequestStatePending  = ChangeRequestState("Pending")
+	ChangeRequestStateApproved = ChangeRequestState("Approved")
+	ChangeRequestStateApplied  = ChangeRequestState("Applied")
+	ChangeRequestStateRejected = ChangeRequestState("Rejected")
+	ChangeRequestStateFailed   = ChangeRequestState("Failed")
+)
+
+type ChangeRequestAction string
+
+const (
+	ChangeRequestActionCreated  = ChangeRequestAction("Created")
+	ChangeRequestActionApproved = ChangeRequestAction("Approved")
+	ChangeRequestActionRejected = ChangeRequestAction("Rejected")
+	ChangeRequestActionApplied  = ChangeRequestAction("Applied")
+	ChangeRequestActionFailed   = ChangeRequestAction("Failed")
+)
+
+// ChangeRequestEvent is an entry of the change request audit trail
+type ChangeRequestEvent struct {
+	Time    metav1.Time         `json:"time"`
+	User    string              `json:"user"`
+	Action  ChangeRequestAction `json:"action"`
+	Comment string              `json:"comment,omitempty"`
+}
+
+// ChangeRequest is a proposed change to the spec of a ClusterProfile/Profile. The change is applied
+// only once approved by a user, other than the requester, allowed to update the profile.
+type ChangeRequest struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	RequestedBy string      `json:"requestedBy"`
+	CreatedAt   metav1.Time `json:"createdAt"`
+	Description string      `json:"description,omitempty"`
+
+	State ChangeRequestState `json:"state"`
+
+	// BaseResourceVersion is the resourceVersion of the profile the change was proposed against.
+	// The change is not applied if the profile changed in the meantime.
+	BaseResourceVersion string `json:"baseResourceVersion"`
+
+	// Spec is the proposed ClusterProfile/Profile spec
+	Spec configv1beta1.Spec `json:"spec"`
+
+	// ValidationErrors lists why the API server rejected the change on approval
+	ValidationErrors []ValidationError `json:"validationErrors,omitempty"`
+
+	// AuditTrail contains all actions taken on the change request, oldest first
+	AuditTrail []ChangeRequestEvent `json:"auditTrail"`
+}
+
+// ChangeRequestDetail contains a change request along with its impact, computed against the
+// current profile
+type ChangeRequestDetail struct {
+	ChangeRequest `json:",inline"`
+
+	// Outdated is true when the profile changed after the change request was created.
+	// An outdated change request cannot be applied.
+	Outdated bool `json:"outdated"`
+
+	// Changes are the differences between the current and the proposed spec
+	Changes []SpecChange `json:"changes"`
+
+	// AffectedClusters are the clusters matching the profile either before or after the change
+	AffectedClusters []corev1.ObjectReference `json:"affectedClusters"`
+
+	// AddedClusters start matching the profile because of the change
+	AddedClusters []corev1.ObjectReference `json:"addedClusters"`
+
+	// RemovedClusters stop matching the profile because of the change
+	RemovedClusters []corev1.ObjectReference `json:"removedClusters"`
+}
+
+type ChangeRequestsResult struct {
+	TotalChangeRequests int             `json:"totalChangeRequests"`
+	ChangeRequests      []ChangeRequest `json:"changeRequests"`
+}
+
+// ChangeRequestReview is the body of an approve/reject request
+type ChangeRequestReview struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+func getProfileRefFromChangeRequest(changeRequest *ChangeRequest) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:       changeRequest.Kind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+		Namespace:  changeRequest.Namespace,
+		Name:       changeRequest.Name,
+	}
+}
+
+// getProfileObject returns the ClusterProfile/Profile and a pointer to its spec
+func getProfileObject(ctx context.Context, c client.Client, profileRef *corev1.ObjectReference,
+) (client.Object, *configv1beta1.Spec, error) {
+
+	var profile client.Object
+	var spec *configv1beta1.Spec
+	if profileRef.Kind == configv1beta1.ClusterProfileKind {
+		clusterProfile := &configv1beta1.ClusterProfile{}
+		profile, spec = clusterProfile, &clusterProfile.Spec
+	} else {
+		p := &configv1beta1.Profile{}
+		profile, spec = p, &p.Spec
+	}
+
+	err := c.Get(ctx, types.NamespacedName{Namespace: profileRef.Namespace, Name: profileRef.Name}, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return profile, spec, nil
+}
+
+// getProfileSpec returns the spec of a ClusterProfile/Profile
+func getProfileSpec(profile client.Object) (*configv1beta1.Spec, error) {
+	switch p := profile.(type) {
+	case *configv1beta1.ClusterProfile:
+		return &p.Spec, nil
+	case *configv1beta1.Profile:
+		return &p.Spec, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", profile)
+	}
+}
+
+// createChangeRequest stores a change request proposing profile spec. The profile must exist.
+// A user cannot have more than maxPendingChangeRequests pending change requests for the same profile.
+// Change requests closed more than changeRequestRetention ago are removed.
+func createChangeRequest(ctx context.Context, c client.Client, profile client.Object, description, user string,
+	now time.Time) (*ChangeRequest, error) {
+
+	proposedSpec, err := getProfileSpec(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	profileRef := &corev1.ObjectReference{
+		Kind:      profile.GetObjectKind().GroupVersionKind().Kind,
+		Namespace: profile.GetNamespace(),
+		Name:      profile.GetName(),
+	}
+	current, currentSpec, err := getProfileObject(ctx, c, profileRef)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffSpecs(currentSpec, proposedSpec)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, apierrors.NewBadRequest("proposed spec does not change the profile")
+	}
+
+	changeRequests, err := listChangeRequests(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	removeExpiredChangeRequests(ctx, c, changeRequests, now)
+
+	if countPendingChangeRequests(changeRequests, profileRef, user) >= maxPendingChangeRequests {
+		return nil, apierrors.NewTooManyRequests(
+			fmt.Sprintf("user has already %d pending change requests for this profile", maxPendingChangeRequests), 0)
+	}
+
+	id := make([]byte, 8)
+	// crypto/rand Read never returns an error
+	_, _ = rand.Read(id)
+
+	changeRequest := &ChangeRequest{
+		ID:                  hex.EncodeToString(id),
+		Kind:                profileRef.Kind,
+		Namespace:           profileRef.Namespace,
+		Name:                profileRef.Name,
+		RequestedBy:         user,
+		CreatedAt:           metav1.NewTime(now),
+		Description:         description,
+		State:               ChangeRequestStatePending,
+		BaseResourceVersion: current.GetResourceVersion(),
+		Spec:                *proposedSpec,
+		AuditTrail: []ChangeRequestEvent{
+			{Time: metav1.NewTime(now), User: user, Action: ChangeRequestActionCreated, Comment: description},
+		},
+	}
+
+	data, err := json.Marshal(changeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: changeRequestNamespace,
+			Name:      changeRequestNamePrefix + changeRequest.ID,
+			Labels:    map[string]string{changeRequestLabel: "ok"},
+		},
+		Data: map[string]string{changeRequestKey: string(data)},
+	}
+
+	if err := c.Create(ctx, configMap); err != nil {
+		return nil, err
+	}
+
+	return changeRequest, nil
+}
+
+// countPendingChangeRequests returns the number of pending change requests user created for the profile
+func countPendingChangeRequests(changeRequests []ChangeRequest, profileRef *corev1.ObjectReference,
+	user string) int {
+
+	count := 0
+	for i := range changeRequests {
+		cr := &changeRequests[i]
+		if cr.State == ChangeRequestStatePending && cr.RequestedBy == user && cr.Kind == profileRef.Kind &&
+			cr.Namespace == profileRef.Namespace && cr.Name == profileRef.Name {
+
+			count++
+		}
+	}
+	return count
+}
+
+// isChangeRequestExpired returns true if the change request was applied, rejected or failed
+// more than changeRequestRetention ago
+func isChangeRequestExpired(changeRequest *ChangeRequest, now time.Time) bool {
+	switch changeRequest.State {
+	case ChangeRequestStateApplied, ChangeRequestStateRejected, ChangeRequestStateFailed:
+	default:
+		return false
+	}
+
+	closedAt := changeRequest.CreatedAt.Time
+	if len(changeRequest.AuditTrail) > 0 {
+		closedAt = changeRequest.AuditTrail[len(changeRequest.AuditTrail)-1].Time.Time
+	}
+
+	return now.Sub(closedAt) > changeRequestRetention
+}
+
+// removeExpiredChangeRequests deletes the ConfigMaps storing expired change requests. This is
+// best effort: change requests failing to be deleted are removed next time.
+func removeExpiredChangeRequests(ctx context.Context, c client.Client, changeRequests []ChangeRequest,
+	now time.Time) {
+
+	for i := range changeRequests {
+		if !isChangeRequestExpired(&changeRequests[i], now) {
+			continue
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: changeRequestNamespace,
+				Name:      changeRequestNamePrefix + changeRequests[i].ID,
+			},
+		}
+		_ = c.Delete(ctx, configMap)
+	}
+}
+
+// getChangeRequest returns the change request along with the ConfigMap storing it
+func getChangeRequest(ctx context.Context, c client.Client, id string) (*ChangeRequest, *corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: changeRequestNamespace, Name: changeRequestNamePrefix + id},
+		configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, apierrors.NewNotFound(changeRequestResource, id)
+		}
+		return nil, nil, err
+	}
+
+	changeRequest, err := decodeChangeRequest(configMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return changeRequest, configMap, nil
+}
+
+func decodeChangeRequest(configMap *corev1.ConfigMap) (*ChangeRequest, error) {
+	changeRequest := &ChangeRequest{}
+	if err := json.Unmarshal([]byte(configMap.Data[changeRequestKey]), changeRequest); err != nil {
+		return nil, fmt.Errorf("failed to decode change request %s: %w", configMap.Name, err)
+	}
+	return changeRequest, nil
+}
+
+// listChangeRequests returns all change requests, most recent first
+func listChangeRequests(ctx context.Context, c client.Client) ([]ChangeRequest, error) {
+	configMaps := &corev1.ConfigMapList{}
+	err := c.List(ctx, configMaps, client.InNamespace(changeRequestNamespace),
+		client.MatchingLabels{changeRequestLabel: "ok"})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ChangeRequest, 0, len(configMaps.Items))
+	for i := range configMaps.Items {
+		changeRequest, err := decodeChangeRequest(&configMaps.Items[i])
+		if err != nil {
+			continue
+		}
+		result = append(result, *changeRequest)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(&result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt.Time)
+	})
+
+	return result, nil
+}
+
+// saveChangeRequest updates the ConfigMap storing the change request. The update fails if the
+// change request was modified since configMap was read.
+func saveChangeRequest(ctx context.Context, c client.Client, configMap *corev1.ConfigMap,
+	changeRequest *ChangeRequest) error {
+
+	data, err := json.Marshal(changeRequest)
+	if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[changeRequestKey] = string(data)
+
+	return c.Update(ctx, configMap)
+}
+
+// getPendingChangeRequest returns the change request if it is still pending
+func getPendingChangeRequest(ctx context.Context, c client.Client, id string,
+) (*ChangeRequest, *corev1.ConfigMap, error) {
+
+	changeRequest, configMap, err := getChangeRequest(ctx, c, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if changeRequest.State != ChangeRequestStatePending {
+		return nil, nil, apierrors.NewConflict(changeRequestResource, id,
+			fmt.Errorf("change request is %s", changeRequest.State))
+	}
+
+	return changeRequest, configMap, nil
+}
+
+// approveChangeRequest approves a pending change request and applies the proposed spec using client c,
+// which impersonates the approver. The requester cannot approve its own change request.
+// The approval is recorded before the change is applied, so concurrent approvals cannot apply it twice.
+// Once the change is applied, the change request is returned even if its new state could not be saved.
+func approveChangeRequest(ctx context.Context, store, c client.Client, id, user, comment string,
+	now time.Time) (*ChangeRequest, error) {
+
+	changeRequest, configMap, err := getPendingChangeRequest(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if changeRequest.RequestedBy == user {
+		return nil, apierrors.NewForbidden(changeRequestResource, id,
+			fmt.Errorf("change request cannot be approved by its requester"))
+	}
+
+	changeRequest.State = ChangeRequestStateApproved
+	changeRequest.AuditTrail = append(changeRequest.AuditTrail,
+		ChangeRequestEvent{Time: metav1.NewTime(now), User: user, Action: ChangeRequestActionApproved, Comment: comment})
+	if err := saveChangeRequest(ctx, store, configMap, changeRequest); err != nil {
+		return nil, err
+	}
+
+	var profile client.Object
+	if changeRequest.Kind == configv1beta1.ClusterProfileKind {
+		profile = &configv1beta1.ClusterProfile{Spec: changeRequest.Spec}
+	} else {
+		profile = &configv1beta1.Profile{Spec: changeRequest.Spec}
+	}
+	profile.GetObjectKind().SetGroupVersionKind(configv1beta1.GroupVersion.WithKind(changeRequest.Kind))
+	profile.SetNamespace(changeRequest.Namespace)
+	profile.SetName(changeRequest.Name)
+	profile.SetResourceVersion(changeRequest.BaseResourceVersion)
+
+	result, applyErr := updateProfileObject(ctx, c, profile, false)
+
+	event := ChangeRequestEvent{Time: metav1.NewTime(time.Now()), User: user, Action: ChangeRequestActionApplied}
+	switch {
+	case applyErr != nil:
+		event.Action = ChangeRequestActionFailed
+		event.Comment = applyErr.Error()
+		if apierrors.IsConflict(applyErr) {
+			event.Comment = "profile changed after the change request was created"
+		}
+	case len(result.ValidationErrors) > 0:
+		event.Action = ChangeRequestActionFailed
+		changeRequest.ValidationErrors = result.ValidationErrors
+		messages := make([]string, len(result.ValidationErrors))
+		for i := range result.ValidationErrors {
+			messages[i] = result.ValidationErrors[i].Message
+		}
+		event.Comment = strings.Join(messages, "; ")
+	}
+
+	changeRequest.State = ChangeRequestStateApplied
+	if event.Action == ChangeRequestActionFailed {
+		changeRequest.State = ChangeRequestStateFailed
+	}
+	changeRequest.AuditTrail = append(changeRequest.AuditTrail, event)
+
+	// The profile might have been changed already, so the outcome is returned even if it cannot be recorded
+	if err := saveChangeRequestOutcome(ctx, store, configMap, changeRequest); err != nil {
+		if applyErr != nil {
+			return changeRequest, applyErr
+		}
+		return changeRequest, fmt.Errorf("change request is %s but its state could not be saved: %w",
+			changeRequest.State, err)
+	}
+
+	return changeRequest, applyErr
+}
+
+// saveChangeRequestOutcome records whether an approved change request was applied. On conflict,
+// the ConfigMap is read again and the update retried.
+func saveChangeRequestOutcome(ctx context.Context, store client.Client, configMap *corev1.ConfigMap,
+	changeRequest *ChangeRequest) error {
+
+	err := saveChangeRequest(ctx, store, configMap, changeRequest)
+	if !apierrors.IsConflict(err) {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		_, current, err := getChangeRequest(ctx, store, changeRequest.ID)
+		if err != nil {
+			return err
+		}
+		return saveChangeRequest(ctx, store, current, changeRequest)
+	})
+}
+
+// rejectChangeRequest rejects a pending change request
+func rejectChangeRequest(ctx context.Context, store client.Client, id, user, comment string,
+	now time.Time) (*ChangeRequest, error) {
+
+	changeRequest, configMap, err := getPendingChangeRequest(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changeRequest.State = ChangeRequestStateRejected
+	changeRequest.AuditTrail = append(changeRequest.AuditTrail,
+		ChangeRequestEvent{Time: metav1.NewTime(now), User: user, Action: ChangeRequestActionRejected, Comment: comment})
+	if err := saveChangeRequest(ctx, store, configMap, changeRequest); err != nil {
+		return nil, err
+	}
+
+	return changeRequest, nil
+}
+
+// getChangeRequestDetail computes the changes and the affected clusters of a change request
+// against the current profile. Only clusters in clusters are considered.
+func (m *instance) getChangeRequestDetail(ctx context.Context, changeRequest *ChangeRequest,
+	clusters map[corev1.ObjectReference]ClusterInfo) (*ChangeRequestDetail, error) {
+
+	profileRef := getProfileRefFromChangeRequest(changeRequest)
+	current, currentSpec, err := getProfileObject(ctx, m.client, profileRef)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffSpecs(currentSpec, &changeRequest.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	currentClusters := m.getProfileSpecClusters(profileRef, currentSpec, clusters)
+	proposedClusters := m.getProfileSpecClusters(profileRef, &changeRequest.Spec, clusters)
+
+	addedClusters := getMissingClusterRefs(proposedClusters, currentClusters)
+
+	affectedClusters := make([]corev1.ObjectReference, 0, len(currentClusters)+len(addedClusters))
+	affectedClusters = append(affectedClusters, currentClusters...)
+	affectedClusters = append(affectedClusters, addedClusters...)
+	sortClusterRefs(affectedClusters)
+
+	return &ChangeRequestDetail{
+		ChangeRequest:    *changeRequest,
+		Outdated:         current.GetResourceVersion() != changeRequest.BaseResourceVersion,
+		Changes:          changes,
+		AffectedClusters: affectedClusters,
+		AddedClusters:    addedClusters,
+		RemovedClusters:  getMissingClusterRefs(currentClusters, proposedClusters),
+	}, nil
+}
+
+// getProfileSpecClusters returns, among clusters, the ones a ClusterProfile/Profile with spec matches:
+// clusters whose labels match the ClusterSelector, clusters listed in ClusterRefs and clusters currently
+// selected by the referenced ClusterSets/Sets. Profiles only match clusters in their namespace.
+func (m *instance) getProfileSpecClusters(profileRef *corev1.ObjectReference, spec *configv1beta1.Spec,
+	clusters map[corev1.ObjectReference]ClusterInfo) []corev1.ObjectReference {
+
+	matching := make(map[corev1.ObjectReference]bool)
+	for k := range clusters {
+		if isSelectorMatching(&spec.ClusterSelector.LabelSelector, clusters[k].Labels) {
+			matching[k] = true
+		}
+	}
+
+	for i := range spec.ClusterRefs {
+		matching[*normalizeClusterRef(&spec.ClusterRefs[i])] = true
+	}
+
+	if len(spec.SetRefs) > 0 {
+		m.setMux.RLock()
+		setRef := corev1.ObjectReference{
+			Kind:       libsveltosv1beta1.ClusterSetKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+		if profileRef.Kind == configv1beta1.ProfileKind {
+			setRef.Kind = libsveltosv1beta1.SetKind
+			setRef.Namespace = profileRef.Namespace
+		}
+		for i := range spec.SetRefs {
+			setRef.Name = spec.SetRefs[i]
+			info := m.sets[setRef]
+			for j := range info.SelectedClusters {
+				matching[*normalizeClusterRef(&info.SelectedClusters[j])] = true
+			}
+		}
+		m.setMux.RUnlock()
+	}
+
+	result := make([]corev1.ObjectReference, 0, len(matching))
+	for k := range matching {
+		if profileRef.Kind == configv1beta1.ProfileKind && k.Namespace != profileRef.Namespace {
+			continue
+		}
+		if _, ok := clusters[k]; !ok {
+			continue
+		}
+		result = append(result, k)
+	}
+
+	sortClusterRefs(result)
+	return result
+}
+
+// filterChangeRequests returns the change requests matching filters and state (if set) whose
+// profile user can see
+func filterChangeRequests(changeRequests []ChangeRequest, filters *profileFilters, state string,
+	canGetProfile func(profileRef *corev1.ObjectReference) (bool, error)) []ChangeRequest {
+
+	result := make([]ChangeRequest, 0)
+	for i := range changeRequests {
+		if filters.Kind != "" && changeRequests[i].Kind != filters.Kind {
+			continue
+		}
+		if filters.Namespace != "" && !strings.Contains(changeRequests[i].Namespace, filters.Namespace) {
+			continue
+		}
+		if filters.Name != "" && !strings.Contains(changeRequests[i].Name, filters.Name) {
+			continue
+		}
+		if state != "" && string(changeRequests[i].State) != state {
+			continue
+		}
+
+		ok, err := canGetProfile(getProfileRefFromChangeRequest(&changeRequests[i]))
+		if err != nil || !ok {
+			continue
+		}
+
+		result = append(result, changeRequests[i])
+	}
+
+	return result
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Change requests", func() {
+	var logger logr.Logger
+	var clusterProfile *configv1beta1.ClusterProfile
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		clusterProfile = &configv1beta1.ClusterProfile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ClusterProfileKind, APIVersion: configv1beta1.GroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: configv1beta1.Spec{
+				SyncMode: configv1beta1.SyncModeContinuous,
+				ClusterSelector: libsveltosv1beta1.Selector{
+					LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}},
+				},
+			},
+		}
+	})
+
+	getProposedProfile := func() *configv1beta1.ClusterProfile {
+		proposed := clusterProfile.DeepCopy()
+		proposed.Spec.ClusterSelector.MatchLabels = map[string]string{"env": "production"}
+		return proposed
+	}
+
+	It("createChangeRequest stores proposed spec and rejects changes with no effect", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+
+		requester := randomString()
+		description := randomString()
+		changeRequest, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), description,
+			requester, time.Now())
+		Expect(err).To(BeNil())
+		Expect(changeRequest.State).To(Equal(server.ChangeRequestStatePending))
+		Expect(changeRequest.RequestedBy).To(Equal(requester))
+		Expect(changeRequest.BaseResourceVersion).ToNot(BeEmpty())
+		Expect(changeRequest.AuditTrail).To(HaveLen(1))
+		Expect(changeRequest.AuditTrail[0].Action).To(Equal(server.ChangeRequestActionCreated))
+
+		stored, _, err := server.GetChangeRequest(context.TODO(), c, changeRequest.ID)
+		Expect(err).To(BeNil())
+		Expect(stored.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "production"}))
+		Expect(stored.Description).To(Equal(description))
+
+		changeRequests, err := server.ListChangeRequests(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(changeRequests).To(HaveLen(1))
+
+		_, _, err = server.GetChangeRequest(context.TODO(), c, randomString())
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		// Proposed spec is identical to the current one
+		_, err = server.CreateChangeRequest(context.TODO(), c, clusterProfile, "", requester, time.Now())
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+	})
+
+	It("createChangeRequest limits pending change requests and removes expired ones", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+
+		requester := randomString()
+		for range server.MaxPendingChangeRequests {
+			_, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "", requester, time.Now())
+			Expect(err).To(BeNil())
+		}
+
+		_, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "", requester, time.Now())
+		Expect(apierrors.IsTooManyRequests(err)).To(BeTrue())
+
+		// Limit is per user
+		_, err = server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "", randomString(), time.Now())
+		Expect(err).To(BeNil())
+
+		changeRequests, err := server.ListChangeRequests(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(changeRequests).To(HaveLen(server.MaxPendingChangeRequests + 1))
+
+		// Rejected change requests no longer count and are removed once retention expires
+		rejectedAt := time.Now().Add(-server.ChangeRequestRetention - time.Hour)
+		for i := range changeRequests {
+			if changeRequests[i].RequestedBy == requester {
+				_, err = server.RejectChangeRequest(context.TODO(), c, changeRequests[i].ID, randomString(), "",
+					rejectedAt)
+				Expect(err).To(BeNil())
+				break
+			}
+		}
+
+		_, err = server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "", requester, time.Now())
+		Expect(err).To(BeNil())
+
+		changeRequests, err = server.ListChangeRequests(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(changeRequests).To(HaveLen(server.MaxPendingChangeRequests + 1))
+		for i := range changeRequests {
+			Expect(changeRequests[i].State).To(Equal(server.ChangeRequestStatePending))
+		}
+	})
+
+	It("approveChangeRequest applies the change only when approved by another user", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+
+		requester := randomString()
+		changeRequest, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "",
+			requester, time.Now())
+		Expect(err).To(BeNil())
+
+		// Requester cannot approve its own change request
+		_, err = server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, requester, "", time.Now())
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+
+		approver := randomString()
+		comment := randomString()
+		approved, err := server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, approver, comment,
+			time.Now())
+		Expect(err).To(BeNil())
+		Expect(approved.State).To(Equal(server.ChangeRequestStateApplied))
+		Expect(approved.AuditTrail).To(HaveLen(3))
+		Expect(approved.AuditTrail[1].Action).To(Equal(server.ChangeRequestActionApproved))
+		Expect(approved.AuditTrail[1].User).To(Equal(approver))
+		Expect(approved.AuditTrail[1].Comment).To(Equal(comment))
+		Expect(approved.AuditTrail[2].Action).To(Equal(server.ChangeRequestActionApplied))
+
+		current := &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "production"}))
+
+		// Change request is not pending anymore
+		_, err = server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, approver, "", time.Now())
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+		_, err = server.RejectChangeRequest(context.TODO(), c, changeRequest.ID, approver, "", time.Now())
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+	})
+
+	It("approveChangeRequest retries saving the outcome on conflict and returns it even if saving fails", func() {
+		// The first ConfigMap update records the approval, the following ones the outcome
+		configMapUpdates := 0
+		failOutcome := false
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).WithInterceptorFuncs(
+			interceptor.Funcs{
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if _, ok := obj.(*corev1.ConfigMap); ok {
+						configMapUpdates++
+						if configMapUpdates == 2 {
+							// ConfigMap is modified concurrently
+							current := &corev1.ConfigMap{}
+							Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), current)).To(Succeed())
+							current.Annotations = map[string]string{randomString(): randomString()}
+							Expect(c.Update(ctx, current)).To(Succeed())
+						} else if configMapUpdates > 2 && failOutcome {
+							return errors.New("injected error")
+						}
+					}
+					return c.Update(ctx, obj, opts...)
+				},
+			}).Build()
+
+		changeRequest, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "",
+			randomString(), time.Now())
+		Expect(err).To(BeNil())
+
+		approved, err := server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, randomString(), "",
+			time.Now())
+		Expect(err).To(BeNil())
+		Expect(approved.State).To(Equal(server.ChangeRequestStateApplied))
+
+		stored, _, err := server.GetChangeRequest(context.TODO(), c, changeRequest.ID)
+		Expect(err).To(BeNil())
+		Expect(stored.State).To(Equal(server.ChangeRequestStateApplied))
+
+		// Outcome cannot be saved
+		configMapUpdates = 0
+		failOutcome = true
+		clusterProfile = &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: stored.Name}, clusterProfile)).To(Succeed())
+		proposed := clusterProfile.DeepCopy()
+		proposed.Spec.ClusterSelector.MatchLabels = map[string]string{"env": "staging"}
+		changeRequest, err = server.CreateChangeRequest(context.TODO(), c, proposed, "", randomString(), time.Now())
+		Expect(err).To(BeNil())
+
+		approved, err = server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, randomString(), "",
+			time.Now())
+		Expect(err).ToNot(BeNil())
+		Expect(approved).ToNot(BeNil())
+		Expect(approved.State).To(Equal(server.ChangeRequestStateApplied))
+
+		current := &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "staging"}))
+	})
+
+	It("approveChangeRequest fails if profile changed after the change request was created", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+
+		changeRequest, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "",
+			randomString(), time.Now())
+		Expect(err).To(BeNil())
+
+		current := &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		current.Spec.SyncMode = configv1beta1.SyncModeOneTime
+		Expect(c.Update(context.TODO(), current)).To(Succeed())
+
+		failed, err := server.ApproveChangeRequest(context.TODO(), c, c, changeRequest.ID, randomString(), "",
+			time.Now())
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+		Expect(failed.State).To(Equal(server.ChangeRequestStateFailed))
+		Expect(failed.AuditTrail[len(failed.AuditTrail)-1].Action).To(Equal(server.ChangeRequestActionFailed))
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "staging"}))
+	})
+
+	It("rejectChangeRequest records the rejection and leaves the profile unchanged", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+
+		changeRequest, err := server.CreateChangeRequest(context.TODO(), c, getProposedProfile(), "",
+			randomString(), time.Now())
+		Expect(err).To(BeNil())
+
+		reviewer := randomString()
+		rejected, err := server.RejectChangeRequest(context.TODO(), c, changeRequest.ID, reviewer, randomString(),
+			time.Now())
+		Expect(err).To(BeNil())
+		Expect(rejected.State).To(Equal(server.ChangeRequestStateRejected))
+		Expect(rejected.AuditTrail[1].User).To(Equal(reviewer))
+
+		current := &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "staging"}))
+	})
+
+	It("getProfileSpecClusters returns clusters matching selector and clusterRefs", func() {
+		manager := server.NewManagerInstance(nil, scheme, logger)
+
+		namespace := randomString()
+		production := corev1.ObjectReference{Namespace: namespace, Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		staging := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		other := corev1.ObjectReference{Namespace: namespace, Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		clusters := map[corev1.ObjectReference]server.ClusterInfo{
+			production: {Labels: map[string]string{"env": "production"}},
+			staging:    {Labels: map[string]string{"env": "staging"}},
+			other:      {Labels: map[string]string{"env": "test"}},
+		}
+
+		spec := &configv1beta1.Spec{
+			ClusterSelector: libsveltosv1beta1.Selector{
+				LabelSelector: metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"production", "staging"}},
+					},
+				},
+			},
+			ClusterRefs: []corev1.ObjectReference{other},
+		}
+
+		profileRef := &corev1.ObjectReference{Kind: configv1beta1.ClusterProfileKind, Name: randomString()}
+		Expect(manager.GetProfileSpecClusters(profileRef, spec, clusters)).To(ConsistOf(production, staging, other))
+
+		// Profiles only match clusters in their namespace
+		profileRef = &corev1.ObjectReference{Kind: configv1beta1.ProfileKind, Namespace: namespace, Name: randomString()}
+		Expect(manager.GetProfileSpecClusters(profileRef, spec, clusters)).To(ConsistOf(production, other))
+	})
+})
@@ -0,0 +1,361 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+type ClassifierInfo struct {
+	// DeployedResourceConstraint contains the rules on resources deployed in the cluster
+	DeployedResourceConstraint *libsveltosv1beta1.DeployedResourceConstraint `json:"deployedResourceConstraint,omitempty"`
+
+	// KubernetesVersionConstraints contains the rules on the cluster Kubernetes version
+	KubernetesVersionConstraints []libsveltosv1beta1.KubernetesVersionConstraint `json:"kubernetesVersionConstraints"`
+
+	// ClassifierLabels are the labels applied to matching clusters
+	ClassifierLabels []libsveltosv1beta1.ClassifierLabel `json:"classifierLabels"`
+
+	// MatchingClusterStatuses contains, for each matching cluster, labels managed by this
+	// classifier and labels this classifier could not manage
+	MatchingClusterStatuses []libsveltosv1beta1.MachingClusterStatus `json:"matchingClusterStatuses"`
+}
+
+type classifierReportInfo struct {
+	ClassifierName   string
+	ClusterNamespace string
+	ClusterName      string
+	ClusterType      libsveltosv1beta1.ClusterType
+	Match            bool
+}
+
+type ClassifierResult struct {
+	Name string `json:"name"`
+
+	ClassifierInfo `json:",inline"`
+
+	// MatchingClusters are the clusters currently matching the classifier
+	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
+}
+
+type ClassifiersResult struct {
+	TotalClassifiers int                `json:"totalClassifiers"`
+	Classifiers      []ClassifierResult `json:"classifiers"`
+}
+
+// ConflictingClassifier is a Classifier which could not set a label on a cluster
+type ConflictingClassifier struct {
+	Name string `json:"name"`
+
+	// Value is the label value the classifier wants to set
+	Value string `json:"value"`
+
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+// ClassifierLabelConflict represents a label more than one classifier wants to set on a cluster
+type ClassifierLabelConflict struct {
+	Cluster corev1.ObjectReference `json:"cluster"`
+
+	// LabelKey is the key of the label in conflict
+	LabelKey string `json:"labelKey"`
+
+	// ManagingClassifier is the classifier currently managing the label. Empty if none.
+	ManagingClassifier string `json:"managingClassifier"`
+
+	// ConflictingClassifiers are the classifiers which could not set the label
+	ConflictingClassifiers []ConflictingClassifier `json:"conflictingClassifiers"`
+}
+
+type ClassifierConflictsResult struct {
+	TotalConflicts int                       `json:"totalConflicts"`
+	Conflicts      []ClassifierLabelConflict `json:"conflicts"`
+}
+
+// ClusterClassifier is a Classifier managing labels on a cluster
+type ClusterClassifier struct {
+	Name string `json:"name"`
+
+	// ManagedLabels are the labels (key and value) the classifier manages on the cluster
+	ManagedLabels map[string]string `json:"managedLabels"`
+}
+
+func (m *instance) AddClassifier(classifier *libsveltosv1beta1.Classifier) {
+	info := ClassifierInfo{
+		DeployedResourceConstraint:   classifier.Spec.DeployedResourceConstraint,
+		KubernetesVersionConstraints: classifier.Spec.KubernetesVersionConstraints,
+		ClassifierLabels:             classifier.Spec.ClassifierLabels,
+		MatchingClusterStatuses:      classifier.Status.MachingClusterStatuses,
+	}
+
+	m.classifierMux.Lock()
+	defer m.classifierMux.Unlock()
+
+	m.classifiers[classifier.Name] = info
+}
+
+func (m *instance) RemoveClassifier(classifierName string) {
+	m.classifierMux.Lock()
+	defer m.classifierMux.Unlock()
+
+	delete(m.classifiers, classifierName)
+}
+
+func (m *instance) AddClassifierReport(classifierReport *libsveltosv1beta1.ClassifierReport) {
+	info := classifierReportInfo{
+		ClassifierName:   classifierReport.Spec.ClassifierName,
+		ClusterNamespace: classifierReport.Spec.ClusterNamespace,
+		ClusterName:      classifierReport.Spec.ClusterName,
+		ClusterType:      classifierReport.Spec.ClusterType,
+		Match:            classifierReport.Spec.Match,
+	}
+
+	m.classifierMux.Lock()
+	defer m.classifierMux.Unlock()
+
+	m.classifierReports[*getKeyFromObject(m.scheme, classifierReport)] = info
+}
+
+func (m *instance) RemoveClassifierReport(classifierReportNamespace, classifierReportName string) {
+	classifierReport := &corev1.ObjectReference{
+		Namespace:  classifierReportNamespace,
+		Name:       classifierReportName,
+		Kind:       libsveltosv1beta1.ClassifierReportKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+
+	m.classifierMux.Lock()
+	defer m.classifierMux.Unlock()
+
+	delete(m.classifierReports, *classifierReport)
+}
+
+// getClassifiers returns all Classifiers whose name contains nameFilter, sorted by name.
+// Only clusters present in the clusters map are reported.
+func (m *instance) getClassifiers(nameFilter string, clusters map[corev1.ObjectReference]ClusterInfo,
+) []ClassifierResult {
+
+	m.classifierMux.RLock()
+	defer m.classifierMux.RUnlock()
+
+	matchingClusters := map[string][]corev1.ObjectReference{}
+	for k := range m.classifierReports {
+		report := m.classifierReports[k]
+		if !report.Match {
+			continue
+		}
+		clusterRef := getClusterRef(report.ClusterNamespace, report.ClusterName, report.ClusterType)
+		if _, ok := clusters[*clusterRef]; !ok {
+			continue
+		}
+		matchingClusters[report.ClassifierName] = append(matchingClusters[report.ClassifierName], *clusterRef)
+	}
+
+	result := make([]ClassifierResult, 0, len(m.classifiers))
+	for name := range m.classifiers {
+		if nameFilter != "" && !strings.Contains(name, nameFilter) {
+			continue
+		}
+
+		info := m.classifiers[name]
+		info.MatchingClusterStatuses = filterMatchingClusterStatuses(info.MatchingClusterStatuses, clusters)
+
+		refs := matchingClusters[name]
+		if refs == nil {
+			refs = make([]corev1.ObjectReference, 0)
+		}
+		sortClusterRefs(refs)
+
+		result = append(result, ClassifierResult{
+			Name:             name,
+			ClassifierInfo:   info,
+			MatchingClusters: refs,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getClassifierConflicts returns labels more than one Classifier wants to set on the same cluster.
+// Only clusters present in the clusters map are considered.
+func (m *instance) getClassifierConflicts(clusters map[corev1.ObjectReference]ClusterInfo,
+) []ClassifierLabelConflict {
+
+	m.classifierMux.RLock()
+	defer m.classifierMux.RUnlock()
+
+	return evaluateClassifierConflicts(m.classifiers, clusters)
+}
+
+// getClusterClassifiers returns the Classifiers managing labels on a cluster
+func (m *instance) getClusterClassifiers(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) []ClusterClassifier {
+
+	m.classifierMux.RLock()
+	defer m.classifierMux.RUnlock()
+
+	clusterRef := getClusterRef(clusterNamespace, clusterName, clusterType)
+
+	result := getClassifiersManagingCluster(m.classifiers, clusterRef)
+	if result == nil {
+		result = make([]ClusterClassifier, 0)
+	}
+	return result
+}
+
+// setClusterClassifiers sets the Classifiers managing labels on each cluster
+func (m *instance) setClusterClassifiers(clusters ManagedClusters, clusterType libsveltosv1beta1.ClusterType) {
+	m.classifierMux.RLock()
+	defer m.classifierMux.RUnlock()
+
+	for i := range clusters {
+		clusterRef := getClusterRef(clusters[i].Namespace, clusters[i].Name, clusterType)
+		clusters[i].Classifiers = getClassifiersManagingCluster(m.classifiers, clusterRef)
+	}
+}
+
+// getClassifiersManagingCluster returns, sorted by name, the Classifiers managing labels on a cluster.
+// Nil if no Classifier manages labels on the cluster.
+func getClassifiersManagingCluster(classifiers map[string]ClassifierInfo, clusterRef *corev1.ObjectReference,
+) []ClusterClassifier {
+
+	var result []ClusterClassifier
+	for name := range classifiers {
+		info := classifiers[name]
+		for i := range info.MatchingClusterStatuses {
+			status := &info.MatchingClusterStatuses[i]
+			if !isSameCluster(&status.ClusterRef, clusterRef) || len(status.ManagedLabels) == 0 {
+				continue
+			}
+
+			result = append(result, ClusterClassifier{
+				Name:          name,
+				ManagedLabels: getManagedLabels(info.ClassifierLabels, status.ManagedLabels),
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func evaluateClassifierConflicts(classifiers map[string]ClassifierInfo, clusters map[corev1.ObjectReference]ClusterInfo,
+) []ClassifierLabelConflict {
+
+	type conflictKey struct {
+		cluster  corev1.ObjectReference
+		labelKey string
+	}
+
+	conflicts := map[conflictKey]*ClassifierLabelConflict{}
+	managers := map[conflictKey]string{}
+
+	for name := range classifiers {
+		info := classifiers[name]
+		for i := range info.MatchingClusterStatuses {
+			status := &info.MatchingClusterStatuses[i]
+			clusterRef := normalizeClusterRef(&status.ClusterRef)
+			if _, ok := clusters[*clusterRef]; !ok {
+				continue
+			}
+
+			for _, labelKey := range status.ManagedLabels {
+				managers[conflictKey{cluster: *clusterRef, labelKey: labelKey}] = name
+			}
+
+			for j := range status.UnManagedLabels {
+				key := conflictKey{cluster: *clusterRef, labelKey: status.UnManagedLabels[j].Key}
+				conflict, ok := conflicts[key]
+				if !ok {
+					conflict = &ClassifierLabelConflict{
+						Cluster:                *clusterRef,
+						LabelKey:               key.labelKey,
+						ConflictingClassifiers: make([]ConflictingClassifier, 0),
+					}
+					conflicts[key] = conflict
+				}
+				conflict.ConflictingClassifiers = append(conflict.ConflictingClassifiers, ConflictingClassifier{
+					Name:           name,
+					Value:          getClassifierLabelValue(info.ClassifierLabels, key.labelKey),
+					FailureMessage: status.UnManagedLabels[j].FailureMessage,
+				})
+			}
+		}
+	}
+
+	result := make([]ClassifierLabelConflict, 0, len(conflicts))
+	for k := range conflicts {
+		conflict := conflicts[k]
+		conflict.ManagingClassifier = managers[k]
+		sort.Slice(conflict.ConflictingClassifiers, func(i, j int) bool {
+			return conflict.ConflictingClassifiers[i].Name < conflict.ConflictingClassifiers[j].Name
+		})
+		result = append(result, *conflict)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !isSameCluster(&result[i].Cluster, &result[j].Cluster) {
+			return getClusterRefKey(&result[i].Cluster) < getClusterRefKey(&result[j].Cluster)
+		}
+		return result[i].LabelKey < result[j].LabelKey
+	})
+
+	return result
+}
+
+func filterMatchingClusterStatuses(statuses []libsveltosv1beta1.MachingClusterStatus,
+	clusters map[corev1.ObjectReference]ClusterInfo) []libsveltosv1beta1.MachingClusterStatus {
+
+	result := make([]libsveltosv1beta1.MachingClusterStatus, 0, len(statuses))
+	for i := range statuses {
+		if _, ok := clusters[*normalizeClusterRef(&statuses[i].ClusterRef)]; ok {
+			result = append(result, statuses[i])
+		}
+	}
+	return result
+}
+
+func getManagedLabels(classifierLabels []libsveltosv1beta1.ClassifierLabel, managedKeys []string,
+) map[string]string {
+
+	result := make(map[string]string, len(managedKeys))
+	for _, key := range managedKeys {
+		result[key] = getClassifierLabelValue(classifierLabels, key)
+	}
+	return result
+}
+
+func getClassifierLabelValue(classifierLabels []libsveltosv1beta1.ClassifierLabel, key string) string {
+	for i := range classifierLabels {
+		if classifierLabels[i].Key == key {
+			return classifierLabels[i].Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Classifiers", func() {
+	It("getManagedLabels returns key and value of each managed label", func() {
+		classifierLabels := []libsveltosv1beta1.ClassifierLabel{
+			{Key: "env", Value: "production"},
+			{Key: "region", Value: "west"},
+		}
+
+		labels := server.GetManagedLabels(classifierLabels, []string{"env"})
+		Expect(len(labels)).To(Equal(1))
+		Expect(labels["env"]).To(Equal("production"))
+	})
+
+	It("getClassifiersManagingCluster returns Classifiers managing labels on a cluster", func() {
+		cluster := corev1.ObjectReference{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		classifiers := map[string]server.ClassifierInfo{
+			"b": {
+				ClassifierLabels: []libsveltosv1beta1.ClassifierLabel{{Key: "region", Value: "west"}},
+				MatchingClusterStatuses: []libsveltosv1beta1.MachingClusterStatus{
+					{ClusterRef: cluster, ManagedLabels: []string{"region"}},
+				},
+			},
+			"a": {
+				ClassifierLabels: []libsveltosv1beta1.ClassifierLabel{{Key: "env", Value: "production"}},
+				MatchingClusterStatuses: []libsveltosv1beta1.MachingClusterStatus{
+					{ClusterRef: cluster, ManagedLabels: []string{"env"}},
+				},
+			},
+			// matching the cluster without managing any label
+			"c": {
+				ClassifierLabels: []libsveltosv1beta1.ClassifierLabel{{Key: "env", Value: "staging"}},
+				MatchingClusterStatuses: []libsveltosv1beta1.MachingClusterStatus{
+					{ClusterRef: cluster, UnManagedLabels: []libsveltosv1beta1.UnManagedLabel{{Key: "env"}}},
+				},
+			},
+		}
+
+		result := server.GetClassifiersManagingCluster(classifiers, &cluster)
+		Expect(len(result)).To(Equal(2))
+		Expect(result[0].Name).To(Equal("a"))
+		Expect(result[0].ManagedLabels).To(HaveKeyWithValue("env", "production"))
+		Expect(result[1].Name).To(Equal("b"))
+		Expect(result[1].ManagedLabels).To(HaveKeyWithValue("region", "west"))
+
+		otherCluster := corev1.ObjectReference{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+		Expect(server.GetClassifiersManagingCluster(classifiers, &otherCluster)).To(BeNil())
+	})
+
+	It("evaluateClassifierConflicts reports labels more than one classifier wants to set", func() {
+		cluster := corev1.ObjectReference{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+		otherCluster := corev1.ObjectReference{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		failureMessage := "label env is already managed by classifier a"
+		classifiers := map[string]server.ClassifierInfo{
+			"a": {
+				ClassifierLabels: []libsveltosv1beta1.ClassifierLabel{{Key: "env", Value: "production"}},
+				MatchingClusterStatuses: []libsveltosv1beta1.MachingClusterStatus{
+					{ClusterRef: cluster, ManagedLabels: []string{"env"}},
+				},
+			},
+			"b": {
+				ClassifierLabels: []libsveltosv1beta1.ClassifierLabel{{Key: "env", Value: "staging"}},
+				MatchingClusterStatuses: []libsveltosv1beta1.MachingClusterStatus{
+					{
+						ClusterRef: cluster,
+						UnManagedLabels: []libsveltosv1beta1.UnManagedLabel{
+							{Key: "env", FailureMessage: &failureMessage},
+						},
+					},
+					// conflicts on clusters not accessible are not reported
+					{
+						ClusterRef:      otherCluster,
+						UnManagedLabels: []libsveltosv1beta1.UnManagedLabel{{Key: "env"}},
+					},
+				},
+			},
+		}
+
+		clusters := map[corev1.ObjectReference]server.ClusterInfo{
+			cluster: {},
+		}
+
+		conflicts := server.EvaluateClassifierConflicts(classifiers, clusters)
+		Expect(len(conflicts)).To(Equal(1))
+		Expect(conflicts[0].Cluster).To(Equal(cluster))
+		Expect(conflicts[0].LabelKey).To(Equal("env"))
+		Expect(conflicts[0].ManagingClassifier).To(Equal("a"))
+		Expect(len(conflicts[0].ConflictingClassifiers)).To(Equal(1))
+		Expect(conflicts[0].ConflictingClassifiers[0].Name).To(Equal("b"))
+		Expect(conflicts[0].ConflictingClassifiers[0].Value).To(Equal("staging"))
+		Expect(conflicts[0].ConflictingClassifiers[0].FailureMessage).ToNot(BeNil())
+		Expect(*conflicts[0].ConflictingClassifiers[0].FailureMessage).To(Equal(failureMessage))
+	})
+})
@@ -0,0 +1,280 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+type ComparedCluster struct {
+	Namespace   string                        `json:"namespace"`
+	Name        string                        `json:"name"`
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
+}
+
+type ChartVersionMismatch struct {
+	// ReleaseName name of the release deployed in both clusters
+	ReleaseName string `json:"releaseName"`
+
+	// Namespace where chart is deployed in both clusters
+	Namespace string `json:"namespace"`
+
+	// Cluster1 is the helm release as deployed in the first cluster
+	Cluster1 HelmRelease `json:"cluster1"`
+
+	// Cluster2 is the helm release as deployed in the second cluster
+	Cluster2 HelmRelease `json:"cluster2"`
+}
+
+type ResourceProfileMismatch struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+
+	// Cluster1ProfileNames are the profiles that deployed the resource in the first cluster
+	Cluster1ProfileNames []string `json:"cluster1ProfileNames"`
+
+	// Cluster2ProfileNames are the profiles that deployed the resource in the second cluster
+	Cluster2ProfileNames []string `json:"cluster2ProfileNames"`
+}
+
+func (r *ResourceProfileMismatch) key() resourceKey {
+	return resourceKey{Group: r.Group, Kind: r.Kind, Namespace: r.Namespace, Name: r.Name}
+}
+
+type ClusterDiff struct {
+	Cluster1 ComparedCluster `json:"cluster1"`
+	Cluster2 ComparedCluster `json:"cluster2"`
+
+	// HelmReleasesOnlyInCluster1 are the helm releases deployed in the first cluster only
+	HelmReleasesOnlyInCluster1 []HelmRelease `json:"helmReleasesOnlyInCluster1"`
+
+	// HelmReleasesOnlyInCluster2 are the helm releases deployed in the second cluster only
+	HelmReleasesOnlyInCluster2 []HelmRelease `json:"helmReleasesOnlyInCluster2"`
+
+	// ChartVersionMismatches are the helm releases deployed in both clusters with different chart versions
+	ChartVersionMismatches []ChartVersionMismatch `json:"chartVersionMismatches"`
+
+	// ResourcesOnlyInCluster1 are the Kubernetes resources deployed in the first cluster only
+	ResourcesOnlyInCluster1 []Resource `json:"resourcesOnlyInCluster1"`
+
+	// ResourcesOnlyInCluster2 are the Kubernetes resources deployed in the second cluster only
+	ResourcesOnlyInCluster2 []Resource `json:"resourcesOnlyInCluster2"`
+
+	// ResourceProfileMismatches are the resources deployed in both clusters by different profiles
+	ResourceProfileMismatches []ResourceProfileMismatch `json:"resourceProfileMismatches"`
+}
+
+// compareClusters returns the differences between helm releases and resources deployed
+// in cluster1 and cluster2
+func (m *instance) compareClusters(ctx context.Context, cluster1, cluster2 *ComparedCluster,
+) (*ClusterDiff, error) {
+
+	helmReleases1, err := m.getHelmChartsForCluster(ctx, cluster1.Namespace, cluster1.Name, cluster1.ClusterType)
+	if err != nil {
+		return nil, err
+	}
+	resources1, err := m.getResourcesForCluster(ctx, cluster1.Namespace, cluster1.Name, cluster1.ClusterType)
+	if err != nil {
+		return nil, err
+	}
+
+	helmReleases2, err := m.getHelmChartsForCluster(ctx, cluster2.Namespace, cluster2.Name, cluster2.ClusterType)
+	if err != nil {
+		return nil, err
+	}
+	resources2, err := m.getResourcesForCluster(ctx, cluster2.Namespace, cluster2.Name, cluster2.ClusterType)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ClusterDiff{
+		Cluster1: *cluster1,
+		Cluster2: *cluster2,
+	}
+	diff.HelmReleasesOnlyInCluster1, diff.HelmReleasesOnlyInCluster2, diff.ChartVersionMismatches =
+		diffHelmReleases(helmReleases1, helmReleases2)
+	diff.ResourcesOnlyInCluster1, diff.ResourcesOnlyInCluster2, diff.ResourceProfileMismatches =
+		diffResources(resources1, resources2)
+
+	return diff, nil
+}
+
+// diffHelmReleases compares helm releases (identified by release namespace and name). Returns
+// releases only in first set, releases only in second set and releases deployed with different
+// chart versions
+func diffHelmReleases(helmReleases1, helmReleases2 []HelmRelease,
+) (onlyIn1, onlyIn2 []HelmRelease, mismatches []ChartVersionMismatch) {
+
+	releases1 := getHelmReleaseMap(helmReleases1)
+	releases2 := getHelmReleaseMap(helmReleases2)
+
+	onlyIn1 = make([]HelmRelease, 0)
+	onlyIn2 = make([]HelmRelease, 0)
+	mismatches = make([]ChartVersionMismatch, 0)
+
+	for k := range releases1 {
+		r2, ok := releases2[k]
+		if !ok {
+			onlyIn1 = append(onlyIn1, releases1[k])
+			continue
+		}
+		if releases1[k].ChartVersion != r2.ChartVersion {
+			mismatches = append(mismatches, ChartVersionMismatch{
+				ReleaseName: releases1[k].ReleaseName,
+				Namespace:   releases1[k].Namespace,
+				Cluster1:    releases1[k],
+				Cluster2:    r2,
+			})
+		}
+	}
+
+	for k := range releases2 {
+		if _, ok := releases1[k]; !ok {
+			onlyIn2 = append(onlyIn2, releases2[k])
+		}
+	}
+
+	sort.Slice(onlyIn1, func(i, j int) bool { return sortHelmReleasesByName(onlyIn1, i, j) })
+	sort.Slice(onlyIn2, func(i, j int) bool { return sortHelmReleasesByName(onlyIn2, i, j) })
+	sort.Slice(mismatches, func(i, j int) bool {
+		if mismatches[i].Namespace == mismatches[j].Namespace {
+			return mismatches[i].ReleaseName < mismatches[j].ReleaseName
+		}
+		return mismatches[i].Namespace < mismatches[j].Namespace
+	})
+
+	return onlyIn1, onlyIn2, mismatches
+}
+
+// diffResources compares resources (identified by group, kind, namespace and name). Returns
+// resources only in first set, resources only in second set and resources deployed by different
+// profiles
+func diffResources(resources1, resources2 []Resource,
+) (onlyIn1, onlyIn2 []Resource, mismatches []ResourceProfileMismatch) {
+
+	deployed1 := getResourceMap(resources1)
+	deployed2 := getResourceMap(resources2)
+
+	onlyIn1 = make([]Resource, 0)
+	onlyIn2 = make([]Resource, 0)
+	mismatches = make([]ResourceProfileMismatch, 0)
+
+	for k := range deployed1 {
+		r2, ok := deployed2[k]
+		if !ok {
+			onlyIn1 = append(onlyIn1, deployed1[k])
+			continue
+		}
+
+		profiles1 := getSortedCopy(deployed1[k].ProfileNames)
+		profiles2 := getSortedCopy(r2.ProfileNames)
+		if !reflect.DeepEqual(profiles1, profiles2) {
+			mismatches = append(mismatches, ResourceProfileMismatch{
+				Name:                 k.Name,
+				Namespace:            k.Namespace,
+				Group:                k.Group,
+				Kind:                 k.Kind,
+				Cluster1ProfileNames: profiles1,
+				Cluster2ProfileNames: profiles2,
+			})
+		}
+	}
+
+	for k := range deployed2 {
+		if _, ok := deployed1[k]; !ok {
+			onlyIn2 = append(onlyIn2, deployed2[k])
+		}
+	}
+
+	sort.Slice(onlyIn1, func(i, j int) bool {
+		return getResourceKey(&onlyIn1[i]).String() < getResourceKey(&onlyIn1[j]).String()
+	})
+	sort.Slice(onlyIn2, func(i, j int) bool {
+		return getResourceKey(&onlyIn2[i]).String() < getResourceKey(&onlyIn2[j]).String()
+	})
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].key().String() < mismatches[j].key().String()
+	})
+
+	return onlyIn1, onlyIn2, mismatches
+}
+
+type helmReleaseID struct {
+	Namespace   string
+	ReleaseName string
+}
+
+type resourceKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (k resourceKey) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", k.Group, k.Kind, k.Namespace, k.Name)
+}
+
+func getHelmReleaseMap(helmReleases []HelmRelease) map[helmReleaseID]HelmRelease {
+	result := make(map[helmReleaseID]HelmRelease, len(helmReleases))
+	for i := range helmReleases {
+		result[helmReleaseID{Namespace: helmReleases[i].Namespace, ReleaseName: helmReleases[i].ReleaseName}] =
+			helmReleases[i]
+	}
+	return result
+}
+
+// getResourceKey returns the key identifying a resource. Version is intentionally ignored
+// so the same resource deployed using different API versions is not reported as missing.
+func getResourceKey(resource *Resource) resourceKey {
+	return resourceKey{
+		Group:     resource.Group,
+		Kind:      resource.Kind,
+		Namespace: resource.Namespace,
+		Name:      resource.Name,
+	}
+}
+
+func getResourceMap(resources []Resource) map[resourceKey]Resource {
+	result := make(map[resourceKey]Resource, len(resources))
+	for i := range resources {
+		result[getResourceKey(&resources[i])] = resources[i]
+	}
+	return result
+}
+
+func getSortedCopy(items []string) []string {
+	result := make([]string, len(items))
+	copy(result, items)
+	sort.Strings(result)
+	return result
+}
+
+// sortHelmReleasesByName sorts helm releases by namespace and then release name
+func sortHelmReleasesByName(helmReleases []HelmRelease, i, j int) bool {
+	if helmReleases[i].Namespace == helmReleases[j].Namespace {
+		return helmReleases[i].ReleaseName < helmReleases[j].ReleaseName
+	}
+	return helmReleases[i].Namespace < helmReleases[j].Namespace
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Compare clusters", func() {
+	It("diffHelmReleases reports missing releases and chart version mismatches", func() {
+		common := server.HelmRelease{
+			Namespace:    randomString(),
+			ReleaseName:  randomString(),
+			ChartVersion: "1.0.0",
+		}
+		upgraded := common
+		upgraded.ChartVersion = "1.1.0"
+
+		same := server.HelmRelease{
+			Namespace:    randomString(),
+			ReleaseName:  randomString(),
+			ChartVersion: "2.0.0",
+		}
+		onlyInFirst := server.HelmRelease{
+			Namespace:   randomString(),
+			ReleaseName: randomString(),
+		}
+		onlyInSecond := server.HelmRelease{
+			Namespace:   randomString(),
+			ReleaseName: randomString(),
+		}
+
+		onlyIn1, onlyIn2, mismatches := server.DiffHelmReleases(
+			[]server.HelmRelease{common, same, onlyInFirst},
+			[]server.HelmRelease{upgraded, same, onlyInSecond})
+
+		Expect(onlyIn1).To(ConsistOf(onlyInFirst))
+		Expect(onlyIn2).To(ConsistOf(onlyInSecond))
+		Expect(len(mismatches)).To(Equal(1))
+		Expect(mismatches[0].ReleaseName).To(Equal(common.ReleaseName))
+		Expect(mismatches[0].Cluster1.ChartVersion).To(Equal("1.0.0"))
+		Expect(mismatches[0].Cluster2.ChartVersion).To(Equal("1.1.0"))
+	})
+
+	It("diffResources reports missing resources and resources deployed by different profiles", func() {
+		profile1 := "ClusterProfile/" + randomString()
+		profile2 := "ClusterProfile/" + randomString()
+
+		common := server.Resource{
+			Namespace:    randomString(),
+			Name:         randomString(),
+			Kind:         "ConfigMap",
+			Version:      "v1",
+			ProfileNames: []string{profile1, profile2},
+		}
+		// Same profiles in a different order is not a mismatch
+		commonReordered := common
+		commonReordered.ProfileNames = []string{profile2, profile1}
+
+		other := server.Resource{
+			Namespace:    randomString(),
+			Name:         randomString(),
+			Group:        "apps",
+			Kind:         "Deployment",
+			Version:      "v1",
+			ProfileNames: []string{profile1},
+		}
+		otherDifferentProfile := other
+		otherDifferentProfile.ProfileNames = []string{profile2}
+
+		onlyInFirst := server.Resource{
+			Name:    randomString(),
+			Kind:    "Namespace",
+			Version: "v1",
+		}
+
+		onlyIn1, onlyIn2, mismatches := server.DiffResources(
+			[]server.Resource{common, other, onlyInFirst},
+			[]server.Resource{commonReordered, otherDifferentProfile})
+
+		Expect(onlyIn1).To(ConsistOf(onlyInFirst))
+		Expect(onlyIn2).To(BeEmpty())
+		Expect(len(mismatches)).To(Equal(1))
+		Expect(mismatches[0].Name).To(Equal(other.Name))
+		Expect(mismatches[0].Cluster1ProfileNames).To(Equal([]string{profile1}))
+		Expect(mismatches[0].Cluster2ProfileNames).To(Equal([]string{profile2}))
+	})
+})
@@ -0,0 +1,303 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+const (
+	fullCompliance = 100
+)
+
+type ExpectedHelmChart struct {
+	// RepositoryURL is the URL helm chart repository
+	RepositoryURL string `json:"repositoryURL"`
+
+	// ReleaseName is the chart release
+	ReleaseName string `json:"releaseName"`
+
+	// ReleaseNamespace is the namespace release will be installed
+	ReleaseNamespace string `json:"releaseNamespace"`
+
+	// ChartVersion is the chart version expected to be deployed.
+	// Empty means any version is accepted.
+	ChartVersion string `json:"chartVersion"`
+
+	// ProfileName is the name of the ClusterProfile/Profile requiring the helm chart
+	ProfileName string `json:"profileName"`
+}
+
+type HelmChartVersionDrift struct {
+	ReleaseName      string `json:"releaseName"`
+	ReleaseNamespace string `json:"releaseNamespace"`
+	ExpectedVersion  string `json:"expectedVersion"`
+	DeployedVersion  string `json:"deployedVersion"`
+	ProfileName      string `json:"profileName"`
+}
+
+type ClusterCompliance struct {
+	Namespace   string                        `json:"namespace"`
+	Name        string                        `json:"name"`
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
+
+	// ExpectedFeatures is the union of features across all profiles matching the cluster
+	ExpectedFeatures []ProfileStatusResult `json:"expectedFeatures"`
+
+	// MissingHelmCharts are helm charts required by a matching profile but not deployed
+	MissingHelmCharts []ExpectedHelmChart `json:"missingHelmCharts"`
+
+	// ExtraHelmCharts are helm charts deployed but not required by any matching profile
+	ExtraHelmCharts []HelmRelease `json:"extraHelmCharts"`
+
+	// VersionMismatches are helm charts deployed with a version different than the expected one
+	VersionMismatches []HelmChartVersionDrift `json:"versionMismatches"`
+
+	// CompliantItems is the number of expected helm charts deployed with the expected version
+	CompliantItems int `json:"compliantItems"`
+
+	// TotalItems is the number of expected helm charts plus the number of extra helm charts
+	TotalItems int `json:"totalItems"`
+
+	// CompliancePercentage is CompliantItems/TotalItems. 100 when nothing is expected or deployed.
+	CompliancePercentage float64 `json:"compliancePercentage"`
+}
+
+type FleetCompliance struct {
+	// TotalClusters is the number of clusters evaluated
+	TotalClusters int `json:"totalClusters"`
+
+	// CompliantClusters is the number of clusters with no drift
+	CompliantClusters int `json:"compliantClusters"`
+
+	// CompliancePercentage is computed over all items of all clusters
+	CompliancePercentage float64 `json:"compliancePercentage"`
+
+	// Clusters contains per cluster compliance
+	Clusters []ClusterCompliance `json:"clusters"`
+}
+
+// getClusterCompliance compares helm charts expected on a cluster (helm charts listed in the Spec of
+// every ClusterProfile/Profile matching the cluster) with the helm charts ClusterConfiguration reports
+// as deployed. profileSpecs is used to avoid fetching the same profile multiple times.
+func (m *instance) getClusterCompliance(ctx context.Context, namespace, name string,
+	clusterType libsveltosv1beta1.ClusterType, profileSpecs map[corev1.ObjectReference]*configv1beta1.Spec,
+) (*ClusterCompliance, error) {
+
+	clusterProfileStatuses := m.GetClusterProfileStatusesByCluster(&namespace, &name, clusterType)
+
+	expected := make([]ExpectedHelmChart, 0)
+	for i := range clusterProfileStatuses {
+		status := &clusterProfileStatuses[i]
+		profileRef := &corev1.ObjectReference{
+			Kind:       status.ProfileType,
+			APIVersion: configv1beta1.GroupVersion.String(),
+			Name:       status.ProfileName,
+		}
+		if status.ProfileType == configv1beta1.ProfileKind {
+			// Profile and its ClusterSummaries are in the same namespace
+			profileRef.Namespace = status.Namespace
+		}
+
+		spec, err := m.getCachedProfileSpec(ctx, profileRef, profileSpecs)
+		if err != nil {
+			return nil, err
+		}
+		if spec == nil {
+			continue
+		}
+
+		expected = append(expected,
+			getExpectedHelmCharts(fmt.Sprintf("%s/%s", status.ProfileType, status.ProfileName), spec)...)
+	}
+
+	deployed, err := m.getHelmChartsForCluster(ctx, namespace, name, clusterType)
+	if err != nil {
+		return nil, err
+	}
+
+	compliance := computeCompliance(expected, deployed)
+	compliance.Namespace = namespace
+	compliance.Name = name
+	compliance.ClusterType = clusterType
+	compliance.ExpectedFeatures = flattenProfileStatuses(clusterProfileStatuses, false)
+	sort.Slice(compliance.ExpectedFeatures, func(i, j int) bool {
+		return sortClusterProfileStatus(compliance.ExpectedFeatures, i, j)
+	})
+
+	return compliance, nil
+}
+
+// getFleetCompliance evaluates compliance for all clusters and aggregates results
+func (m *instance) getFleetCompliance(ctx context.Context, clusters []corev1.ObjectReference,
+) (*FleetCompliance, error) {
+
+	profileSpecs := make(map[corev1.ObjectReference]*configv1beta1.Spec)
+
+	fleet := &FleetCompliance{
+		Clusters: make([]ClusterCompliance, 0, len(clusters)),
+	}
+
+	compliantItems := 0
+	totalItems := 0
+	for i := range clusters {
+		cluster := &clusters[i]
+		compliance, err := m.getClusterCompliance(ctx, cluster.Namespace, cluster.Name,
+			clusterproxy.GetClusterType(cluster), profileSpecs)
+		if err != nil {
+			return nil, err
+		}
+
+		compliantItems += compliance.CompliantItems
+		totalItems += compliance.TotalItems
+		if compliance.CompliantItems == compliance.TotalItems {
+			fleet.CompliantClusters++
+		}
+		fleet.Clusters = append(fleet.Clusters, *compliance)
+	}
+
+	fleet.TotalClusters = len(fleet.Clusters)
+	fleet.CompliancePercentage = getCompliancePercentage(compliantItems, totalItems)
+
+	return fleet, nil
+}
+
+func (m *instance) getCachedProfileSpec(ctx context.Context, profileRef *corev1.ObjectReference,
+	profileSpecs map[corev1.ObjectReference]*configv1beta1.Spec) (*configv1beta1.Spec, error) {
+
+	if spec, ok := profileSpecs[*profileRef]; ok {
+		return spec, nil
+	}
+
+	var spec *configv1beta1.Spec
+	if profileRef.Kind == configv1beta1.ClusterProfileKind {
+		cp, err := m.getClusterProfileInstance(ctx, profileRef.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			spec = &cp.Spec
+		}
+	} else {
+		p, err := m.getProfileInstance(ctx, profileRef.Namespace, profileRef.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			spec = &p.Spec
+		}
+	}
+
+	profileSpecs[*profileRef] = spec
+	return spec, nil
+}
+
+// getExpectedHelmCharts returns the helm charts a profile expects to be installed
+func getExpectedHelmCharts(profileName string, spec *configv1beta1.Spec) []ExpectedHelmChart {
+	result := make([]ExpectedHelmChart, 0, len(spec.HelmCharts))
+	for i := range spec.HelmCharts {
+		chart := &spec.HelmCharts[i]
+		if chart.HelmChartAction == configv1beta1.HelmChartActionUninstall {
+			continue
+		}
+
+		result = append(result, ExpectedHelmChart{
+			RepositoryURL:    chart.RepositoryURL,
+			ReleaseName:      chart.ReleaseName,
+			ReleaseNamespace: chart.ReleaseNamespace,
+			ChartVersion:     chart.ChartVersion,
+			ProfileName:      profileName,
+		})
+	}
+
+	return result
+}
+
+// computeCompliance compares expected and deployed helm charts
+func computeCompliance(expected []ExpectedHelmChart, deployed []HelmRelease) *ClusterCompliance {
+	compliance := &ClusterCompliance{
+		MissingHelmCharts: make([]ExpectedHelmChart, 0),
+		ExtraHelmCharts:   make([]HelmRelease, 0),
+		VersionMismatches: make([]HelmChartVersionDrift, 0),
+	}
+
+	deployedReleases := getHelmReleaseMap(deployed)
+	expectedReleases := make(map[helmReleaseID]bool, len(expected))
+
+	for i := range expected {
+		e := &expected[i]
+		key := helmReleaseID{Namespace: e.ReleaseNamespace, ReleaseName: e.ReleaseName}
+		expectedReleases[key] = true
+
+		d, ok := deployedReleases[key]
+		if !ok {
+			compliance.MissingHelmCharts = append(compliance.MissingHelmCharts, *e)
+			continue
+		}
+
+		if e.ChartVersion != "" && !isSameChartVersion(e.ChartVersion, d.ChartVersion) {
+			compliance.VersionMismatches = append(compliance.VersionMismatches, HelmChartVersionDrift{
+				ReleaseName:      e.ReleaseName,
+				ReleaseNamespace: e.ReleaseNamespace,
+				ExpectedVersion:  e.ChartVersion,
+				DeployedVersion:  d.ChartVersion,
+				ProfileName:      e.ProfileName,
+			})
+			continue
+		}
+
+		compliance.CompliantItems++
+	}
+
+	for k := range deployedReleases {
+		if !expectedReleases[k] {
+			compliance.ExtraHelmCharts = append(compliance.ExtraHelmCharts, deployedReleases[k])
+		}
+	}
+
+	sort.Slice(compliance.ExtraHelmCharts, func(i, j int) bool {
+		return sortHelmReleasesByName(compliance.ExtraHelmCharts, i, j)
+	})
+
+	compliance.TotalItems = len(expected) + len(compliance.ExtraHelmCharts)
+	compliance.CompliancePercentage = getCompliancePercentage(compliance.CompliantItems, compliance.TotalItems)
+
+	return compliance
+}
+
+// isSameChartVersion compares chart versions ignoring the optional "v" prefix
+func isSameChartVersion(v1, v2 string) bool {
+	return strings.TrimPrefix(v1, "v") == strings.TrimPrefix(v2, "v")
+}
+
+func getCompliancePercentage(compliant, total int) float64 {
+	if total == 0 {
+		return fullCompliance
+	}
+
+	return float64(compliant) * fullCompliance / float64(total)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Compliance", func() {
+	It("getExpectedHelmCharts ignores helm charts being uninstalled", func() {
+		spec := &configv1beta1.Spec{
+			HelmCharts: []configv1beta1.HelmChart{
+				{
+					RepositoryURL:    randomString(),
+					ReleaseName:      randomString(),
+					ReleaseNamespace: randomString(),
+					ChartVersion:     "v1.0.0",
+					HelmChartAction:  configv1beta1.HelmChartActionInstall,
+				},
+				{
+					RepositoryURL:    randomString(),
+					ReleaseName:      randomString(),
+					ReleaseNamespace: randomString(),
+					HelmChartAction:  configv1beta1.HelmChartActionUninstall,
+				},
+			},
+		}
+
+		profileName := "ClusterProfile/" + randomString()
+		expected := server.GetExpectedHelmCharts(profileName, spec)
+		Expect(len(expected)).To(Equal(1))
+		Expect(expected[0].ReleaseName).To(Equal(spec.HelmCharts[0].ReleaseName))
+		Expect(expected[0].ChartVersion).To(Equal("v1.0.0"))
+		Expect(expected[0].ProfileName).To(Equal(profileName))
+	})
+
+	It("computeCompliance reports missing, extra and mismatched helm charts", func() {
+		compliant := server.ExpectedHelmChart{
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+			ChartVersion:     "v1.0.0",
+		}
+		mismatched := server.ExpectedHelmChart{
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+			ChartVersion:     "2.0.0",
+		}
+		missing := server.ExpectedHelmChart{
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+		}
+
+		extra := server.HelmRelease{
+			ReleaseName:  randomString(),
+			Namespace:    randomString(),
+			ChartVersion: "0.1.0",
+		}
+
+		deployed := []server.HelmRelease{
+			// "v" prefix is ignored when comparing versions
+			{ReleaseName: compliant.ReleaseName, Namespace: compliant.ReleaseNamespace, ChartVersion: "1.0.0"},
+			{ReleaseName: mismatched.ReleaseName, Namespace: mismatched.ReleaseNamespace, ChartVersion: "1.9.0"},
+			extra,
+		}
+
+		compliance := server.ComputeCompliance(
+			[]server.ExpectedHelmChart{compliant, mismatched, missing}, deployed)
+
+		Expect(compliance.MissingHelmCharts).To(ConsistOf(missing))
+		Expect(compliance.ExtraHelmCharts).To(ConsistOf(extra))
+		Expect(len(compliance.VersionMismatches)).To(Equal(1))
+		Expect(compliance.VersionMismatches[0].ReleaseName).To(Equal(mismatched.ReleaseName))
+		Expect(compliance.VersionMismatches[0].ExpectedVersion).To(Equal("2.0.0"))
+		Expect(compliance.VersionMismatches[0].DeployedVersion).To(Equal("1.9.0"))
+		Expect(compliance.CompliantItems).To(Equal(1))
+		Expect(compliance.TotalItems).To(Equal(4))
+		Expect(compliance.CompliancePercentage).To(Equal(float64(25)))
+	})
+
+	It("computeCompliance reports full compliance when nothing is expected nor deployed", func() {
+		compliance := server.ComputeCompliance(nil, nil)
+		Expect(compliance.TotalItems).To(BeZero())
+		Expect(compliance.CompliancePercentage).To(Equal(float64(100)))
+	})
+})
@@ -0,0 +1,131 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Cluster conditions", func() {
+	It("getCAPIClusterConditions returns v1beta1 and v1beta2 conditions", func() {
+		message := randomString()
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Status: clusterv1.ClusterStatus{
+				Conditions: clusterv1.Conditions{
+					{
+						Type:     clusterv1.ReadyCondition,
+						Status:   corev1.ConditionFalse,
+						Severity: clusterv1.ConditionSeverityWarning,
+						Reason:   randomString(),
+						Message:  message,
+					},
+					{
+						Type:   clusterv1.ControlPlaneInitializedCondition,
+						Status: corev1.ConditionTrue,
+					},
+				},
+				V1Beta2: &clusterv1.ClusterV1Beta2Status{
+					Conditions: []metav1.Condition{
+						{
+							Type:    clusterv1.ClusterAvailableV1Beta2Condition,
+							Status:  metav1.ConditionFalse,
+							Reason:  randomString(),
+							Message: randomString(),
+						},
+					},
+				},
+			},
+		}
+
+		conditions := server.GetCAPIClusterConditions(cluster)
+		Expect(len(conditions)).To(Equal(3))
+		Expect(conditions[0].Type).To(Equal(string(clusterv1.ReadyCondition)))
+		Expect(conditions[0].Status).To(Equal(corev1.ConditionFalse))
+		Expect(conditions[0].Severity).To(Equal(string(clusterv1.ConditionSeverityWarning)))
+		Expect(conditions[0].Message).To(Equal(message))
+		Expect(conditions[0].V1Beta2).To(BeFalse())
+		Expect(conditions[2].Type).To(Equal(clusterv1.ClusterAvailableV1Beta2Condition))
+		Expect(conditions[2].Status).To(Equal(corev1.ConditionFalse))
+		Expect(conditions[2].V1Beta2).To(BeTrue())
+
+		// Only v1beta1 False conditions are part of the failure message
+		failureMessage := server.ExamineClusterConditions(cluster)
+		Expect(failureMessage).ToNot(BeNil())
+		Expect(*failureMessage).To(ContainSubstring(message))
+		Expect(*failureMessage).ToNot(ContainSubstring(cluster.Status.V1Beta2.Conditions[0].Message))
+	})
+
+	It("getSveltosClusterConditions derives Ready and Connected conditions", func() {
+		failureMessage := randomString()
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Status: libsveltosv1beta1.SveltosClusterStatus{
+				Ready:              false,
+				FailureMessage:     &failureMessage,
+				ConnectionStatus:   libsveltosv1beta1.ConnectionDown,
+				ConnectionFailures: 3,
+			},
+		}
+
+		conditions := server.GetSveltosClusterConditions(sveltosCluster)
+		Expect(len(conditions)).To(Equal(2))
+		Expect(conditions[0].Type).To(Equal("Ready"))
+		Expect(conditions[0].Status).To(Equal(corev1.ConditionFalse))
+		Expect(conditions[0].Message).To(Equal(failureMessage))
+		Expect(conditions[1].Type).To(Equal("Connected"))
+		Expect(conditions[1].Status).To(Equal(corev1.ConditionFalse))
+
+		sveltosCluster.Status.Ready = true
+		sveltosCluster.Status.FailureMessage = nil
+		sveltosCluster.Status.ConnectionStatus = libsveltosv1beta1.ConnectionHealthy
+		conditions = server.GetSveltosClusterConditions(sveltosCluster)
+		Expect(conditions[0].Status).To(Equal(corev1.ConditionTrue))
+		Expect(conditions[0].Message).To(BeEmpty())
+		Expect(conditions[1].Status).To(Equal(corev1.ConditionTrue))
+	})
+
+	It("hasMatchingCondition filters by condition type and status", func() {
+		conditions := []server.ClusterCondition{
+			{Type: "Ready", Status: corev1.ConditionTrue},
+			{Type: "Connected", Status: corev1.ConditionFalse},
+		}
+
+		Expect(server.HasMatchingCondition(conditions, "", "")).To(BeTrue())
+		Expect(server.HasMatchingCondition(nil, "", "")).To(BeTrue())
+		Expect(server.HasMatchingCondition(conditions, "Ready", "")).To(BeTrue())
+		Expect(server.HasMatchingCondition(conditions, "Ready", "True")).To(BeTrue())
+		Expect(server.HasMatchingCondition(conditions, "Ready", "False")).To(BeFalse())
+		Expect(server.HasMatchingCondition(conditions, "", "False")).To(BeTrue())
+		Expect(server.HasMatchingCondition(conditions, "", "Unknown")).To(BeFalse())
+		Expect(server.HasMatchingCondition(conditions, randomString(), "")).To(BeFalse())
+	})
+})
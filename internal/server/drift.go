@@ -0,0 +1,525 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// maxDriftEvents is the maximum number of drift events kept per resource
+	maxDriftEvents = 20
+
+	// resourceSummaryNamespace is the namespace ResourceSummaries are created in managed clusters
+	resourceSummaryNamespace = "projectsveltos"
+
+	// driftSamplingInterval is how often ResourceSummaries are read from managed clusters
+	driftSamplingInterval = time.Minute
+
+	// driftSamplingTimeout bounds the time spent reading ResourceSummaries from a single cluster
+	driftSamplingTimeout = 30 * time.Second
+
+	// driftSamplingConcurrency is the maximum number of clusters read at the same time
+	driftSamplingConcurrency = 5
+
+	// driftSamplingBackoff is how long a cluster whose ResourceSummaries could not be read is skipped
+	driftSamplingBackoff = 10 * time.Minute
+)
+
+type DriftCategory string
+
+const (
+	DriftCategoryResources DriftCategory = "Resources"
+	DriftCategoryKustomize DriftCategory = "Kustomize"
+	DriftCategoryHelm      DriftCategory = "Helm"
+)
+
+type DriftEvent struct {
+	// DetectedTime is when the drift was detected
+	DetectedTime metav1.Time `json:"detectedTime"`
+
+	// Reconciled is true once Sveltos has reconciled the resource back
+	Reconciled bool `json:"reconciled"`
+
+	// ReconciledTime is when the drift was reconciled
+	ReconciledTime *metav1.Time `json:"reconciledTime,omitempty"`
+}
+
+type ResourceDrift struct {
+	// Resource that drifted. Empty when drift-detection-manager reported a
+	// drift for a category but the drifted resource could not be identified.
+	Resource libsveltosv1beta1.Resource `json:"resource"`
+
+	// Category is the feature that deployed the resource
+	Category DriftCategory `json:"category"`
+
+	// ProfileName is the ClusterProfile/Profile which deployed the resource
+	ProfileName string `json:"profileName"`
+
+	// DriftCount is how many times the resource drifted
+	DriftCount int `json:"driftCount"`
+
+	// LastDriftTime is when the last drift was detected
+	LastDriftTime metav1.Time `json:"lastDriftTime"`
+
+	// Events contains the most recent drift events, most recent last
+	Events []DriftEvent `json:"events"`
+}
+
+type ClusterResourceDrift struct {
+	Namespace   string                        `json:"namespace"`
+	Name        string                        `json:"name"`
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
+
+	ResourceDrift `json:",inline"`
+}
+
+type ClusterDriftResult struct {
+	TotalResources int             `json:"totalResources"`
+	Resources      []ResourceDrift `json:"resources"`
+}
+
+type FleetDriftResult struct {
+	TotalResources int                    `json:"totalResources"`
+	Resources      []ClusterResourceDrift `json:"resources"`
+}
+
+type driftKey struct {
+	Category DriftCategory
+	Resource libsveltosv1beta1.Resource
+}
+
+// resourceSummaryDrift contains what was last observed for a ResourceSummary
+// and all drifts detected so far
+type resourceSummaryDrift struct {
+	// hashes are the resource hashes observed at the last reconciled state
+	hashes  map[driftKey]string
+	changed map[DriftCategory]bool
+	drifts  map[driftKey]*ResourceDrift
+
+	// inManagementCluster is true when the ResourceSummary is in the management cluster
+	// (agentless mode) and so it is watched
+	inManagementCluster bool
+}
+
+// UpdateResourceSummary evaluates a ResourceSummary present in the management cluster and
+// records drift events (see evaluateDrifts).
+func (m *instance) UpdateResourceSummary(resourceSummary *libsveltosv1beta1.ResourceSummary) {
+	m.updateResourceSummary(resourceSummary, true)
+}
+
+func (m *instance) updateResourceSummary(resourceSummary *libsveltosv1beta1.ResourceSummary,
+	inManagementCluster bool) {
+
+	clusterSummaryRef := getClusterSummaryRefForResourceSummary(resourceSummary)
+	if clusterSummaryRef == nil {
+		return
+	}
+
+	now := metav1.Now()
+
+	m.driftMux.Lock()
+	defer m.driftMux.Unlock()
+
+	current, ok := m.resourceSummaryDrifts[*clusterSummaryRef]
+	if !ok {
+		// First time this ResourceSummary is seen. Use it as baseline.
+		current = newResourceSummaryDrift(resourceSummary)
+		current.inManagementCluster = inManagementCluster
+		m.resourceSummaryDrifts[*clusterSummaryRef] = current
+		return
+	}
+
+	evaluateDrifts(current, getResourceSummaryHashes(resourceSummary), getResourceSummaryChanged(resourceSummary), &now)
+}
+
+// RemoveResourceSummary removes any drift information for the ClusterSummary
+func (m *instance) RemoveResourceSummary(clusterSummaryNamespace, clusterSummaryName string) {
+	clusterSummaryRef := &corev1.ObjectReference{
+		Namespace:  clusterSummaryNamespace,
+		Name:       clusterSummaryName,
+		Kind:       configv1beta1.ClusterSummaryKind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+	}
+
+	m.driftMux.Lock()
+	defer m.driftMux.Unlock()
+
+	delete(m.resourceSummaryDrifts, *clusterSummaryRef)
+}
+
+func newResourceSummaryDrift(resourceSummary *libsveltosv1beta1.ResourceSummary) *resourceSummaryDrift {
+	return &resourceSummaryDrift{
+		hashes:  getResourceSummaryHashes(resourceSummary),
+		changed: getResourceSummaryChanged(resourceSummary),
+		drifts:  make(map[driftKey]*ResourceDrift),
+	}
+}
+
+// evaluateDrifts records a drift when drift-detection-manager flags a category as changed (flag goes
+// from false to true). The drift is attributed to the resources whose hash differs from the one observed
+// at the last reconciled state, or to the category when no such resource exists. Hash changes observed
+// while a category is not flagged (deployments) or while it is flagged (including Sveltos reverting the
+// drift) are not drifts. A drift is marked as reconciled when the flag is cleared.
+func evaluateDrifts(current *resourceSummaryDrift, hashes map[driftKey]string,
+	changed map[DriftCategory]bool, now *metav1.Time) {
+
+	for category, isChanged := range changed {
+		wasChanged := current.changed[category]
+		if isChanged && !wasChanged {
+			drifted := false
+			for k, hash := range hashes {
+				if k.Category != category {
+					continue
+				}
+				if reconciledHash, ok := current.hashes[k]; ok && reconciledHash != hash {
+					recordDrift(current, k, now)
+					drifted = true
+				}
+			}
+			if !drifted {
+				// Drift reported but no hash changed. Record it at category level.
+				recordDrift(current, driftKey{Category: category}, now)
+			}
+		}
+		if !isChanged && wasChanged {
+			markDriftsReconciled(current, category, now)
+		}
+	}
+
+	// Hashes are the reconciled state only for categories which are not flagged as changed
+	reconciledHashes := make(map[driftKey]string, len(hashes))
+	for k, hash := range current.hashes {
+		if changed[k.Category] {
+			reconciledHashes[k] = hash
+		}
+	}
+	for k, hash := range hashes {
+		if !changed[k.Category] {
+			reconciledHashes[k] = hash
+		}
+	}
+
+	current.hashes = reconciledHashes
+	current.changed = changed
+}
+
+func recordDrift(current *resourceSummaryDrift, key driftKey, now *metav1.Time) {
+	drift, ok := current.drifts[key]
+	if !ok {
+		drift = &ResourceDrift{
+			Resource: key.Resource,
+			Category: key.Category,
+			Events:   make([]DriftEvent, 0),
+		}
+		current.drifts[key] = drift
+	}
+
+	drift.DriftCount++
+	drift.LastDriftTime = *now
+	drift.Events = append(drift.Events, DriftEvent{DetectedTime: *now})
+	if len(drift.Events) > maxDriftEvents {
+		drift.Events = drift.Events[len(drift.Events)-maxDriftEvents:]
+	}
+}
+
+func markDriftsReconciled(current *resourceSummaryDrift, category DriftCategory, now *metav1.Time) {
+	for k := range current.drifts {
+		if k.Category != category {
+			continue
+		}
+		events := current.drifts[k].Events
+		for i := range events {
+			if !events[i].Reconciled {
+				events[i].Reconciled = true
+				events[i].ReconciledTime = now
+			}
+		}
+	}
+}
+
+// getClusterSummaryRefForResourceSummary returns the ClusterSummary a ResourceSummary was created for
+func getClusterSummaryRefForResourceSummary(resourceSummary *libsveltosv1beta1.ResourceSummary,
+) *corev1.ObjectReference {
+
+	var namespace, name string
+	if resourceSummary.Annotations != nil {
+		namespace = resourceSummary.Annotations[libsveltosv1beta1.ClusterSummaryNamespaceAnnotation]
+		name = resourceSummary.Annotations[libsveltosv1beta1.ClusterSummaryNameAnnotation]
+	}
+	if (namespace == "" || name == "") && resourceSummary.Labels != nil {
+		namespace = resourceSummary.Labels[libsveltosv1beta1.ClusterSummaryNamespaceLabel]
+		name = resourceSummary.Labels[libsveltosv1beta1.ClusterSummaryNameLabel]
+	}
+
+	if namespace == "" || name == "" {
+		return nil
+	}
+
+	return &corev1.ObjectReference{
+		Namespace:  namespace,
+		Name:       name,
+		Kind:       configv1beta1.ClusterSummaryKind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+	}
+}
+
+func getResourceSummaryChanged(resourceSummary *libsveltosv1beta1.ResourceSummary) map[DriftCategory]bool {
+	return map[DriftCategory]bool{
+		DriftCategoryResources: resourceSummary.Status.ResourcesChanged,
+		DriftCategoryKustomize: resourceSummary.Status.KustomizeResourcesChanged,
+		DriftCategoryHelm:      resourceSummary.Status.HelmResourcesChanged,
+	}
+}
+
+func getResourceSummaryHashes(resourceSummary *libsveltosv1beta1.ResourceSummary) map[driftKey]string {
+	result := make(map[driftKey]string)
+
+	addHashes := func(category DriftCategory, resourceHashes []libsveltosv1beta1.ResourceHash) {
+		for i := range resourceHashes {
+			result[driftKey{Category: category, Resource: resourceHashes[i].Resource}] = resourceHashes[i].Hash
+		}
+	}
+
+	addHashes(DriftCategoryResources, resourceSummary.Status.ResourceHashes)
+	addHashes(DriftCategoryKustomize, resourceSummary.Status.KustomizeResourceHashes)
+	addHashes(DriftCategoryHelm, resourceSummary.Status.HelmResourceHashes)
+
+	return result
+}
+
+// refreshResourceSummariesFromCluster reads ResourceSummaries from the managed cluster. This
+// is needed when drift-detection-manager runs in the managed cluster (ResourceSummaries
+// are not in the management cluster so they are not watched).
+func (m *instance) refreshResourceSummariesFromCluster(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) error {
+
+	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, m.client, clusterNamespace, clusterName,
+		"", "", clusterType, m.logger)
+	if err != nil {
+		return err
+	}
+
+	resourceSummaries := &libsveltosv1beta1.ResourceSummaryList{}
+	err = remoteClient.List(ctx, resourceSummaries, client.InNamespace(resourceSummaryNamespace))
+	if err != nil {
+		return err
+	}
+
+	for i := range resourceSummaries.Items {
+		m.updateResourceSummary(&resourceSummaries.Items[i], false)
+	}
+
+	return nil
+}
+
+// sampleDrifts reads ResourceSummaries from managed clusters every driftSamplingInterval, so drifts
+// are recorded even if nobody is querying them. Drift history is kept in memory only.
+func (m *instance) sampleDrifts(ctx context.Context) {
+	ticker := time.NewTicker(driftSamplingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.sampleClustersDrifts(ctx, m.getClustersToSampleForDrifts(time.Now()))
+	}
+}
+
+// sampleClustersDrifts reads ResourceSummaries from clusters, at most driftSamplingConcurrency at a time.
+// Clusters which cannot be read are skipped for driftSamplingBackoff.
+func (m *instance) sampleClustersDrifts(ctx context.Context, clusters []corev1.ObjectReference) {
+	semaphore := make(chan struct{}, driftSamplingConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range clusters {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(cluster *corev1.ObjectReference) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			clusterType := clusterproxy.GetClusterType(cluster)
+			sampleCtx, cancel := context.WithTimeout(ctx, driftSamplingTimeout)
+			defer cancel()
+
+			err := m.refreshResourceSummariesFromCluster(sampleCtx, cluster.Namespace, cluster.Name, clusterType)
+			if err != nil {
+				m.logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to get ResourceSummaries from %s %s/%s: %v",
+					clusterType, cluster.Namespace, cluster.Name, err))
+				m.setDriftSamplingFailure(cluster, time.Now())
+			}
+		}(&clusters[i])
+	}
+
+	wg.Wait()
+}
+
+// setDriftSamplingFailure records that ResourceSummaries could not be read from the cluster at now
+func (m *instance) setDriftSamplingFailure(cluster *corev1.ObjectReference, now time.Time) {
+	m.driftMux.Lock()
+	defer m.driftMux.Unlock()
+
+	m.driftSamplingFailures[*cluster] = now.Add(driftSamplingBackoff)
+}
+
+// getClustersToSampleForDrifts returns the clusters with at least one ClusterSummary in ContinuousWithDriftDetection
+// mode whose ResourceSummary is not known to be in the management cluster (those are watched instead).
+// Clusters which could not be read recently are skipped.
+func (m *instance) getClustersToSampleForDrifts(now time.Time) []corev1.ObjectReference {
+	m.clusterStatusesMux.RLock()
+	clusterSummaries := make(map[corev1.ObjectReference]*corev1.ObjectReference)
+	for k, status := range m.clusterSummaryReport {
+		if status.syncMode != configv1beta1.SyncModeContinuousWithDriftDetection {
+			continue
+		}
+		clusterSummaries[k] = getClusterRef(status.Namespace, status.ClusterName, status.ClusterType)
+	}
+	m.clusterStatusesMux.RUnlock()
+
+	m.driftMux.Lock()
+	defer m.driftMux.Unlock()
+
+	clusters := make(map[corev1.ObjectReference]bool)
+	for clusterSummaryRef, clusterRef := range clusterSummaries {
+		if current, ok := m.resourceSummaryDrifts[clusterSummaryRef]; ok && current.inManagementCluster {
+			continue
+		}
+		if retryTime, ok := m.driftSamplingFailures[*clusterRef]; ok {
+			if now.Before(retryTime) {
+				continue
+			}
+			delete(m.driftSamplingFailures, *clusterRef)
+		}
+		clusters[*clusterRef] = true
+	}
+
+	result := make([]corev1.ObjectReference, 0, len(clusters))
+	for k := range clusters {
+		result = append(result, k)
+	}
+	return result
+}
+
+// getClusterDrifts returns all drifts detected for resources deployed in a cluster
+func (m *instance) getClusterDrifts(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) []ResourceDrift {
+
+	clusterProfileStatuses := m.getClusterProfileStatusesWithKeyByCluster(clusterNamespace, clusterName, clusterType)
+
+	m.driftMux.RLock()
+	defer m.driftMux.RUnlock()
+
+	result := make([]ResourceDrift, 0)
+	for clusterSummaryRef, status := range clusterProfileStatuses {
+		current, ok := m.resourceSummaryDrifts[clusterSummaryRef]
+		if !ok {
+			continue
+		}
+
+		profileName := fmt.Sprintf("%s/%s", status.ProfileType, status.ProfileName)
+		for k := range current.drifts {
+			drift := *current.drifts[k]
+			drift.ProfileName = profileName
+			drift.Events = make([]DriftEvent, len(current.drifts[k].Events))
+			copy(drift.Events, current.drifts[k].Events)
+			result = append(result, drift)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return sortResourceDrifts(result, i, j)
+	})
+
+	return result
+}
+
+// getMostDriftedResources returns drifted resources across all clusters, sorted
+// by number of drifts
+func (m *instance) getMostDriftedResources(clusters []corev1.ObjectReference) []ClusterResourceDrift {
+	result := make([]ClusterResourceDrift, 0)
+	for i := range clusters {
+		clusterType := clusterproxy.GetClusterType(&clusters[i])
+		drifts := m.getClusterDrifts(clusters[i].Namespace, clusters[i].Name, clusterType)
+		for j := range drifts {
+			result = append(result, ClusterResourceDrift{
+				Namespace:     clusters[i].Namespace,
+				Name:          clusters[i].Name,
+				ClusterType:   clusterType,
+				ResourceDrift: drifts[j],
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].DriftCount == result[j].DriftCount {
+			return result[i].LastDriftTime.After(result[j].LastDriftTime.Time)
+		}
+		return result[i].DriftCount > result[j].DriftCount
+	})
+
+	return result
+}
+
+// getClusterProfileStatusesWithKeyByCluster returns ClusterProfileStatuses (keyed by ClusterSummary) for a cluster
+func (m *instance) getClusterProfileStatusesWithKeyByCluster(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) map[corev1.ObjectReference]ClusterProfileStatus {
+
+	m.clusterStatusesMux.RLock()
+	defer m.clusterStatusesMux.RUnlock()
+
+	result := make(map[corev1.ObjectReference]ClusterProfileStatus)
+	for k, status := range m.clusterSummaryReport {
+		if status.Namespace == clusterNamespace && status.ClusterName == clusterName &&
+			status.ClusterType == clusterType {
+
+			result[k] = status
+		}
+	}
+
+	return result
+}
+
+// sortResourceDrifts sorts by last drift time (most recent first) and then by resource
+func sortResourceDrifts(drifts []ResourceDrift, i, j int) bool {
+	if drifts[i].LastDriftTime.Equal(&drifts[j].LastDriftTime) {
+		return getResourceDriftName(&drifts[i]) < getResourceDriftName(&drifts[j])
+	}
+	return drifts[i].LastDriftTime.After(drifts[j].LastDriftTime.Time)
+}
+
+func getResourceDriftName(drift *ResourceDrift) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", drift.Category, drift.Resource.Group, drift.Resource.Kind,
+		drift.Resource.Namespace, drift.Resource.Name)
+}
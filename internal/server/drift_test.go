@@ -0,0 +1,181 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Drift", func() {
+	var resource libsveltosv1beta1.Resource
+
+	BeforeEach(func() {
+		resource = libsveltosv1beta1.Resource{
+			Namespace: randomString(),
+			Name:      randomString(),
+			Kind:      "ConfigMap",
+			Version:   "v1",
+		}
+	})
+
+	getResourceSummary := func(hash string, changed bool) *libsveltosv1beta1.ResourceSummary {
+		return &libsveltosv1beta1.ResourceSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Status: libsveltosv1beta1.ResourceSummaryStatus{
+				ResourceHashes: []libsveltosv1beta1.ResourceHash{
+					{Resource: resource, Hash: hash},
+				},
+				ResourcesChanged: changed,
+			},
+		}
+	}
+
+	It("getClusterSummaryRefForResourceSummary uses annotations and falls back to labels", func() {
+		resourceSummary := getResourceSummary(randomString(), false)
+		Expect(server.GetClusterSummaryRefForResourceSummary(resourceSummary)).To(BeNil())
+
+		namespace := randomString()
+		name := randomString()
+		resourceSummary.Labels = map[string]string{
+			libsveltosv1beta1.ClusterSummaryNamespaceLabel: namespace,
+			libsveltosv1beta1.ClusterSummaryNameLabel:      name,
+		}
+		ref := server.GetClusterSummaryRefForResourceSummary(resourceSummary)
+		Expect(ref).ToNot(BeNil())
+		Expect(ref.Namespace).To(Equal(namespace))
+		Expect(ref.Name).To(Equal(name))
+		Expect(ref.Kind).To(Equal(configv1beta1.ClusterSummaryKind))
+
+		resourceSummary.Annotations = map[string]string{
+			libsveltosv1beta1.ClusterSummaryNamespaceAnnotation: randomString(),
+			libsveltosv1beta1.ClusterSummaryNameAnnotation:      randomString(),
+		}
+		ref = server.GetClusterSummaryRefForResourceSummary(resourceSummary)
+		Expect(ref).ToNot(BeNil())
+		Expect(ref.Namespace).To(Equal(resourceSummary.Annotations[libsveltosv1beta1.ClusterSummaryNamespaceAnnotation]))
+		Expect(ref.Name).To(Equal(resourceSummary.Annotations[libsveltosv1beta1.ClusterSummaryNameAnnotation]))
+	})
+
+	It("evaluateDrifts records drifts when a category is flagged and marks them reconciled", func() {
+		current := server.NewResourceSummaryDrift(getResourceSummary("hash1", false))
+		Expect(server.GetResourceDrifts(current)).To(BeEmpty())
+
+		// Same hash, no drift
+		server.EvaluateDrifts(current, getResourceSummary("hash1", false))
+		Expect(server.GetResourceDrifts(current)).To(BeEmpty())
+
+		// Hash changed by a deployment, no drift
+		server.EvaluateDrifts(current, getResourceSummary("hash2", false))
+		Expect(server.GetResourceDrifts(current)).To(BeEmpty())
+
+		// Hash changed and drift reported
+		server.EvaluateDrifts(current, getResourceSummary("hash3", true))
+		drifts := server.GetResourceDrifts(current)
+		Expect(len(drifts)).To(Equal(1))
+		Expect(drifts[0].Resource).To(Equal(resource))
+		Expect(drifts[0].Category).To(Equal(server.DriftCategoryResources))
+		Expect(drifts[0].DriftCount).To(Equal(1))
+		Expect(len(drifts[0].Events)).To(Equal(1))
+		Expect(drifts[0].Events[0].Reconciled).To(BeFalse())
+
+		// Sveltos reverts the resource while still flagged: not a new drift
+		server.EvaluateDrifts(current, getResourceSummary("hash2", true))
+		drifts = server.GetResourceDrifts(current)
+		Expect(drifts[0].DriftCount).To(Equal(1))
+
+		// Sveltos reconciled the resource back
+		server.EvaluateDrifts(current, getResourceSummary("hash2", false))
+		drifts = server.GetResourceDrifts(current)
+		Expect(len(drifts)).To(Equal(1))
+		Expect(drifts[0].DriftCount).To(Equal(1))
+		Expect(drifts[0].Events[0].Reconciled).To(BeTrue())
+		Expect(drifts[0].Events[0].ReconciledTime).ToNot(BeNil())
+
+		// Drifted again, compared with the reconciled hash
+		server.EvaluateDrifts(current, getResourceSummary("hash4", true))
+		drifts = server.GetResourceDrifts(current)
+		Expect(len(drifts)).To(Equal(1))
+		Expect(drifts[0].DriftCount).To(Equal(2))
+		Expect(len(drifts[0].Events)).To(Equal(2))
+		Expect(drifts[0].Events[1].Reconciled).To(BeFalse())
+	})
+
+	It("evaluateDrifts records a category drift when no resource hash changed", func() {
+		current := server.NewResourceSummaryDrift(getResourceSummary("hash1", false))
+
+		server.EvaluateDrifts(current, getResourceSummary("hash1", true))
+		drifts := server.GetResourceDrifts(current)
+		Expect(len(drifts)).To(Equal(1))
+		Expect(drifts[0].Resource).To(Equal(libsveltosv1beta1.Resource{}))
+		Expect(drifts[0].Category).To(Equal(server.DriftCategoryResources))
+		Expect(drifts[0].DriftCount).To(Equal(1))
+	})
+
+	It("getClustersToSampleForDrifts skips clusters whose ResourceSummaries are in the management cluster", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		manager := server.NewManagerInstance(c, scheme, logr.Discard())
+
+		getClusterSummary := func(syncMode configv1beta1.SyncMode) *configv1beta1.ClusterSummary {
+			clusterSummary := createTestClusterSummary(randomString(), randomString(), randomString(), randomString(), nil)
+			clusterSummary.Namespace = clusterSummary.Spec.ClusterNamespace
+			clusterSummary.Spec.ClusterProfileSpec.SyncMode = syncMode
+			return clusterSummary
+		}
+
+		agentless := getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection)
+		withAgent := getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection)
+		// No ResourceSummary is created
+		continuous := getClusterSummary(configv1beta1.SyncModeContinuous)
+		manager.AddClusterProfileStatus(agentless)
+		manager.AddClusterProfileStatus(withAgent)
+		manager.AddClusterProfileStatus(continuous)
+		now := time.Now()
+		Expect(manager.GetClustersToSampleForDrifts(now)).To(HaveLen(2))
+
+		// ResourceSummary watched in the management cluster
+		resourceSummary := getResourceSummary(randomString(), false)
+		resourceSummary.Annotations = map[string]string{
+			libsveltosv1beta1.ClusterSummaryNamespaceAnnotation: agentless.Namespace,
+			libsveltosv1beta1.ClusterSummaryNameAnnotation:      agentless.Name,
+		}
+		manager.UpdateResourceSummary(resourceSummary)
+
+		clusters := manager.GetClustersToSampleForDrifts(now)
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Namespace).To(Equal(withAgent.Spec.ClusterNamespace))
+		Expect(clusters[0].Name).To(Equal(withAgent.Spec.ClusterName))
+
+		// Cluster which could not be read is skipped until backoff expires
+		manager.SetDriftSamplingFailure(&clusters[0], now)
+		Expect(manager.GetClustersToSampleForDrifts(now.Add(time.Minute))).To(BeEmpty())
+		Expect(manager.GetClustersToSampleForDrifts(now.Add(time.Hour))).To(HaveLen(1))
+	})
+})
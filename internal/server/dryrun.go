@@ -0,0 +1,207 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	clusterReportKind = "ClusterReport"
+)
+
+// DryRunReport contains what addon-controller would do in a cluster because of a
+// ClusterProfile/Profile in DryRun mode
+type DryRunReport struct {
+	ProfileName string `json:"profileName"`
+	ProfileType string `json:"profileType"`
+
+	// ProfileNamespace is set only for Profiles
+	ProfileNamespace string `json:"profileNamespace,omitempty"`
+
+	ClusterNamespace string                        `json:"clusterNamespace"`
+	ClusterName      string                        `json:"clusterName"`
+	ClusterType      libsveltosv1beta1.ClusterType `json:"clusterType"`
+
+	// ReleaseReports contains the planned action for each helm release.
+	ReleaseReports []configv1beta1.ReleaseReport `json:"releaseReports"`
+
+	// ResourceReports contains the planned action for each resource deployed because of PolicyRefs.
+	// For updates, Message contains the diff.
+	ResourceReports []configv1beta1.ResourceReport `json:"resourceReports"`
+
+	// KustomizeResourceReports contains the planned action for each resource deployed because
+	// of KustomizationRefs. For updates, Message contains the diff.
+	KustomizeResourceReports []configv1beta1.ResourceReport `json:"kustomizeResourceReports"`
+}
+
+type DryRunResult struct {
+	TotalReports int            `json:"totalReports"`
+	Reports      []DryRunReport `json:"reports"`
+}
+
+// AddClusterReport caches a ClusterReport
+func (m *instance) AddClusterReport(clusterReport *configv1beta1.ClusterReport) {
+	report := getDryRunReport(clusterReport)
+	if report == nil {
+		return
+	}
+
+	m.clusterReportMux.Lock()
+	defer m.clusterReportMux.Unlock()
+
+	m.clusterReports[*getKeyFromObject(m.scheme, clusterReport)] = *report
+}
+
+// RemoveClusterReport removes a ClusterReport from the cache
+func (m *instance) RemoveClusterReport(clusterReportNamespace, clusterReportName string) {
+	clusterReport := &corev1.ObjectReference{
+		Namespace:  clusterReportNamespace,
+		Name:       clusterReportName,
+		Kind:       clusterReportKind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+	}
+
+	m.clusterReportMux.Lock()
+	defer m.clusterReportMux.Unlock()
+
+	delete(m.clusterReports, *clusterReport)
+}
+
+// getDryRunReportsForCluster returns the DryRun reports for all profiles matching a cluster
+func (m *instance) getDryRunReportsForCluster(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) []DryRunReport {
+
+	m.clusterReportMux.RLock()
+	defer m.clusterReportMux.RUnlock()
+
+	result := make([]DryRunReport, 0)
+	for k := range m.clusterReports {
+		report := m.clusterReports[k]
+		if report.ClusterNamespace == clusterNamespace && report.ClusterName == clusterName &&
+			report.ClusterType == clusterType {
+
+			result = append(result, report)
+		}
+	}
+
+	sortDryRunReports(result)
+	return result
+}
+
+// getDryRunReportsForProfile returns the DryRun reports for all clusters matching a profile.
+// Only clusters present in the clusters map are considered.
+func (m *instance) getDryRunReportsForProfile(profileRef *corev1.ObjectReference,
+	clusters map[corev1.ObjectReference]ClusterInfo) []DryRunReport {
+
+	m.clusterReportMux.RLock()
+	defer m.clusterReportMux.RUnlock()
+
+	result := make([]DryRunReport, 0)
+	for k := range m.clusterReports {
+		report := m.clusterReports[k]
+		if report.ProfileType != profileRef.Kind || report.ProfileName != profileRef.Name ||
+			report.ProfileNamespace != profileRef.Namespace {
+
+			continue
+		}
+
+		if _, ok := clusters[*getClusterRef(report.ClusterNamespace, report.ClusterName, report.ClusterType)]; !ok {
+			continue
+		}
+
+		result = append(result, report)
+	}
+
+	sortDryRunReports(result)
+	return result
+}
+
+// getDryRunReport converts a ClusterReport. Returns nil if the ClusterReport is not owned
+// by a ClusterProfile/Profile.
+func getDryRunReport(clusterReport *configv1beta1.ClusterReport) *DryRunReport {
+	var profileOwner *corev1.ObjectReference
+	for i := range clusterReport.OwnerReferences {
+		ref := &clusterReport.OwnerReferences[i]
+		if ref.Kind == configv1beta1.ClusterProfileKind || ref.Kind == configv1beta1.ProfileKind {
+			profileOwner = &corev1.ObjectReference{Kind: ref.Kind, Name: ref.Name}
+			break
+		}
+	}
+
+	if profileOwner == nil {
+		return nil
+	}
+
+	report := &DryRunReport{
+		ProfileName:              profileOwner.Name,
+		ProfileType:              profileOwner.Kind,
+		ClusterNamespace:         clusterReport.Spec.ClusterNamespace,
+		ClusterName:              clusterReport.Spec.ClusterName,
+		ClusterType:              libsveltosv1beta1.ClusterTypeCapi,
+		ReleaseReports:           clusterReport.Status.ReleaseReports,
+		ResourceReports:          clusterReport.Status.ResourceReports,
+		KustomizeResourceReports: clusterReport.Status.KustomizeResourceReports,
+	}
+
+	if profileOwner.Kind == configv1beta1.ProfileKind {
+		// Profile and its ClusterReports are in the same namespace
+		report.ProfileNamespace = clusterReport.Namespace
+	}
+
+	if clusterReport.Labels != nil &&
+		clusterReport.Labels[configv1beta1.ClusterTypeLabel] == string(libsveltosv1beta1.ClusterTypeSveltos) {
+
+		report.ClusterType = libsveltosv1beta1.ClusterTypeSveltos
+	}
+
+	if report.ReleaseReports == nil {
+		report.ReleaseReports = make([]configv1beta1.ReleaseReport, 0)
+	}
+	if report.ResourceReports == nil {
+		report.ResourceReports = make([]configv1beta1.ResourceReport, 0)
+	}
+	if report.KustomizeResourceReports == nil {
+		report.KustomizeResourceReports = make([]configv1beta1.ResourceReport, 0)
+	}
+
+	return report
+}
+
+// sortDryRunReports sorts by profile and then by cluster
+func sortDryRunReports(reports []DryRunReport) {
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].ProfileType != reports[j].ProfileType {
+			return reports[i].ProfileType < reports[j].ProfileType
+		}
+		if reports[i].ProfileNamespace != reports[j].ProfileNamespace {
+			return reports[i].ProfileNamespace < reports[j].ProfileNamespace
+		}
+		if reports[i].ProfileName != reports[j].ProfileName {
+			return reports[i].ProfileName < reports[j].ProfileName
+		}
+		if reports[i].ClusterNamespace != reports[j].ClusterNamespace {
+			return reports[i].ClusterNamespace < reports[j].ClusterNamespace
+		}
+		return reports[i].ClusterName < reports[j].ClusterName
+	})
+}
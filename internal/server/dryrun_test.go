@@ -0,0 +1,103 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("DryRun", func() {
+	getClusterReport := func(ownerKind string) *configv1beta1.ClusterReport {
+		return &configv1beta1.ClusterReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				Labels: map[string]string{
+					configv1beta1.ClusterTypeLabel: string(libsveltosv1beta1.ClusterTypeSveltos),
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						Kind:       ownerKind,
+						Name:       randomString(),
+						APIVersion: configv1beta1.GroupVersion.String(),
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterReportSpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+			},
+			Status: configv1beta1.ClusterReportStatus{
+				ResourceReports: []configv1beta1.ResourceReport{
+					{
+						Resource: configv1beta1.Resource{Name: randomString(), Kind: "ConfigMap"},
+						Action:   string(configv1beta1.UpdateResourceAction),
+						Message:  randomString(),
+					},
+				},
+				ReleaseReports: []configv1beta1.ReleaseReport{
+					{
+						ReleaseName:      randomString(),
+						ReleaseNamespace: randomString(),
+						ChartVersion:     "1.0.0",
+						Action:           string(configv1beta1.InstallHelmAction),
+					},
+				},
+			},
+		}
+	}
+
+	It("getDryRunReport converts a ClusterReport created for a ClusterProfile", func() {
+		clusterReport := getClusterReport(configv1beta1.ClusterProfileKind)
+
+		report := server.GetDryRunReport(clusterReport)
+		Expect(report).ToNot(BeNil())
+		Expect(report.ProfileType).To(Equal(configv1beta1.ClusterProfileKind))
+		Expect(report.ProfileName).To(Equal(clusterReport.OwnerReferences[0].Name))
+		Expect(report.ProfileNamespace).To(BeEmpty())
+		Expect(report.ClusterNamespace).To(Equal(clusterReport.Spec.ClusterNamespace))
+		Expect(report.ClusterName).To(Equal(clusterReport.Spec.ClusterName))
+		Expect(report.ClusterType).To(Equal(libsveltosv1beta1.ClusterTypeSveltos))
+		Expect(report.ResourceReports).To(Equal(clusterReport.Status.ResourceReports))
+		Expect(report.ReleaseReports).To(Equal(clusterReport.Status.ReleaseReports))
+		Expect(report.KustomizeResourceReports).ToNot(BeNil())
+		Expect(report.KustomizeResourceReports).To(BeEmpty())
+	})
+
+	It("getDryRunReport sets profile namespace for Profiles", func() {
+		clusterReport := getClusterReport(configv1beta1.ProfileKind)
+		clusterReport.Labels = nil
+
+		report := server.GetDryRunReport(clusterReport)
+		Expect(report).ToNot(BeNil())
+		Expect(report.ProfileType).To(Equal(configv1beta1.ProfileKind))
+		Expect(report.ProfileNamespace).To(Equal(clusterReport.Namespace))
+		Expect(report.ClusterType).To(Equal(libsveltosv1beta1.ClusterTypeCapi))
+	})
+
+	It("getDryRunReport ignores ClusterReports not owned by a profile", func() {
+		clusterReport := getClusterReport(randomString())
+		Expect(server.GetDryRunReport(clusterReport)).To(BeNil())
+	})
+})
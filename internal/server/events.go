@@ -0,0 +1,482 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// Labels event-manager adds to the ClusterProfiles it generates
+const (
+	eventTriggerNameLabel            = "eventtrigger.lib.projectsveltos.io/eventtriggername"
+	eventReportNameLabel             = "eventtrigger.lib.projectsveltos.io/eventreportname"
+	eventClusterNamespaceLabel       = "eventtrigger.lib.projectsveltos.io/clusterNamespace"
+	eventClusterNameLabel            = "eventtrigger.lib.projectsveltos.io/clustername"
+	eventClusterTypeLabel            = "eventtrigger.lib.projectsveltos.io/clustertype"
+	generatingResourceNamespaceLabel = "eventtrigger.lib.projectsveltos.io/resourcenamespace"
+	generatingResourceNameLabel      = "eventtrigger.lib.projectsveltos.io/resourcename"
+)
+
+type EventSourceInfo struct {
+	// ResourceSelectors identifies what Kubernetes resources to select
+	ResourceSelectors []libsveltosv1beta1.ResourceSelector `json:"resourceSelectors"`
+
+	// AggregatedSelection is an optional Lua function used to further filter resources
+	AggregatedSelection string `json:"aggregatedSelection,omitempty"`
+
+	// CollectResources indicates whether matching resources are collected
+	CollectResources bool `json:"collectResources"`
+}
+
+type EventTriggerInfo struct {
+	// EventSourceName is the name of the referenced EventSource
+	EventSourceName string `json:"eventSourceName"`
+
+	// SourceClusterSelector identifies clusters to watch for events
+	SourceClusterSelector libsveltosv1beta1.Selector `json:"sourceClusterSelector"`
+
+	// OneForEvent indicates whether a ClusterProfile is generated for each matching resource
+	OneForEvent bool `json:"oneForEvent"`
+
+	// MatchingClusters are the clusters currently matching SourceClusterSelector
+	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
+}
+
+type eventReportInfo struct {
+	EventSourceName   string
+	ClusterNamespace  string
+	ClusterName       string
+	ClusterType       libsveltosv1beta1.ClusterType
+	MatchingResources []corev1.ObjectReference
+}
+
+type EventTriggerResult struct {
+	Name string `json:"name"`
+
+	EventTriggerInfo `json:",inline"`
+}
+
+type EventTriggersResult struct {
+	TotalEventTriggers int                  `json:"totalEventTriggers"`
+	EventTriggers      []EventTriggerResult `json:"eventTriggers"`
+}
+
+// GeneratedClusterProfile is a ClusterProfile an EventTrigger generated
+type GeneratedClusterProfile struct {
+	Name string `json:"name"`
+
+	// Resource is the matching resource that caused the generation. Set only for
+	// EventTriggers with OneForEvent set to true.
+	Resource *corev1.ObjectReference `json:"resource,omitempty"`
+}
+
+type ClusterEventTrigger struct {
+	// Name of the EventTrigger
+	Name string `json:"name"`
+
+	// GeneratedClusterProfiles are the ClusterProfiles the EventTrigger generated
+	// for this cluster and event
+	GeneratedClusterProfiles []GeneratedClusterProfile `json:"generatedClusterProfiles"`
+}
+
+type ClusterEvent struct {
+	// EventSourceName is the name of the EventSource which matched
+	EventSourceName string `json:"eventSourceName"`
+
+	// EventSource contains the EventSource definition. Nil if EventSource is not found.
+	EventSource *EventSourceInfo `json:"eventSource,omitempty"`
+
+	// MatchingResources are the resources in the managed cluster which triggered the event
+	MatchingResources []corev1.ObjectReference `json:"matchingResources"`
+
+	// EventTriggers are the EventTriggers reacting to the event in this cluster
+	EventTriggers []ClusterEventTrigger `json:"eventTriggers"`
+}
+
+type ClusterEventsResult struct {
+	TotalEvents int            `json:"totalEvents"`
+	Events      []ClusterEvent `json:"events"`
+}
+
+func (m *instance) AddEventSource(eventSource *libsveltosv1beta1.EventSource) {
+	info := EventSourceInfo{
+		ResourceSelectors:   eventSource.Spec.ResourceSelectors,
+		AggregatedSelection: eventSource.Spec.AggregatedSelection,
+		CollectResources:    eventSource.Spec.CollectResources,
+	}
+
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	m.eventSources[eventSource.Name] = info
+}
+
+func (m *instance) RemoveEventSource(eventSourceName string) {
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	delete(m.eventSources, eventSourceName)
+}
+
+func (m *instance) AddEventTrigger(eventTrigger *eventv1beta1.EventTrigger) {
+	matchingClusters := make([]corev1.ObjectReference, len(eventTrigger.Status.MatchingClusterRefs))
+	copy(matchingClusters, eventTrigger.Status.MatchingClusterRefs)
+
+	info := EventTriggerInfo{
+		EventSourceName:       eventTrigger.Spec.EventSourceName,
+		SourceClusterSelector: eventTrigger.Spec.SourceClusterSelector,
+		OneForEvent:           eventTrigger.Spec.OneForEvent,
+		MatchingClusters:      matchingClusters,
+	}
+
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	m.eventTriggers[eventTrigger.Name] = info
+}
+
+func (m *instance) RemoveEventTrigger(eventTriggerName string) {
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	delete(m.eventTriggers, eventTriggerName)
+}
+
+func (m *instance) AddEventReport(eventReport *libsveltosv1beta1.EventReport) {
+	matchingResources := make([]corev1.ObjectReference, len(eventReport.Spec.MatchingResources))
+	copy(matchingResources, eventReport.Spec.MatchingResources)
+
+	info := eventReportInfo{
+		EventSourceName:   eventReport.Spec.EventSourceName,
+		ClusterNamespace:  eventReport.Spec.ClusterNamespace,
+		ClusterName:       eventReport.Spec.ClusterName,
+		ClusterType:       eventReport.Spec.ClusterType,
+		MatchingResources: matchingResources,
+	}
+
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	m.eventReports[*getKeyFromObject(m.scheme, eventReport)] = info
+}
+
+func (m *instance) RemoveEventReport(eventReportNamespace, eventReportName string) {
+	eventReport := &corev1.ObjectReference{
+		Namespace:  eventReportNamespace,
+		Name:       eventReportName,
+		Kind:       libsveltosv1beta1.EventReportKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+
+	m.eventMux.Lock()
+	defer m.eventMux.Unlock()
+
+	delete(m.eventReports, *eventReport)
+}
+
+// getEventTriggers returns all EventTriggers whose name contains nameFilter, sorted by name
+func (m *instance) getEventTriggers(nameFilter string) []EventTriggerResult {
+	m.eventMux.RLock()
+	defer m.eventMux.RUnlock()
+
+	result := make([]EventTriggerResult, 0, len(m.eventTriggers))
+	for name := range m.eventTriggers {
+		if nameFilter != "" && !strings.Contains(name, nameFilter) {
+			continue
+		}
+		result = append(result, EventTriggerResult{Name: name, EventTriggerInfo: m.eventTriggers[name]})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getClusterEvents returns events reported for a cluster along with the EventTriggers
+// reacting to those and the ClusterProfiles they generated
+func (m *instance) getClusterEvents(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) ([]ClusterEvent, error) {
+
+	events, eventTriggers := m.getCachedClusterEvents(clusterNamespace, clusterName, clusterType)
+
+	for i := range events {
+		for _, eventTriggerName := range eventTriggers {
+			eventTrigger := m.getEventTriggerInfo(eventTriggerName)
+			if eventTrigger == nil {
+				continue
+			}
+
+			generated, err := m.getGeneratedClusterProfiles(ctx, eventTriggerName, events[i].EventSourceName,
+				clusterNamespace, clusterName, clusterType)
+			if err != nil {
+				return nil, err
+			}
+
+			// EventSourceName can be expressed as a template. So an EventTrigger is
+			// considered if it references the EventSource or it generated ClusterProfiles for it.
+			if eventTrigger.EventSourceName != events[i].EventSourceName && len(generated) == 0 {
+				continue
+			}
+
+			events[i].EventTriggers = append(events[i].EventTriggers, ClusterEventTrigger{
+				Name:                     eventTriggerName,
+				GeneratedClusterProfiles: generated,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// getCachedClusterEvents returns events reported for a cluster and the names of the
+// EventTriggers matching the cluster
+func (m *instance) getCachedClusterEvents(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) ([]ClusterEvent, []string) {
+
+	m.eventMux.RLock()
+	defer m.eventMux.RUnlock()
+
+	events := make([]ClusterEvent, 0)
+	for k := range m.eventReports {
+		report := m.eventReports[k]
+		if report.ClusterNamespace != clusterNamespace || report.ClusterName != clusterName ||
+			report.ClusterType != clusterType {
+
+			continue
+		}
+
+		event := ClusterEvent{
+			EventSourceName:   report.EventSourceName,
+			MatchingResources: report.MatchingResources,
+			EventTriggers:     make([]ClusterEventTrigger, 0),
+		}
+		if eventSource, ok := m.eventSources[report.EventSourceName]; ok {
+			event.EventSource = &eventSource
+		}
+		events = append(events, event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].EventSourceName < events[j].EventSourceName
+	})
+
+	eventTriggers := make([]string, 0)
+	for name := range m.eventTriggers {
+		if isClusterMatchingEventTrigger(m.eventTriggers[name].MatchingClusters, clusterNamespace,
+			clusterName, clusterType) {
+
+			eventTriggers = append(eventTriggers, name)
+		}
+	}
+	sort.Strings(eventTriggers)
+
+	return events, eventTriggers
+}
+
+func (m *instance) getEventTriggerInfo(eventTriggerName string) *EventTriggerInfo {
+	m.eventMux.RLock()
+	defer m.eventMux.RUnlock()
+
+	info, ok := m.eventTriggers[eventTriggerName]
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+// getGeneratedClusterProfiles returns the ClusterProfiles an EventTrigger generated for a
+// cluster because of an event
+func (m *instance) getGeneratedClusterProfiles(ctx context.Context, eventTriggerName, eventSourceName,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+) ([]GeneratedClusterProfile, error) {
+
+	clusterProfiles := &configv1beta1.ClusterProfileList{}
+	err := m.client.List(ctx, clusterProfiles, client.MatchingLabels{
+		eventTriggerNameLabel:      eventTriggerName,
+		eventReportNameLabel:       eventSourceName,
+		eventClusterNamespaceLabel: clusterNamespace,
+		eventClusterNameLabel:      clusterName,
+		eventClusterTypeLabel:      string(clusterType),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GeneratedClusterProfile, len(clusterProfiles.Items))
+	for i := range clusterProfiles.Items {
+		result[i] = GeneratedClusterProfile{
+			Name:     clusterProfiles.Items[i].Name,
+			Resource: getGeneratingResource(clusterProfiles.Items[i].Labels),
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// getGeneratingResource returns the resource which caused a ClusterProfile to be generated.
+// event-manager sets those labels only when EventTrigger OneForEvent is true. The namespace label
+// is not set for cluster wide resources.
+func getGeneratingResource(labels map[string]string) *corev1.ObjectReference {
+	name, ok := labels[generatingResourceNameLabel]
+	if !ok {
+		return nil
+	}
+
+	return &corev1.ObjectReference{
+		Namespace: labels[generatingResourceNamespaceLabel],
+		Name:      name,
+	}
+}
+
+func isClusterMatchingEventTrigger(matchingClusters []corev1.ObjectReference, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) bool {
+
+	for i := range matchingClusters {
+		cluster := &matchingClusters[i]
+		if cluster.Namespace != clusterNamespace || cluster.Name != clusterName {
+			continue
+		}
+		isSveltosCluster := cluster.Kind == libsveltosv1beta1.SveltosClusterKind
+		if isSveltosCluster == (clusterType == libsveltosv1beta1.ClusterTypeSveltos) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeInaccessibleEventTriggers removes from events all EventTriggers user cannot access
+func (m *instance) removeInaccessibleEventTriggers(events []ClusterEvent, user string) error {
+	canGet := map[string]bool{}
+	for i := range events {
+		accessible := make([]ClusterEventTrigger, 0, len(events[i].EventTriggers))
+		for j := range events[i].EventTriggers {
+			name := events[i].EventTriggers[j].Name
+			allowed, ok := canGet[name]
+			if !ok {
+				var err error
+				allowed, err = m.canGetEventTrigger(name, user)
+				if err != nil {
+					return err
+				}
+				canGet[name] = allowed
+			}
+			if allowed {
+				accessible = append(accessible, events[i].EventTriggers[j])
+			}
+		}
+		events[i].EventTriggers = accessible
+	}
+
+	return nil
+}
+
+// ProfileProvenance describes why event-manager generated a ClusterProfile
+type ProfileProvenance struct {
+	// EventTriggerName is the name of the EventTrigger which generated the ClusterProfile
+	EventTriggerName string `json:"eventTriggerName"`
+
+	// EventSourceName is the name of the EventSource which matched
+	EventSourceName string `json:"eventSourceName,omitempty"`
+
+	// EventReport is the EventReport, in the management cluster, containing the event
+	EventReport *corev1.ObjectReference `json:"eventReport,omitempty"`
+
+	// Cluster is the managed cluster where the event happened
+	Cluster *corev1.ObjectReference `json:"cluster,omitempty"`
+
+	// Resource is the matching resource that caused the generation. Set only for
+	// EventTriggers with OneForEvent set to true.
+	Resource *corev1.ObjectReference `json:"resource,omitempty"`
+}
+
+// GetProfileProvenance returns the provenance of a ClusterProfile/Profile generated by an EventTrigger.
+// Returns nil for any other ClusterProfile/Profile.
+func GetProfileProvenance(profile client.Object) *ProfileProvenance {
+	labels := profile.GetLabels()
+
+	eventTriggerName := labels[eventTriggerNameLabel]
+	if eventTriggerName == "" {
+		for _, ref := range profile.GetOwnerReferences() {
+			if ref.Kind == eventv1beta1.EventTriggerKind {
+				eventTriggerName = ref.Name
+				break
+			}
+		}
+	}
+
+	if eventTriggerName == "" {
+		return nil
+	}
+
+	provenance := &ProfileProvenance{
+		EventTriggerName: eventTriggerName,
+		EventSourceName:  labels[eventReportNameLabel],
+		Resource:         getGeneratingResource(labels),
+	}
+
+	clusterName := labels[eventClusterNameLabel]
+	if clusterName == "" {
+		return provenance
+	}
+
+	clusterNamespace := labels[eventClusterNamespaceLabel]
+	clusterType := libsveltosv1beta1.ClusterType(labels[eventClusterTypeLabel])
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		provenance.Cluster = &corev1.ObjectReference{
+			Namespace:  clusterNamespace,
+			Name:       clusterName,
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+	} else {
+		clusterType = libsveltosv1beta1.ClusterTypeCapi
+		provenance.Cluster = &corev1.ObjectReference{
+			Namespace:  clusterNamespace,
+			Name:       clusterName,
+			Kind:       clusterv1.ClusterKind,
+			APIVersion: clusterv1.GroupVersion.String(),
+		}
+	}
+
+	if provenance.EventSourceName != "" {
+		provenance.EventReport = &corev1.ObjectReference{
+			Namespace:  clusterNamespace,
+			Name:       libsveltosv1beta1.GetEventReportName(provenance.EventSourceName, clusterName, &clusterType),
+			Kind:       libsveltosv1beta1.EventReportKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+	}
+
+	return provenance
+}
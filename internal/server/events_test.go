@@ -0,0 +1,244 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Events", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+	})
+
+	It("getGeneratingResource returns resource only when labels are set", func() {
+		Expect(server.GetGeneratingResource(map[string]string{randomString(): randomString()})).To(BeNil())
+
+		namespace := randomString()
+		name := randomString()
+		resource := server.GetGeneratingResource(map[string]string{
+			"eventtrigger.lib.projectsveltos.io/resourcenamespace": namespace,
+			"eventtrigger.lib.projectsveltos.io/resourcename":      name,
+		})
+		Expect(resource).ToNot(BeNil())
+		Expect(resource.Namespace).To(Equal(namespace))
+		Expect(resource.Name).To(Equal(name))
+	})
+
+	It("isClusterMatchingEventTrigger considers cluster type", func() {
+		namespace := randomString()
+		name := randomString()
+		matchingClusters := []corev1.ObjectReference{
+			{
+				Namespace: namespace, Name: name,
+				Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+			},
+		}
+
+		Expect(server.IsClusterMatchingEventTrigger(matchingClusters, namespace, name,
+			libsveltosv1beta1.ClusterTypeSveltos)).To(BeTrue())
+		Expect(server.IsClusterMatchingEventTrigger(matchingClusters, namespace, name,
+			libsveltosv1beta1.ClusterTypeCapi)).To(BeFalse())
+		Expect(server.IsClusterMatchingEventTrigger(matchingClusters, namespace, randomString(),
+			libsveltosv1beta1.ClusterTypeSveltos)).To(BeFalse())
+	})
+
+	It("getCachedClusterEvents returns EventReports and EventTriggers for a cluster", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+
+		eventSource := &libsveltosv1beta1.EventSource{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: libsveltosv1beta1.EventSourceSpec{
+				CollectResources: true,
+			},
+		}
+
+		eventReport := &libsveltosv1beta1.EventReport{
+			ObjectMeta: metav1.ObjectMeta{Namespace: clusterNamespace, Name: randomString()},
+			Spec: libsveltosv1beta1.EventReportSpec{
+				ClusterNamespace: clusterNamespace,
+				ClusterName:      clusterName,
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+				EventSourceName:  eventSource.Name,
+				MatchingResources: []corev1.ObjectReference{
+					{Kind: "Service", APIVersion: "v1", Namespace: randomString(), Name: randomString()},
+				},
+			},
+		}
+
+		eventTrigger := &eventv1beta1.EventTrigger{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: eventv1beta1.EventTriggerSpec{
+				EventSourceName: eventSource.Name,
+			},
+			Status: eventv1beta1.EventTriggerStatus{
+				MatchingClusterRefs: []corev1.ObjectReference{
+					{
+						Namespace: clusterNamespace, Name: clusterName,
+						Kind: clusterv1.ClusterKind, APIVersion: clusterv1.GroupVersion.String(),
+					},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		server.InitializeManagerInstance(ctx, nil, c, scheme, randomPort(), logger)
+		manager := server.GetManagerInstance()
+		manager.AddEventSource(eventSource)
+		manager.AddEventReport(eventReport)
+		manager.AddEventTrigger(eventTrigger)
+
+		events, eventTriggers := manager.GetCachedClusterEvents(clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeCapi)
+		Expect(len(events)).To(Equal(1))
+		Expect(events[0].EventSourceName).To(Equal(eventSource.Name))
+		Expect(events[0].EventSource).ToNot(BeNil())
+		Expect(events[0].EventSource.CollectResources).To(BeTrue())
+		Expect(events[0].MatchingResources).To(Equal(eventReport.Spec.MatchingResources))
+		Expect(eventTriggers).To(ContainElement(eventTrigger.Name))
+
+		events, eventTriggers = manager.GetCachedClusterEvents(clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(events).To(BeEmpty())
+		Expect(eventTriggers).ToNot(ContainElement(eventTrigger.Name))
+
+		manager.RemoveEventReport(eventReport.Namespace, eventReport.Name)
+		manager.RemoveEventTrigger(eventTrigger.Name)
+		events, eventTriggers = manager.GetCachedClusterEvents(clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeCapi)
+		Expect(events).To(BeEmpty())
+		Expect(eventTriggers).ToNot(ContainElement(eventTrigger.Name))
+	})
+
+	It("GetProfileProvenance returns EventTrigger, EventReport, cluster and resource", func() {
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		Expect(server.GetProfileProvenance(clusterProfile)).To(BeNil())
+
+		eventTriggerName := randomString()
+		eventSourceName := randomString()
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		resourceNamespace := randomString()
+		resourceName := randomString()
+		clusterProfile.Labels = map[string]string{
+			"eventtrigger.lib.projectsveltos.io/eventtriggername":  eventTriggerName,
+			"eventtrigger.lib.projectsveltos.io/eventreportname":   eventSourceName,
+			"eventtrigger.lib.projectsveltos.io/clusterNamespace":  clusterNamespace,
+			"eventtrigger.lib.projectsveltos.io/clustername":       clusterName,
+			"eventtrigger.lib.projectsveltos.io/clustertype":       string(libsveltosv1beta1.ClusterTypeSveltos),
+			"eventtrigger.lib.projectsveltos.io/resourcenamespace": resourceNamespace,
+			"eventtrigger.lib.projectsveltos.io/resourcename":      resourceName,
+		}
+
+		provenance := server.GetProfileProvenance(clusterProfile)
+		Expect(provenance).ToNot(BeNil())
+		Expect(provenance.EventTriggerName).To(Equal(eventTriggerName))
+		Expect(provenance.EventSourceName).To(Equal(eventSourceName))
+		Expect(provenance.Cluster).ToNot(BeNil())
+		Expect(provenance.Cluster.Namespace).To(Equal(clusterNamespace))
+		Expect(provenance.Cluster.Name).To(Equal(clusterName))
+		Expect(provenance.Cluster.Kind).To(Equal(libsveltosv1beta1.SveltosClusterKind))
+		Expect(provenance.Resource).ToNot(BeNil())
+		Expect(provenance.Resource.Namespace).To(Equal(resourceNamespace))
+		Expect(provenance.Resource.Name).To(Equal(resourceName))
+
+		clusterType := libsveltosv1beta1.ClusterTypeSveltos
+		Expect(provenance.EventReport).ToNot(BeNil())
+		Expect(provenance.EventReport.Namespace).To(Equal(clusterNamespace))
+		Expect(provenance.EventReport.Name).To(Equal(
+			libsveltosv1beta1.GetEventReportName(eventSourceName, clusterName, &clusterType)))
+
+		// event-manager does not set the resourcenamespace label for cluster wide resources
+		delete(clusterProfile.Labels, "eventtrigger.lib.projectsveltos.io/resourcenamespace")
+		provenance = server.GetProfileProvenance(clusterProfile)
+		Expect(provenance.Resource).ToNot(BeNil())
+		Expect(provenance.Resource.Namespace).To(BeEmpty())
+		Expect(provenance.Resource.Name).To(Equal(resourceName))
+	})
+
+	It("getProfileFiltersFromQuery rejects an invalid generated value", func() {
+		gin.SetMode(gin.TestMode)
+		getFilters := func(url string) (*bool, error) {
+			req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
+			Expect(err).To(BeNil())
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = req
+			filters, err := server.GetProfileFiltersFromQuery(c)
+			if err != nil {
+				return nil, err
+			}
+			return filters.Generated, nil
+		}
+
+		generated, err := getFilters("/profiles?generated=true")
+		Expect(err).To(BeNil())
+		Expect(generated).ToNot(BeNil())
+		Expect(*generated).To(BeTrue())
+
+		generated, err = getFilters("/profiles")
+		Expect(err).To(BeNil())
+		Expect(generated).To(BeNil())
+
+		_, err = getFilters("/profiles?generated=yes-please")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("GetProfileProvenance falls back to EventTrigger owner reference", func() {
+		eventTriggerName := randomString()
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: eventv1beta1.EventTriggerKind, Name: eventTriggerName, APIVersion: eventv1beta1.GroupVersion.String()},
+				},
+			},
+		}
+
+		provenance := server.GetProfileProvenance(clusterProfile)
+		Expect(provenance).ToNot(BeNil())
+		Expect(provenance.EventTriggerName).To(Equal(eventTriggerName))
+		Expect(provenance.Cluster).To(BeNil())
+		Expect(provenance.EventReport).To(BeNil())
+	})
+})
@@ -16,6 +16,26 @@ limitations under the License.
 
 package server
 
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	MaxPendingChangeRequests = maxPendingChangeRequests
+	ChangeRequestRetention   = changeRequestRetention
+)
+
 var (
 	GetClustersInRange    = getClustersInRange
 	GetHelmReleaseInRange = getHelmReleaseInRange
@@ -24,11 +44,101 @@ var (
 	SortResources  = sortResources
 	SortHelmCharts = sortHelmCharts
 
-	ExamineClusterConditions = examineClusterConditions
+	ExamineClusterConditions    = examineClusterConditions
+	GetCAPIClusterConditions    = getCAPIClusterConditions
+	GetSveltosClusterConditions = getSveltosClusterConditions
+	HasMatchingCondition        = hasMatchingCondition
+
+	GetSveltosClusterInfo     = getSveltosClusterInfo
+	ParseTokenRequestTime     = parseTokenRequestTime
+	EvaluateFleetConnectivity = evaluateFleetConnectivity
+
+	ProbeRestConfig          = probeRestConfig
+	GetCertificateExpiration = getCertificateExpiration
+
+	SetPauseAnnotations     = setPauseAnnotations
+	ValidateClusterAPIPause = validateClusterAPIPause
+	GetPauseResult          = getPauseResult
+
+	PatchClusterLabels     = patchClusterLabels
+	GetMatchingProfiles    = getMatchingProfiles
+	GetImpersonationConfig = getImpersonationConfig
+
+	GetRestConfigFromKubeconfig = getRestConfigFromKubeconfig
+	RegisterSveltosCluster      = registerSveltosCluster
+	DeregisterSveltosCluster    = deregisterSveltosCluster
+
+	DecodeProfile       = decodeProfile
+	GetValidationErrors = getValidationErrors
+	CreateProfileObject = createProfileObject
+	UpdateProfileObject = updateProfileObject
+	DeleteProfileObject = deleteProfileObject
+
+	GetClusterSummaryRef = getClusterSummaryRef
+	RequestRedeploy      = requestRedeploy
+	IsRedeployComplete   = isRedeployComplete
+	HasRedeployStarted   = hasRedeployStarted
+	WatchRedeploy        = watchRedeploy
+
+	GetSyncModeConfirmationToken = getSyncModeConfirmationToken
+	GetDryRunSummary             = getDryRunSummary
+	SetProfileSyncMode           = setProfileSyncMode
+
+	ValidateBulkOperationRequest = validateBulkOperationRequest
+	NewBulkJob                   = newBulkJob
+
+	DiffSpecs            = diffSpecs
+	CreateChangeRequest  = createChangeRequest
+	GetChangeRequest     = getChangeRequest
+	ListChangeRequests   = listChangeRequests
+	ApproveChangeRequest = approveChangeRequest
+	RejectChangeRequest  = rejectChangeRequest
+
+	GetSpecAuthor          = getSpecAuthor
+	GetProfileRevisionDiff = getProfileRevisionDiff
+
+	ExportProfileBundle = exportProfileBundle
+	EncodeProfileBundle = encodeProfileBundle
+	DecodeProfileBundle = decodeProfileBundle
+	ImportProfileBundle = importProfileBundle
+
+	DecodeHelmRelease             = decodeHelmRelease
+	GetHelmReleaseRevision        = getHelmReleaseRevision
+	MaskSensitiveValues           = maskSensitiveValues
+	CanGetSecretsInManagedCluster = canGetSecretsInManagedCluster
+
+	DiffHelmReleases = diffHelmReleases
+	DiffResources    = diffResources
+
+	ComputeCompliance     = computeCompliance
+	GetExpectedHelmCharts = getExpectedHelmCharts
+
+	GetClusterSummaryRefForResourceSummary = getClusterSummaryRefForResourceSummary
+
+	GetDryRunReport = getDryRunReport
+
+	GetGeneratingResource         = getGeneratingResource
+	IsClusterMatchingEventTrigger = isClusterMatchingEventTrigger
+
+	EvaluateClassifierConflicts   = evaluateClassifierConflicts
+	GetClassifiersManagingCluster = getClassifiersManagingCluster
+	GetManagedLabels              = getManagedLabels
+
+	EvaluateClusterHealth  = evaluateClusterHealth
+	AppendHealthEvaluation = appendHealthEvaluation
+
+	GetRoleDefinitions = getRoleDefinitions
+	GetTenants         = getTenants
+
+	RecordSelectionChange = recordSelectionChange
+
+	GetCAPIClusterInfo = getCAPIClusterInfo
 )
 
 var (
 	GetClusterFiltersFromQuery = getClusterFiltersFromQuery
+	GetProfileFiltersFromQuery = getProfileFiltersFromQuery
+	GetBundleOptionsFromQuery  = getBundleOptionsFromQuery
 )
 
 func GetNamespaceFilter(f clusterFilters) string {
@@ -42,3 +152,97 @@ func GetNameFilter(f clusterFilters) string {
 func GetLabelFilter(f clusterFilters) string {
 	return f.labelSelector.String()
 }
+
+type ResourceSummaryDrift = resourceSummaryDrift
+
+func NewResourceSummaryDrift(resourceSummary *libsveltosv1beta1.ResourceSummary) *ResourceSummaryDrift {
+	return newResourceSummaryDrift(resourceSummary)
+}
+
+func EvaluateDrifts(current *ResourceSummaryDrift, resourceSummary *libsveltosv1beta1.ResourceSummary) {
+	now := metav1.Now()
+	evaluateDrifts(current, getResourceSummaryHashes(resourceSummary), getResourceSummaryChanged(resourceSummary), &now)
+}
+
+func GetResourceDrifts(current *ResourceSummaryDrift) []ResourceDrift {
+	result := make([]ResourceDrift, 0, len(current.drifts))
+	for k := range current.drifts {
+		result = append(result, *current.drifts[k])
+	}
+	return result
+}
+
+func (m *instance) GetCachedClusterEvents(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) ([]ClusterEvent, []string) {
+
+	return m.getCachedClusterEvents(clusterNamespace, clusterName, clusterType)
+}
+
+func (m *instance) GetContributingSets(profileRef *corev1.ObjectReference, setRefs []string,
+	cluster *corev1.ObjectReference) []string {
+
+	return m.getContributingSets(profileRef, setRefs, cluster)
+}
+
+func (m *instance) ProbeCluster(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *ProbeResult {
+
+	return m.probeCluster(ctx, clusterNamespace, clusterName, clusterType)
+}
+
+func (m *instance) SetClusterPaused(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, paused bool, user, reason string) error {
+
+	return m.setClusterPaused(ctx, clusterNamespace, clusterName, clusterType, paused, user, reason)
+}
+
+func (m *instance) StartBulkJob(job *BulkJob, concurrency int,
+	action func(ctx context.Context, cluster *BulkClusterResult) error) *BulkJob {
+
+	return m.startBulkJob(job, concurrency, action)
+}
+
+func (m *instance) GetBulkJob(id, user string) *BulkJob {
+	return m.getBulkJob(id, user)
+}
+
+func (m *instance) RunBulkAction(ctx context.Context, request *BulkOperationRequest, cluster *BulkClusterResult,
+	user string, impersonatingClient client.Client) error {
+
+	return m.runBulkAction(ctx, request, cluster, &authenticationv1.UserInfo{Username: user}, impersonatingClient)
+}
+
+func (m *instance) GetProfileSpecClusters(profileRef *corev1.ObjectReference, spec *configv1beta1.Spec,
+	clusters map[corev1.ObjectReference]ClusterInfo) []corev1.ObjectReference {
+
+	return m.getProfileSpecClusters(profileRef, spec, clusters)
+}
+
+func (m *instance) GetClustersToSampleForDrifts(now time.Time) []corev1.ObjectReference {
+	return m.getClustersToSampleForDrifts(now)
+}
+
+func (m *instance) SetDriftSamplingFailure(cluster *corev1.ObjectReference, now time.Time) {
+	m.setDriftSamplingFailure(cluster, now)
+}
+
+func (m *instance) GetProfileRevisions(profile *corev1.ObjectReference) []ProfileRevision {
+	return m.getProfileRevisions(profile)
+}
+
+// NewManagerInstance returns a manager not shared with other tests. Only fields needed
+// by tests using a client are initialized.
+func NewManagerInstance(c client.Client, scheme *runtime.Scheme, logger logr.Logger) *instance {
+	return &instance{
+		client:                c,
+		scheme:                scheme,
+		logger:                logger,
+		probes:                make(map[corev1.ObjectReference]*ProbeResult),
+		probesInFlight:        make(map[corev1.ObjectReference]chan struct{}),
+		clusterSummaryReport:  make(map[corev1.ObjectReference]ClusterProfileStatus),
+		resourceSummaryDrifts: make(map[corev1.ObjectReference]*resourceSummaryDrift),
+		driftSamplingFailures: make(map[corev1.ObjectReference]time.Time),
+		bulkJobs:              make(map[string]*BulkJob),
+		profileRevisions:      make(map[corev1.ObjectReference][]ProfileRevision),
+	}
+}
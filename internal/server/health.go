@@ -0,0 +1,565 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// maxHealthEvaluations is the maximum number of health evaluations kept per cluster
+	maxHealthEvaluations = 20
+)
+
+type ClusterHealthCheckInfo struct {
+	ClusterSelector libsveltosv1beta1.Selector        `json:"clusterSelector"`
+	LivenessChecks  []libsveltosv1beta1.LivenessCheck `json:"livenessChecks"`
+	Notifications   []libsveltosv1beta1.Notification  `json:"notifications"`
+
+	// MatchingClusters are the clusters currently matching the ClusterHealthCheck
+	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
+
+	// clusterConditions contains, per cluster, the outcome of each liveness check
+	clusterConditions map[corev1.ObjectReference][]libsveltosv1beta1.Condition
+}
+
+type HealthCheckInfo struct {
+	ResourceSelectors []libsveltosv1beta1.ResourceSelector `json:"resourceSelectors"`
+	EvaluateHealth    string                               `json:"evaluateHealth"`
+	CollectResources  bool                                 `json:"collectResources"`
+}
+
+type healthCheckReportInfo struct {
+	HealthCheckName string
+	Cluster         corev1.ObjectReference
+	Resources       []ResourceHealth
+}
+
+type ClusterHealthCheckResult struct {
+	Name string `json:"name"`
+
+	ClusterHealthCheckInfo `json:",inline"`
+
+	// HealthChecks contains the HealthChecks referenced by liveness checks of type HealthCheck
+	HealthChecks map[string]HealthCheckInfo `json:"healthChecks"`
+}
+
+type ClusterHealthChecksResult struct {
+	TotalClusterHealthChecks int                        `json:"totalClusterHealthChecks"`
+	ClusterHealthChecks      []ClusterHealthCheckResult `json:"clusterHealthChecks"`
+}
+
+// LivenessCheckStatus is the outcome of a ClusterHealthCheck liveness check on a cluster
+type LivenessCheckStatus struct {
+	ClusterHealthCheckName string                              `json:"clusterHealthCheckName"`
+	Name                   string                              `json:"name"`
+	Type                   libsveltosv1beta1.ConditionType     `json:"type"`
+	Status                 corev1.ConditionStatus              `json:"status"`
+	Severity               libsveltosv1beta1.ConditionSeverity `json:"severity,omitempty"`
+	Message                string                              `json:"message,omitempty"`
+	LastTransitionTime     metav1.Time                         `json:"lastTransitionTime"`
+}
+
+// ResourceHealth is the health of a resource evaluated by a HealthCheck on a cluster
+type ResourceHealth struct {
+	HealthCheckName string                         `json:"healthCheckName"`
+	Resource        corev1.ObjectReference         `json:"resource"`
+	HealthStatus    libsveltosv1beta1.HealthStatus `json:"healthStatus"`
+	Message         string                         `json:"message,omitempty"`
+}
+
+type ClusterHealthSummary struct {
+	// Status is the worst health status across all liveness checks and resources
+	Status libsveltosv1beta1.HealthStatus `json:"status"`
+
+	// FailingChecks is the number of failing liveness checks plus the number of resources not healthy
+	FailingChecks int `json:"failingChecks"`
+}
+
+// HealthEvaluation is the health of a cluster at a given time
+type HealthEvaluation struct {
+	Time metav1.Time `json:"time"`
+
+	ClusterHealthSummary `json:",inline"`
+
+	// Messages contains a message for each failing liveness check and for each resource not healthy
+	Messages []string `json:"messages"`
+}
+
+type ClusterHealthResult struct {
+	// Health is nil if no health check is evaluated on the cluster
+	Health *ClusterHealthSummary `json:"health"`
+
+	LivenessChecks []LivenessCheckStatus `json:"livenessChecks"`
+	Resources      []ResourceHealth      `json:"resources"`
+
+	// History contains the last health evaluations, most recent first
+	History []HealthEvaluation `json:"history"`
+}
+
+type UnhealthyCluster struct {
+	Cluster corev1.ObjectReference `json:"cluster"`
+
+	ClusterHealthSummary `json:",inline"`
+}
+
+type FleetHealthResult struct {
+	TotalClusters        int `json:"totalClusters"`
+	HealthyClusters      int `json:"healthyClusters"`
+	ProgressingClusters  int `json:"progressingClusters"`
+	DegradedClusters     int `json:"degradedClusters"`
+	SuspendedClusters    int `json:"suspendedClusters"`
+	NotEvaluatedClusters int `json:"notEvaluatedClusters"`
+
+	TotalUnhealthyClusters int                `json:"totalUnhealthyClusters"`
+	UnhealthyClusters      []UnhealthyCluster `json:"unhealthyClusters"`
+}
+
+func (m *instance) AddClusterHealthCheck(clusterHealthCheck *libsveltosv1beta1.ClusterHealthCheck) {
+	info := ClusterHealthCheckInfo{
+		ClusterSelector:   clusterHealthCheck.Spec.ClusterSelector,
+		LivenessChecks:    clusterHealthCheck.Spec.LivenessChecks,
+		Notifications:     clusterHealthCheck.Spec.Notifications,
+		MatchingClusters:  make([]corev1.ObjectReference, 0, len(clusterHealthCheck.Status.MatchingClusterRefs)),
+		clusterConditions: map[corev1.ObjectReference][]libsveltosv1beta1.Condition{},
+	}
+
+	for i := range clusterHealthCheck.Status.MatchingClusterRefs {
+		info.MatchingClusters = append(info.MatchingClusters,
+			*normalizeClusterRef(&clusterHealthCheck.Status.MatchingClusterRefs[i]))
+	}
+	for i := range clusterHealthCheck.Status.ClusterConditions {
+		cc := &clusterHealthCheck.Status.ClusterConditions[i]
+		info.clusterConditions[*normalizeClusterRef(&cc.ClusterInfo.Cluster)] = cc.Conditions
+	}
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	affectedClusters := getClustersWithConditions(m.clusterHealthChecks[clusterHealthCheck.Name], info)
+	m.clusterHealthChecks[clusterHealthCheck.Name] = info
+
+	now := metav1.Now()
+	for i := range affectedClusters {
+		m.recordHealthEvaluation(&affectedClusters[i], &now)
+	}
+}
+
+func (m *instance) RemoveClusterHealthCheck(clusterHealthCheckName string) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	affectedClusters := getClustersWithConditions(m.clusterHealthChecks[clusterHealthCheckName])
+	delete(m.clusterHealthChecks, clusterHealthCheckName)
+
+	now := metav1.Now()
+	for i := range affectedClusters {
+		m.recordHealthEvaluation(&affectedClusters[i], &now)
+	}
+}
+
+func (m *instance) AddHealthCheck(healthCheck *libsveltosv1beta1.HealthCheck) {
+	info := HealthCheckInfo{
+		ResourceSelectors: healthCheck.Spec.ResourceSelectors,
+		EvaluateHealth:    healthCheck.Spec.EvaluateHealth,
+		CollectResources:  healthCheck.Spec.CollectResources,
+	}
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	m.healthChecks[healthCheck.Name] = info
+}
+
+func (m *instance) RemoveHealthCheck(healthCheckName string) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	delete(m.healthChecks, healthCheckName)
+}
+
+func (m *instance) AddHealthCheckReport(healthCheckReport *libsveltosv1beta1.HealthCheckReport) {
+	info := healthCheckReportInfo{
+		HealthCheckName: healthCheckReport.Spec.HealthCheckName,
+		Cluster: *getClusterRef(healthCheckReport.Spec.ClusterNamespace, healthCheckReport.Spec.ClusterName,
+			healthCheckReport.Spec.ClusterType),
+		Resources: make([]ResourceHealth, len(healthCheckReport.Spec.ResourceStatuses)),
+	}
+
+	// Collected resources are intentionally not cached
+	for i := range healthCheckReport.Spec.ResourceStatuses {
+		rs := &healthCheckReport.Spec.ResourceStatuses[i]
+		info.Resources[i] = ResourceHealth{
+			HealthCheckName: healthCheckReport.Spec.HealthCheckName,
+			Resource:        rs.ObjectRef,
+			HealthStatus:    rs.HealthStatus,
+			Message:         rs.Message,
+		}
+	}
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	m.healthCheckReports[*getKeyFromObject(m.scheme, healthCheckReport)] = info
+
+	now := metav1.Now()
+	m.recordHealthEvaluation(&info.Cluster, &now)
+}
+
+func (m *instance) RemoveHealthCheckReport(healthCheckReportNamespace, healthCheckReportName string) {
+	healthCheckReport := &corev1.ObjectReference{
+		Namespace:  healthCheckReportNamespace,
+		Name:       healthCheckReportName,
+		Kind:       libsveltosv1beta1.HealthCheckReportKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	info, ok := m.healthCheckReports[*healthCheckReport]
+	if !ok {
+		return
+	}
+
+	delete(m.healthCheckReports, *healthCheckReport)
+
+	now := metav1.Now()
+	m.recordHealthEvaluation(&info.Cluster, &now)
+}
+
+// removeHealthHistory removes health evaluations recorded for a cluster
+func (m *instance) removeHealthHistory(clusterRef *corev1.ObjectReference) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	delete(m.healthHistory, *clusterRef)
+}
+
+// getClusterHealthChecks returns all ClusterHealthChecks whose name contains nameFilter, sorted by name.
+// Only clusters present in the clusters map are reported.
+func (m *instance) getClusterHealthChecks(nameFilter string, clusters map[corev1.ObjectReference]ClusterInfo,
+) []ClusterHealthCheckResult {
+
+	m.healthMux.RLock()
+	defer m.healthMux.RUnlock()
+
+	result := make([]ClusterHealthCheckResult, 0, len(m.clusterHealthChecks))
+	for name := range m.clusterHealthChecks {
+		if nameFilter != "" && !strings.Contains(name, nameFilter) {
+			continue
+		}
+
+		info := m.clusterHealthChecks[name]
+
+		matchingClusters := make([]corev1.ObjectReference, 0, len(info.MatchingClusters))
+		for i := range info.MatchingClusters {
+			if _, ok := clusters[info.MatchingClusters[i]]; ok {
+				matchingClusters = append(matchingClusters, info.MatchingClusters[i])
+			}
+		}
+		sortClusterRefs(matchingClusters)
+		info.MatchingClusters = matchingClusters
+
+		healthChecks := map[string]HealthCheckInfo{}
+		for i := range info.LivenessChecks {
+			lc := &info.LivenessChecks[i]
+			if lc.Type != libsveltosv1beta1.LivenessTypeHealthCheck || lc.LivenessSourceRef == nil {
+				continue
+			}
+			if healthCheck, ok := m.healthChecks[lc.LivenessSourceRef.Name]; ok {
+				healthChecks[lc.LivenessSourceRef.Name] = healthCheck
+			}
+		}
+
+		result = append(result, ClusterHealthCheckResult{
+			Name:                   name,
+			ClusterHealthCheckInfo: info,
+			HealthChecks:           healthChecks,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getClusterHealth returns the current health of a cluster along with the last health evaluations
+func (m *instance) getClusterHealth(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *ClusterHealthResult {
+
+	clusterRef := getClusterRef(clusterNamespace, clusterName, clusterType)
+
+	m.healthMux.RLock()
+	defer m.healthMux.RUnlock()
+
+	livenessChecks, resources := m.getClusterHealthStatuses(clusterRef)
+	summary, _ := evaluateClusterHealth(livenessChecks, resources)
+
+	history := m.healthHistory[*clusterRef]
+	result := &ClusterHealthResult{
+		Health:         summary,
+		LivenessChecks: livenessChecks,
+		Resources:      resources,
+		History:        make([]HealthEvaluation, len(history)),
+	}
+	for i := range history {
+		result.History[len(history)-1-i] = history[i]
+	}
+
+	return result
+}
+
+// getFleetHealth returns a health summary of all clusters in the clusters map
+func (m *instance) getFleetHealth(clusters map[corev1.ObjectReference]ClusterInfo) *FleetHealthResult {
+	m.healthMux.RLock()
+	defer m.healthMux.RUnlock()
+
+	result := &FleetHealthResult{
+		TotalClusters:     len(clusters),
+		UnhealthyClusters: make([]UnhealthyCluster, 0),
+	}
+
+	for k := range clusters {
+		summary, _ := evaluateClusterHealth(m.getClusterHealthStatuses(&k))
+		if summary == nil {
+			result.NotEvaluatedClusters++
+			continue
+		}
+
+		switch summary.Status {
+		case libsveltosv1beta1.HealthStatusHealthy:
+			result.HealthyClusters++
+			continue
+		case libsveltosv1beta1.HealthStatusProgressing:
+			result.ProgressingClusters++
+		case libsveltosv1beta1.HealthStatusSuspended:
+			result.SuspendedClusters++
+		default:
+			result.DegradedClusters++
+		}
+
+		result.UnhealthyClusters = append(result.UnhealthyClusters, UnhealthyCluster{
+			Cluster:              k,
+			ClusterHealthSummary: *summary,
+		})
+	}
+
+	sort.Slice(result.UnhealthyClusters, func(i, j int) bool {
+		return getClusterRefKey(&result.UnhealthyClusters[i].Cluster) <
+			getClusterRefKey(&result.UnhealthyClusters[j].Cluster)
+	})
+	result.TotalUnhealthyClusters = len(result.UnhealthyClusters)
+
+	return result
+}
+
+// setClusterHealth sets the health summary on each cluster
+func (m *instance) setClusterHealth(clusters ManagedClusters, clusterType libsveltosv1beta1.ClusterType) {
+	m.healthMux.RLock()
+	defer m.healthMux.RUnlock()
+
+	for i := range clusters {
+		clusterRef := getClusterRef(clusters[i].Namespace, clusters[i].Name, clusterType)
+		clusters[i].Health, _ = evaluateClusterHealth(m.getClusterHealthStatuses(clusterRef))
+	}
+}
+
+// getClusterHealthStatuses returns liveness checks and resource health for a cluster.
+// Caller must hold healthMux.
+func (m *instance) getClusterHealthStatuses(clusterRef *corev1.ObjectReference,
+) ([]LivenessCheckStatus, []ResourceHealth) {
+
+	livenessChecks := make([]LivenessCheckStatus, 0)
+	for name := range m.clusterHealthChecks {
+		conditions := m.clusterHealthChecks[name].clusterConditions[*clusterRef]
+		for i := range conditions {
+			livenessChecks = append(livenessChecks, LivenessCheckStatus{
+				ClusterHealthCheckName: name,
+				Name:                   conditions[i].Name,
+				Type:                   conditions[i].Type,
+				Status:                 conditions[i].Status,
+				Severity:               conditions[i].Severity,
+				Message:                conditions[i].Message,
+				LastTransitionTime:     conditions[i].LastTransitionTime,
+			})
+		}
+	}
+
+	resources := make([]ResourceHealth, 0)
+	for k := range m.healthCheckReports {
+		report := m.healthCheckReports[k]
+		if isSameCluster(&report.Cluster, clusterRef) {
+			resources = append(resources, report.Resources...)
+		}
+	}
+
+	sort.Slice(livenessChecks, func(i, j int) bool {
+		if livenessChecks[i].ClusterHealthCheckName != livenessChecks[j].ClusterHealthCheckName {
+			return livenessChecks[i].ClusterHealthCheckName < livenessChecks[j].ClusterHealthCheckName
+		}
+		return livenessChecks[i].Name < livenessChecks[j].Name
+	})
+
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].HealthCheckName != resources[j].HealthCheckName {
+			return resources[i].HealthCheckName < resources[j].HealthCheckName
+		}
+		return getResourceHealthKey(&resources[i]) < getResourceHealthKey(&resources[j])
+	})
+
+	return livenessChecks, resources
+}
+
+// recordHealthEvaluation evaluates the health of a cluster and adds it to the cluster history
+// if it differs from the last recorded evaluation. Caller must hold healthMux.
+func (m *instance) recordHealthEvaluation(clusterRef *corev1.ObjectReference, now *metav1.Time) {
+	summary, messages := evaluateClusterHealth(m.getClusterHealthStatuses(clusterRef))
+	if summary == nil {
+		return
+	}
+
+	m.healthHistory[*clusterRef] = appendHealthEvaluation(m.healthHistory[*clusterRef],
+		HealthEvaluation{Time: *now, ClusterHealthSummary: *summary, Messages: messages})
+}
+
+// evaluateClusterHealth returns the health summary and a message for each failing check.
+// Returns nil if there is nothing to evaluate.
+func evaluateClusterHealth(livenessChecks []LivenessCheckStatus, resources []ResourceHealth,
+) (*ClusterHealthSummary, []string) {
+
+	if len(livenessChecks) == 0 && len(resources) == 0 {
+		return nil, nil
+	}
+
+	summary := &ClusterHealthSummary{Status: libsveltosv1beta1.HealthStatusHealthy}
+	messages := make([]string, 0)
+
+	for i := range livenessChecks {
+		status := getLivenessCheckHealthStatus(&livenessChecks[i])
+		if status == libsveltosv1beta1.HealthStatusHealthy {
+			continue
+		}
+		summary.FailingChecks++
+		summary.Status = getWorstHealthStatus(summary.Status, status)
+		messages = append(messages, fmt.Sprintf("%s/%s: %s",
+			livenessChecks[i].ClusterHealthCheckName, livenessChecks[i].Name, livenessChecks[i].Message))
+	}
+
+	for i := range resources {
+		if resources[i].HealthStatus == libsveltosv1beta1.HealthStatusHealthy {
+			continue
+		}
+		summary.FailingChecks++
+		summary.Status = getWorstHealthStatus(summary.Status, resources[i].HealthStatus)
+		messages = append(messages, fmt.Sprintf("%s: %s %s is %s %s", resources[i].HealthCheckName,
+			resources[i].Resource.Kind, getResourceHealthKey(&resources[i]), resources[i].HealthStatus,
+			resources[i].Message))
+	}
+
+	return summary, messages
+}
+
+// getLivenessCheckHealthStatus maps a liveness check condition to a health status.
+// A failing condition is considered Degraded unless its severity is Warning or Info.
+func getLivenessCheckHealthStatus(livenessCheck *LivenessCheckStatus) libsveltosv1beta1.HealthStatus {
+	switch livenessCheck.Status {
+	case corev1.ConditionTrue:
+		return libsveltosv1beta1.HealthStatusHealthy
+	case corev1.ConditionFalse:
+		if livenessCheck.Severity == libsveltosv1beta1.ConditionSeverityWarning ||
+			livenessCheck.Severity == libsveltosv1beta1.ConditionSeverityInfo {
+
+			return libsveltosv1beta1.HealthStatusProgressing
+		}
+		return libsveltosv1beta1.HealthStatusDegraded
+	default:
+		return libsveltosv1beta1.HealthStatusProgressing
+	}
+}
+
+func getWorstHealthStatus(status1, status2 libsveltosv1beta1.HealthStatus) libsveltosv1beta1.HealthStatus {
+	if getHealthStatusSeverity(status2) > getHealthStatusSeverity(status1) {
+		return status2
+	}
+	return status1
+}
+
+func getHealthStatusSeverity(status libsveltosv1beta1.HealthStatus) int {
+	switch status {
+	case libsveltosv1beta1.HealthStatusHealthy:
+		return 0
+	case libsveltosv1beta1.HealthStatusSuspended:
+		return 1
+	case libsveltosv1beta1.HealthStatusProgressing:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// appendHealthEvaluation adds evaluation to history unless it matches the last recorded one.
+// At most maxHealthEvaluations are kept.
+func appendHealthEvaluation(history []HealthEvaluation, evaluation HealthEvaluation) []HealthEvaluation {
+	if len(history) > 0 {
+		last := &history[len(history)-1]
+		if last.ClusterHealthSummary == evaluation.ClusterHealthSummary &&
+			reflect.DeepEqual(last.Messages, evaluation.Messages) {
+
+			return history
+		}
+	}
+
+	history = append(history, evaluation)
+	if len(history) > maxHealthEvaluations {
+		history = history[len(history)-maxHealthEvaluations:]
+	}
+	return history
+}
+
+// getClustersWithConditions returns all clusters with at least one liveness check outcome
+func getClustersWithConditions(infos ...ClusterHealthCheckInfo) []corev1.ObjectReference {
+	clusters := map[corev1.ObjectReference]bool{}
+	for i := range infos {
+		for k := range infos[i].clusterConditions {
+			clusters[k] = true
+		}
+	}
+
+	result := make([]corev1.ObjectReference, 0, len(clusters))
+	for k := range clusters {
+		result = append(result, k)
+	}
+	return result
+}
+
+func getResourceHealthKey(resourceHealth *ResourceHealth) string {
+	if resourceHealth.Resource.Namespace == "" {
+		return resourceHealth.Resource.Name
+	}
+	return resourceHealth.Resource.Namespace + "/" + resourceHealth.Resource.Name
+}
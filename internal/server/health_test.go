@@ -0,0 +1,98 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Health", func() {
+	It("evaluateClusterHealth returns nil when nothing is evaluated", func() {
+		summary, messages := server.EvaluateClusterHealth(nil, nil)
+		Expect(summary).To(BeNil())
+		Expect(messages).To(BeNil())
+	})
+
+	It("evaluateClusterHealth reports the worst health status", func() {
+		livenessChecks := []server.LivenessCheckStatus{
+			{ClusterHealthCheckName: randomString(), Name: randomString(), Status: corev1.ConditionTrue},
+			{
+				ClusterHealthCheckName: randomString(), Name: randomString(), Status: corev1.ConditionFalse,
+				Severity: libsveltosv1beta1.ConditionSeverityWarning, Message: randomString(),
+			},
+		}
+
+		summary, messages := server.EvaluateClusterHealth(livenessChecks, nil)
+		Expect(summary).ToNot(BeNil())
+		Expect(summary.Status).To(Equal(libsveltosv1beta1.HealthStatusProgressing))
+		Expect(summary.FailingChecks).To(Equal(1))
+		Expect(len(messages)).To(Equal(1))
+
+		resources := []server.ResourceHealth{
+			{
+				HealthCheckName: randomString(),
+				Resource:        corev1.ObjectReference{Kind: "Deployment", Namespace: randomString(), Name: randomString()},
+				HealthStatus:    libsveltosv1beta1.HealthStatusDegraded,
+			},
+			{
+				HealthCheckName: randomString(),
+				Resource:        corev1.ObjectReference{Kind: "Deployment", Namespace: randomString(), Name: randomString()},
+				HealthStatus:    libsveltosv1beta1.HealthStatusHealthy,
+			},
+		}
+
+		summary, messages = server.EvaluateClusterHealth(livenessChecks, resources)
+		Expect(summary).ToNot(BeNil())
+		Expect(summary.Status).To(Equal(libsveltosv1beta1.HealthStatusDegraded))
+		Expect(summary.FailingChecks).To(Equal(2))
+		Expect(len(messages)).To(Equal(2))
+	})
+
+	It("appendHealthEvaluation skips unchanged evaluations and keeps a bounded history", func() {
+		evaluation := server.HealthEvaluation{
+			Time:                 metav1.Now(),
+			ClusterHealthSummary: server.ClusterHealthSummary{Status: libsveltosv1beta1.HealthStatusHealthy},
+			Messages:             []string{},
+		}
+
+		history := server.AppendHealthEvaluation(nil, evaluation)
+		Expect(len(history)).To(Equal(1))
+
+		// Same outcome is not recorded twice
+		evaluation.Time = metav1.NewTime(time.Now().Add(time.Minute))
+		history = server.AppendHealthEvaluation(history, evaluation)
+		Expect(len(history)).To(Equal(1))
+
+		for i := 0; i < 30; i++ {
+			evaluation.FailingChecks = i + 1
+			evaluation.Status = libsveltosv1beta1.HealthStatusDegraded
+			history = server.AppendHealthEvaluation(history, evaluation)
+		}
+
+		Expect(len(history)).To(Equal(20))
+		Expect(history[len(history)-1].FailingChecks).To(Equal(30))
+	})
+})
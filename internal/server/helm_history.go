@@ -0,0 +1,357 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationapi "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+const (
+	// helmSecretType is the type of the Secrets used by Helm to store releases
+	helmSecretType = "helm.sh/release.v1"
+	// helmReleaseKey is the key in the Secret data containing the encoded release
+	helmReleaseKey = "release"
+
+	maskedValue = "*****"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+	// sensitiveKeys contains substrings that, when found (case insensitive) in a
+	// values key, cause the corresponding value to be masked.
+	sensitiveKeys = []string{"password", "passwd", "secret", "token", "credential",
+		"apikey", "api_key", "privatekey", "private_key", "accesskey", "access_key"}
+)
+
+type HelmReleaseRevision struct {
+	// Revision is the release revision number
+	Revision int `json:"revision"`
+
+	// Status is the status of this revision (deployed, failed, superseded, ...)
+	Status string `json:"status"`
+
+	// ChartName is the name of the helm chart
+	ChartName string `json:"chartName"`
+
+	// ChartVersion is the version of the helm chart
+	ChartVersion string `json:"chartVersion"`
+
+	// AppVersion is the version of the application contained in the chart
+	AppVersion string `json:"appVersion"`
+
+	// Description is the human-friendly log entry about this revision
+	Description string `json:"description"`
+
+	// Notes are the rendered NOTES.txt of the chart. Only returned along with values.
+	Notes string `json:"notes,omitempty"`
+
+	// LastDeployed is when this revision was deployed
+	LastDeployed metav1.Time `json:"lastDeployed"`
+
+	// Values are the values used to render this revision (chart defaults
+	// merged with user supplied values). Sensitive values are masked.
+	// When ValuesMasked is true, only user supplied values are returned and all of
+	// them are masked.
+	Values map[string]interface{} `json:"values"`
+
+	// ValuesMasked is true when values were not requested
+	ValuesMasked bool `json:"valuesMasked"`
+}
+
+type HelmReleaseHistory struct {
+	// ReleaseName name of the release deployed in the Cluster.
+	ReleaseName string `json:"releaseName"`
+
+	// Namespace where chart is deployed in the Cluster.
+	Namespace string `json:"namespace"`
+
+	// Revisions contains all stored revisions, most recent first
+	Revisions []HelmReleaseRevision `json:"revisions"`
+}
+
+// getHelmReleaseHistory connects to the managed cluster and reads the Secrets Helm uses
+// to store the release releaseNamespace/releaseName.
+// Values and notes can contain credentials, so they are returned only if showValues is true and
+// the user is allowed to get Secrets in the release namespace of the managed cluster (where Helm
+// stores them). Otherwise only the user supplied values keys are returned.
+func (m *instance) getHelmReleaseHistory(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, releaseNamespace, releaseName string,
+	userInfo *authenticationv1.UserInfo, showValues bool) (*HelmReleaseHistory, error) {
+
+	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, m.client, clusterNamespace, clusterName,
+		"", "", clusterType, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if showValues {
+		canGetSecrets, err := canGetSecretsInManagedCluster(ctx, remoteClient, releaseNamespace, userInfo)
+		if err != nil {
+			return nil, err
+		}
+		if !canGetSecrets {
+			return nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "",
+				fmt.Errorf("values require permission to get Secrets in namespace %s of the managed cluster",
+					releaseNamespace))
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	listOptions := []client.ListOption{
+		client.InNamespace(releaseNamespace),
+		client.MatchingLabels{
+			"owner": "helm",
+			"name":  releaseName,
+		},
+	}
+
+	err = remoteClient.List(ctx, secrets, listOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HelmReleaseHistory{
+		ReleaseName: releaseName,
+		Namespace:   releaseNamespace,
+		Revisions:   make([]HelmReleaseRevision, 0),
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Type != helmSecretType {
+			continue
+		}
+
+		release, err := decodeHelmRelease(secret.Data[helmReleaseKey])
+		if err != nil {
+			return nil, err
+		}
+
+		revision, err := getHelmReleaseRevision(release, showValues)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Revisions = append(result.Revisions, *revision)
+	}
+
+	sort.Slice(result.Revisions, func(i, j int) bool {
+		return result.Revisions[i].Revision > result.Revisions[j].Revision
+	})
+
+	return result, nil
+}
+
+// decodeHelmRelease decodes a release as stored by Helm: base64 encoded, optionally
+// gzipped, JSON representation of the release.
+func decodeHelmRelease(data []byte) (*helmrelease.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Releases stored before compression was introduced are not gzipped
+	if len(b) > len(gzipMagic) && bytes.Equal(b[0:len(gzipMagic)], gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		b, err = io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	release := &helmrelease.Release{}
+	if err := json.Unmarshal(b, release); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// canGetSecretsInManagedCluster returns true if the user is allowed to get Secrets in namespace of
+// the managed cluster remoteClient is connected to
+func canGetSecretsInManagedCluster(ctx context.Context, remoteClient client.Client, namespace string,
+	userInfo *authenticationv1.UserInfo) (bool, error) {
+
+	sar := &authorizationapi.SubjectAccessReview{
+		Spec: authorizationapi.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationapi.ResourceAttributes{
+				Verb:      "get",
+				Version:   "v1",
+				Resource:  "secrets",
+				Namespace: namespace,
+			},
+			User:   userInfo.Username,
+			Groups: userInfo.Groups,
+			UID:    userInfo.UID,
+			Extra:  getSubjectAccessReviewExtra(userInfo),
+		},
+	}
+
+	if err := remoteClient.Create(ctx, sar); err != nil {
+		return false, err
+	}
+
+	return sar.Status.Allowed, nil
+}
+
+// getHelmReleaseRevision returns the revision of a release. Unless showValues is true, notes are
+// not returned and only the user supplied values are, with every value masked.
+func getHelmReleaseRevision(release *helmrelease.Release, showValues bool) (*HelmReleaseRevision, error) {
+	revision := &HelmReleaseRevision{
+		Revision:     release.Version,
+		ValuesMasked: !showValues,
+	}
+
+	if release.Info != nil {
+		revision.Status = release.Info.Status.String()
+		revision.Description = release.Info.Description
+		if showValues {
+			revision.Notes = release.Info.Notes
+		}
+		revision.LastDeployed = metav1.NewTime(release.Info.LastDeployed.Time)
+	}
+
+	if release.Chart != nil && release.Chart.Metadata != nil {
+		revision.ChartName = release.Chart.Metadata.Name
+		revision.ChartVersion = release.Chart.Metadata.Version
+		revision.AppVersion = release.Chart.Metadata.AppVersion
+	}
+
+	if !showValues {
+		revision.Values = maskAllValues(release.Config)
+		return revision, nil
+	}
+
+	values := release.Config
+	if release.Chart != nil {
+		coalesced, err := chartutil.CoalesceValues(release.Chart, release.Config)
+		if err != nil {
+			return nil, err
+		}
+		values = coalesced
+	}
+
+	revision.Values = maskSensitiveValues(values)
+
+	return revision, nil
+}
+
+// maskAllValues returns a copy of values where every value, other than maps and lists, is
+// replaced with a mask
+func maskAllValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		result[k] = maskValue(v)
+	}
+
+	return result
+}
+
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return maskAllValues(v)
+	case chartutil.Values:
+		return maskAllValues(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = maskValue(v[i])
+		}
+		return result
+	default:
+		return maskedValue
+	}
+}
+
+// maskSensitiveValues returns a copy of values where any value whose key looks
+// sensitive (password, token, secret, ...) is replaced with a mask.
+func maskSensitiveValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if isSensitiveKey(k) {
+			result[k] = maskedValue
+			continue
+		}
+		result[k] = maskSensitiveValue(v)
+	}
+
+	return result
+}
+
+func maskSensitiveValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return maskSensitiveValues(v)
+	case chartutil.Values:
+		return maskSensitiveValues(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = maskSensitiveValue(v[i])
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	for i := range sensitiveKeys {
+		if strings.Contains(lowerKey, sensitiveKeys[i]) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+func encodeHelmRelease(release *helmrelease.Release) []byte {
+	b, err := json.Marshal(release)
+	Expect(err).To(BeNil())
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	Expect(err).To(BeNil())
+	_, err = w.Write(b)
+	Expect(err).To(BeNil())
+	Expect(w.Close()).To(Succeed())
+
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+var _ = Describe("Helm release history", func() {
+	It("decodeHelmRelease decodes a release stored by Helm", func() {
+		release := &helmrelease.Release{
+			Name:      randomString(),
+			Namespace: randomString(),
+			Version:   3,
+			Info: &helmrelease.Info{
+				Status: helmrelease.StatusFailed,
+				Notes:  randomString(),
+			},
+		}
+
+		decoded, err := server.DecodeHelmRelease(encodeHelmRelease(release))
+		Expect(err).To(BeNil())
+		Expect(decoded.Name).To(Equal(release.Name))
+		Expect(decoded.Namespace).To(Equal(release.Namespace))
+		Expect(decoded.Version).To(Equal(release.Version))
+		Expect(decoded.Info.Status).To(Equal(helmrelease.StatusFailed))
+		Expect(decoded.Info.Notes).To(Equal(release.Info.Notes))
+	})
+
+	It("getHelmReleaseRevision merges chart values and masks sensitive ones", func() {
+		release := &helmrelease.Release{
+			Version: 2,
+			Info: &helmrelease.Info{
+				Status: helmrelease.StatusDeployed,
+			},
+			Chart: &chart.Chart{
+				Metadata: &chart.Metadata{
+					Name:       "kyverno",
+					Version:    "3.2.6",
+					AppVersion: "v1.12.5",
+				},
+				Values: map[string]interface{}{
+					"replicas": 1,
+					"database": map[string]interface{}{
+						"host":     "localhost",
+						"password": "default",
+					},
+				},
+			},
+			Config: map[string]interface{}{
+				"replicas": 3,
+			},
+		}
+
+		revision, err := server.GetHelmReleaseRevision(release, true)
+		Expect(err).To(BeNil())
+		Expect(revision.Revision).To(Equal(2))
+		Expect(revision.Status).To(Equal(string(helmrelease.StatusDeployed)))
+		Expect(revision.ChartName).To(Equal("kyverno"))
+		Expect(revision.ChartVersion).To(Equal("3.2.6"))
+		Expect(revision.AppVersion).To(Equal("v1.12.5"))
+		Expect(revision.Values["replicas"]).To(Equal(3))
+
+		database, ok := revision.Values["database"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(database["host"]).To(Equal("localhost"))
+		Expect(database["password"]).To(Equal("*****"))
+		Expect(revision.ValuesMasked).To(BeFalse())
+	})
+
+	It("getHelmReleaseRevision returns only masked user supplied values unless values are requested", func() {
+		release := &helmrelease.Release{
+			Version: 1,
+			Info: &helmrelease.Info{
+				Status: helmrelease.StatusDeployed,
+				Notes:  "admin password: " + randomString(),
+			},
+			Chart: &chart.Chart{
+				Metadata: &chart.Metadata{Name: "kyverno", Version: "3.2.6"},
+				Values:   map[string]interface{}{"replicas": 1},
+			},
+			Config: map[string]interface{}{
+				"database": map[string]interface{}{
+					"dsn": "postgres://admin:" + randomString() + "@db",
+				},
+				"hosts": []interface{}{randomString()},
+			},
+		}
+
+		revision, err := server.GetHelmReleaseRevision(release, false)
+		Expect(err).To(BeNil())
+		Expect(revision.ValuesMasked).To(BeTrue())
+		Expect(revision.Notes).To(BeEmpty())
+		Expect(revision.ChartName).To(Equal("kyverno"))
+		// chart defaults are not returned
+		Expect(revision.Values).ToNot(HaveKey("replicas"))
+
+		database, ok := revision.Values["database"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(database["dsn"]).To(Equal("*****"))
+		Expect(revision.Values["hosts"]).To(Equal([]interface{}{"*****"}))
+
+		revision, err = server.GetHelmReleaseRevision(release, true)
+		Expect(err).To(BeNil())
+		Expect(revision.Notes).To(Equal(release.Info.Notes))
+		Expect(revision.Values["replicas"]).To(Equal(1))
+	})
+
+	It("canGetSecretsInManagedCluster verifies user can get Secrets in the release namespace", func() {
+		namespace := randomString()
+		userInfo := &authenticationv1.UserInfo{Username: randomString(), Groups: []string{randomString()}}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				sar, ok := obj.(*authorizationv1.SubjectAccessReview)
+				Expect(ok).To(BeTrue())
+				sar.Status.Allowed = sar.Spec.ResourceAttributes.Namespace == namespace &&
+					sar.Spec.ResourceAttributes.Resource == "secrets" &&
+					sar.Spec.User == userInfo.Username && reflect.DeepEqual(sar.Spec.Groups, userInfo.Groups)
+				return nil
+			},
+		}).Build()
+
+		allowed, err := server.CanGetSecretsInManagedCluster(context.TODO(), c, namespace, userInfo)
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeTrue())
+
+		allowed, err = server.CanGetSecretsInManagedCluster(context.TODO(), c, randomString(), userInfo)
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+	})
+
+	It("maskSensitiveValues masks values in nested lists", func() {
+		values := map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{
+					"name":     "admin",
+					"apiToken": randomString(),
+				},
+			},
+			"clientSecret": randomString(),
+		}
+
+		masked := server.MaskSensitiveValues(values)
+		Expect(masked["clientSecret"]).To(Equal("*****"))
+
+		users, ok := masked["users"].([]interface{})
+		Expect(ok).To(BeTrue())
+		user, ok := users[0].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(user["name"]).To(Equal("admin"))
+		Expect(user["apiToken"]).To(Equal("*****"))
+	})
+})
@@ -20,20 +20,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
 )
@@ -95,6 +101,9 @@ var (
 			return
 		}
 
+		manager.setClusterHealth(result, libsveltosv1beta1.ClusterTypeCapi)
+		manager.setClusterClassifiers(result, libsveltosv1beta1.ClusterTypeCapi)
+
 		response := ClusterResult{
 			TotalClusters:   len(managedClusterData),
 			ManagedClusters: result,
@@ -150,6 +159,9 @@ var (
 			return
 		}
 
+		manager.setClusterHealth(result, libsveltosv1beta1.ClusterTypeSveltos)
+		manager.setClusterClassifiers(result, libsveltosv1beta1.ClusterTypeSveltos)
+
 		response := ClusterResult{
 			TotalClusters:   len(managedClusterData),
 			ManagedClusters: result,
@@ -225,49 +237,1812 @@ var (
 
 		user, err := validateToken(c)
 		if err != nil {
-			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		resources, err := manager.getResourcesForCluster(c.Request.Context(),
+			namespace, name, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		sort.Slice(resources, func(i, j int) bool {
+			return sortResources(resources, i, j)
+		})
+
+		result, err := getResourcesInRange(resources, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ResourceResult{
+			TotalResources: len(resources),
+			Resources:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getHelmReleaseHistory = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get helm release history")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		releaseNamespace, releaseName, err := getHelmReleaseFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		showValues := getShowValuesFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("release %s/%s showValues %t", releaseNamespace, releaseName,
+			showValues))
+
+		userInfo, err := validateTokenAndGetUserInfo(c)
+		if err != nil {
+			return
+		}
+		user := userInfo.Username
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		history, err := manager.getHelmReleaseHistory(c.Request.Context(), namespace, name, clusterType,
+			releaseNamespace, releaseName, userInfo, showValues)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get helm release history %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, history)
+	}
+
+	compareClusters = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("compare add-ons deployed in two clusters")
+
+		cluster1, err := getComparedClusterFromQuery(c, "1")
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		cluster2, err := getComparedClusterFromQuery(c, "2")
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster1 %s:%s/%s cluster2 %s:%s/%s",
+			cluster1.ClusterType, cluster1.Namespace, cluster1.Name,
+			cluster2.ClusterType, cluster2.Namespace, cluster2.Name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		for _, cluster := range []*ComparedCluster{cluster1, cluster2} {
+			canGetCluster, err := manager.canGetCluster(cluster.Namespace, cluster.Name, user, cluster.ClusterType)
+			if err != nil {
+				ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+				_ = c.AbortWithError(http.StatusUnauthorized, err)
+				return
+			}
+
+			if !canGetCluster {
+				_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+				return
+			}
+		}
+
+		diff, err := manager.compareClusters(c.Request.Context(), cluster1, cluster2)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, diff)
+	}
+
+	getClusterCompliance = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get add-on compliance for a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		compliance, err := manager.getClusterCompliance(c.Request.Context(), namespace, name, clusterType,
+			map[corev1.ObjectReference]*configv1beta1.Spec{})
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, compliance)
+	}
+
+	getFleetCompliance = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get add-on compliance for all clusters")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d", limit, skip))
+		filters, err := getClusterFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		fleet, err := manager.getFleetCompliance(c.Request.Context(), getFilteredClusterRefs(clusters, filters))
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		fleet.Clusters, err = getSliceInRange(fleet.Clusters, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, fleet)
+	}
+
+	getClusterDrifts = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get configuration drifts for a cluster")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		// When drift-detection-manager runs in the managed cluster, ResourceSummaries are only
+		// available there. Failing to read those is not an error: cached drifts are returned.
+		err = manager.refreshResourceSummariesFromCluster(c.Request.Context(), namespace, name, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("failed to read ResourceSummaries from cluster: %v", err))
+		}
+
+		drifts := manager.getClusterDrifts(namespace, name, clusterType)
+		result, err := getSliceInRange(drifts, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ClusterDriftResult{
+			TotalResources: len(drifts),
+			Resources:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getMostDriftedResources = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get most drifted resources across all clusters")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d", limit, skip))
+		filters, err := getClusterFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		drifts := manager.getMostDriftedResources(getFilteredClusterRefs(clusters, filters))
+		result, err := getSliceInRange(drifts, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := FleetDriftResult{
+			TotalResources: len(drifts),
+			Resources:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClusterDryRunReports = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get DryRun reports for a cluster")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		reports := manager.getDryRunReportsForCluster(namespace, name, clusterType)
+		result, err := getSliceInRange(reports, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := DryRunResult{
+			TotalReports: len(reports),
+			Reports:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getProfileDryRunReports = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get DryRun reports for a ClusterProfile/Profile")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetProfile, err := manager.canGetProfileRef(profileRef, user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this profile"))
+			return
+		}
+
+		// Only reports for clusters the user has access to are returned
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		reports := manager.getDryRunReportsForProfile(profileRef, clusters)
+		result, err := getSliceInRange(reports, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := DryRunResult{
+			TotalReports: len(reports),
+			Reports:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getEventTriggers = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get EventTriggers")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		nameFilter := c.Query("name")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d name %q", limit, skip, nameFilter))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListEventTriggers, err := manager.canListEventTriggers(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListEventTriggers {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list EventTriggers"))
+			return
+		}
+
+		eventTriggers := manager.getEventTriggers(nameFilter)
+		result, err := getSliceInRange(eventTriggers, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := EventTriggersResult{
+			TotalEventTriggers: len(eventTriggers),
+			EventTriggers:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClusterEvents = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get events for a cluster")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		events, err := manager.getClusterEvents(c.Request.Context(), namespace, name, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Only EventTriggers the user has access to are returned
+		err = manager.removeInaccessibleEventTriggers(events, user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		result, err := getSliceInRange(events, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ClusterEventsResult{
+			TotalEvents: len(events),
+			Events:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClassifiers = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get Classifiers")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		nameFilter := c.Query("name")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d name %q", limit, skip, nameFilter))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListClassifiers, err := manager.canListClassifiers(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListClassifiers {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list Classifiers"))
+			return
+		}
+
+		// Only clusters the user has access to are reported
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		classifiers := manager.getClassifiers(nameFilter, clusters)
+		result, err := getSliceInRange(classifiers, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ClassifiersResult{
+			TotalClassifiers: len(classifiers),
+			Classifiers:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClassifierConflicts = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get Classifier label conflicts")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d", limit, skip))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListClassifiers, err := manager.canListClassifiers(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListClassifiers {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list Classifiers"))
+			return
+		}
+
+		// Only conflicts on clusters the user has access to are returned
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		conflicts := manager.getClassifierConflicts(clusters)
+		result, err := getSliceInRange(conflicts, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ClassifierConflictsResult{
+			TotalConflicts: len(conflicts),
+			Conflicts:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClusterClassifiers = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get Classifiers managing labels of a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		classifiers := manager.getClusterClassifiers(namespace, name, clusterType)
+
+		// Return JSON response
+		c.JSON(http.StatusOK, classifiers)
+	}
+
+	getClusterHealthChecks = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get ClusterHealthChecks")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		nameFilter := c.Query("name")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d name %q", limit, skip, nameFilter))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListClusterHealthChecks, err := manager.canListClusterHealthChecks(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListClusterHealthChecks {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list ClusterHealthChecks"))
+			return
+		}
+
+		// Only clusters the user has access to are reported
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		clusterHealthChecks := manager.getClusterHealthChecks(nameFilter, clusters)
+		result, err := getSliceInRange(clusterHealthChecks, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ClusterHealthChecksResult{
+			TotalClusterHealthChecks: len(clusterHealthChecks),
+			ClusterHealthChecks:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClusterHealth = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get health of a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		response := manager.getClusterHealth(namespace, name, clusterType)
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getFleetHealth = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get health summary of all clusters")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d", limit, skip))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		// Only clusters the user has access to are considered
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		response := manager.getFleetHealth(clusters)
+		response.UnhealthyClusters, err = getSliceInRange(response.UnhealthyClusters, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getRoleRequests = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get RoleRequests")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		nameFilter := c.Query("name")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d name %q", limit, skip, nameFilter))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListRoleRequests, err := manager.canListRoleRequests(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListRoleRequests {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list RoleRequests"))
+			return
+		}
+
+		// Only clusters the user has access to are reported
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		roleRequests := manager.getRoleRequests(nameFilter, clusters)
+		result, err := getSliceInRange(roleRequests, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Roles/ClusterRoles are fetched only for the returned RoleRequests
+		manager.setRoleRefContents(c.Request.Context(), result, user)
+
+		response := RoleRequestsResult{
+			TotalRoleRequests: len(roleRequests),
+			RoleRequests:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getClusterTenants = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get tenants with access to a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		canListRoleRequests, err := manager.canListRoleRequests(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canListRoleRequests {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list RoleRequests"))
+			return
+		}
+
+		tenants := manager.getClusterTenants(namespace, name, clusterType)
+
+		// Return JSON response
+		c.JSON(http.StatusOK, tenants)
+	}
+
+	getSets = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get ClusterSets/Sets")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		kind := c.Query("kind")
+		namespaceFilter := c.Query("namespace")
+		nameFilter := c.Query("name")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d kind %q namespace %q name %q",
+			limit, skip, kind, namespaceFilter, nameFilter))
+
+		if kind != "" && kind != libsveltosv1beta1.ClusterSetKind && kind != libsveltosv1beta1.SetKind {
+			msg := fmt.Sprintf("supported kinds are %q and %q", libsveltosv1beta1.ClusterSetKind, libsveltosv1beta1.SetKind)
+			ginLogger.V(logs.LogInfo).Info(msg)
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New(msg))
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		// Only kinds the user can list are returned
+		kinds := map[string]bool{}
+		canListClusterSets, err := manager.canListClusterSets(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+		kinds[libsveltosv1beta1.ClusterSetKind] = canListClusterSets && kind != libsveltosv1beta1.SetKind
+
+		canListSets, err := manager.canListSets(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+		kinds[libsveltosv1beta1.SetKind] = canListSets && kind != libsveltosv1beta1.ClusterSetKind
+
+		if !kinds[libsveltosv1beta1.ClusterSetKind] && !kinds[libsveltosv1beta1.SetKind] {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to list ClusterSets/Sets"))
+			return
+		}
+
+		// Only clusters the user has access to are reported
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		sets := manager.getSets(kinds, namespaceFilter, nameFilter, clusters)
+		result, err := getSliceInRange(sets, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := SetsResult{
+			TotalSets: len(sets),
+			Sets:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getFleetConnectivity = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get SveltosClusters with connectivity issues")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d", limit, skip))
+
+		expiringWithin := defaultTokenExpiringWithin
+		if value := c.Query("expiringWithin"); value != "" {
+			var err error
+			expiringWithin, err = time.ParseDuration(value)
+			if err != nil {
+				ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+				_ = c.AbortWithError(http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canListAll, err := manager.canListSveltosClusters(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		clusters, err := manager.GetManagedSveltosClusters(c.Request.Context(), canListAll, user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		response := evaluateFleetConnectivity(clusters, time.Now(), expiringWithin)
+		response.ConnectivityIssues, err = getSliceInRange(response.ConnectivityIssues, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	probeCluster = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("probe connectivity to a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+			return
+		}
+
+		// Probe failures are reported in the result
+		result := manager.probeCluster(c.Request.Context(), namespace, name, clusterType)
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	pauseCluster = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("pause cluster")
+		updateClusterPause(c, true)
+	}
+
+	resumeCluster = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("resume cluster")
+		updateClusterPause(c, false)
+	}
+
+	updateClusterLabels = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("patch cluster labels")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s", clusterType, namespace, name))
+
+		labelPatch := &LabelPatch{}
+		if err := c.ShouldBindJSON(labelPatch); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if len(labelPatch.Labels) == 0 {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("no label to patch"))
+			return
+		}
+
+		userInfo, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+		user := userInfo.Username
+
+		manager := GetManagerInstance()
+
+		canListClusterProfiles, err := manager.canListClusterProfiles(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		canListProfiles, err := manager.canListProfiles(user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		// Only profiles user has access to are reported
+		profiles, err := manager.GetProfiles(c.Request.Context(), canListClusterProfiles, canListProfiles, user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		result, err := patchClusterLabels(c.Request.Context(), impersonatingClient, namespace, name, clusterType,
+			labelPatch, profiles)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to patch cluster labels %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	registerCluster = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("register SveltosCluster")
+
+		registration := &ClusterRegistration{}
+		if err := c.ShouldBindJSON(registration); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if registration.Namespace == "" || registration.Name == "" || registration.Kubeconfig == "" {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("namespace, name and kubeconfig are required"))
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s/%s", registration.Namespace, registration.Name))
+
+		restConfig, err := getRestConfigFromKubeconfig([]byte(registration.Kubeconfig))
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		userInfo, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+
+		result, err := registerSveltosCluster(c.Request.Context(), impersonatingClient, registration,
+			restConfig, userInfo.Username)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to register cluster %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		if !result.Registered {
+			// Cluster could not be reached with the provided kubeconfig. Probe details are returned.
+			c.JSON(http.StatusBadRequest, result)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusCreated, result)
+	}
+
+	deregisterCluster = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("deregister SveltosCluster")
+
+		namespace, name, _ := getClusterFromQuery(c)
+		if namespace == "" || name == "" {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("namespace and name are required"))
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s/%s", namespace, name))
+
+		_, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+
+		result, err := deregisterSveltosCluster(c.Request.Context(), impersonatingClient, namespace, name)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to deregister cluster %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	createProfile = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("create a ClusterProfile/Profile")
+		changeProfile(c, false)
+	}
+
+	updateProfile = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("update a ClusterProfile/Profile")
+		changeProfile(c, true)
+	}
+
+	deleteProfile = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("delete a ClusterProfile/Profile")
+
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		dryRun := getDryRunFromQuery(c)
+		force := getForceFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s dryRun %t force %t",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name, dryRun, force))
+
+		userInfo, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		// Only dependents the user can get are listed
+		var dependents []corev1.ObjectReference
+		otherDependents := 0
+		if profileInfo := manager.GetProfile(profileRef); profileInfo.Dependents != nil {
+			for _, dependent := range profileInfo.Dependents.Items() {
+				canGet, err := manager.canGetProfileRef(&dependent, userInfo.Username)
+				if err != nil {
+					ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+					_ = c.AbortWithError(http.StatusUnauthorized, err)
+					return
+				}
+				if canGet {
+					dependents = append(dependents, dependent)
+				} else {
+					otherDependents++
+				}
+			}
+		}
+
+		result, err := deleteProfileObject(c.Request.Context(), impersonatingClient, profileRef, dependents,
+			otherDependents, dryRun, force)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to delete profile %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		if len(result.ValidationErrors) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, result)
+			return
+		}
+
+		if result.ForceRequired {
+			c.JSON(http.StatusConflict, result)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	redeployProfile = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("redeploy a ClusterProfile/Profile on a cluster")
+
+		namespace, name, clusterType := getClusterFromQuery(c)
+		profileRef, err := getRedeployProfileFromQuery(c, namespace)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s profile %s %s", clusterType, namespace, name,
+			profileRef.Kind, profileRef.Name))
+
+		timeout := defaultRedeployWatchTimeout
+		if value := c.Query("timeout"); value != "" {
+			timeout, err = time.ParseDuration(value)
+			if err != nil || timeout <= 0 || timeout > maxRedeployWatchTimeout {
+				msg := fmt.Sprintf("timeout must be a positive duration not greater than %s", maxRedeployWatchTimeout)
+				ginLogger.V(logs.LogInfo).Info(msg)
+				_ = c.AbortWithError(http.StatusBadRequest, errors.New(msg))
+				return
+			}
+		}
+
+		userInfo, err := validateTokenAndGetUserInfo(c)
+		if err != nil {
+			return
+		}
+		user := userInfo.Username
+
+		manager := GetManagerInstance()
+
+		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		canUpdateProfile, err := manager.canUpdateProfile(profileRef, userInfo)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetCluster || !canUpdateProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to redeploy this profile"))
+			return
+		}
+
+		clusterSummaryRef := getClusterSummaryRef(profileRef, namespace, name, clusterType)
+		// Status before the redeploy, so a stale status is not reported as the redeploy outcome
+		baseline := manager.getClusterSummaryStatus(clusterSummaryRef)
+		requestedAt := time.Now()
+		err = requestRedeploy(c.Request.Context(), manager.client, clusterSummaryRef, user, requestedAt)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to request redeploy %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		// Stream status changes as Server-Sent Events
+		c.Header("Cache-Control", "no-cache")
+		watchRedeploy(ctx, redeployPollInterval, &baseline, requestedAt,
+			func() ClusterProfileStatus { return manager.getClusterSummaryStatus(clusterSummaryRef) },
+			func(event string, data any) {
+				c.SSEvent(event, data)
+				c.Writer.Flush()
+			})
+	}
+
+	updateProfileSyncMode = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("change sync mode of a ClusterProfile/Profile")
+
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		request := &SyncModeRequest{}
+		if err := c.ShouldBindJSON(request); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if !isSupportedSyncMode(request.SyncMode) {
+			_ = c.AbortWithError(http.StatusBadRequest, fmt.Errorf("unsupported syncMode %q", request.SyncMode))
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s syncMode %s",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name, request.SyncMode))
+
+		userInfo, err := validateTokenAndGetUserInfo(c)
+		if err != nil {
+			return
+		}
+		user := userInfo.Username
+
+		manager := GetManagerInstance()
+
+		canUpdateProfile, err := manager.canUpdateProfile(profileRef, userInfo)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canUpdateProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to update this profile"))
+			return
+		}
+
+		// Only reports for clusters the user has access to are summarized
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		reports := manager.getDryRunReportsForProfile(profileRef, clusters)
+
+		result, err := setProfileSyncMode(c.Request.Context(), manager.client, manager.confirmationKey, profileRef,
+			request, user, reports)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to change sync mode %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		if result.ConfirmationRequired {
+			c.JSON(http.StatusPreconditionRequired, result)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	startBulkOperation = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("start bulk operation on clusters")
+
+		filters, err := getClusterFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		// Prevent accidentally acting on the whole fleet
+		if filters.labelSelector.Empty() {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("labels is required"))
+			return
+		}
+
+		request := &BulkOperationRequest{}
+		if err := c.ShouldBindJSON(request); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if err := validateBulkOperationRequest(request); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("action %s selector %s dryRun %t", request.Action,
+			filters.labelSelector.String(), request.DryRun))
+
+		userInfo, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+		user := userInfo.Username
+
+		manager := GetManagerInstance()
+
+		clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		job := newBulkJob(request, getFilteredClusterRefs(clusters, filters), user, time.Now(),
+			func(cluster *corev1.ObjectReference) (bool, error) {
+				return manager.canUpdateCluster(cluster.Namespace, cluster.Name, userInfo,
+					clusterproxy.GetClusterType(cluster))
+			})
+
+		if request.DryRun {
+			// Return JSON response
+			c.JSON(http.StatusOK, job)
+			return
+		}
+
+		job = manager.startBulkJob(job, bulkConcurrency, func(ctx context.Context, cluster *BulkClusterResult) error {
+			return manager.runBulkAction(ctx, request, cluster, userInfo, impersonatingClient)
+		})
+
+		// Return JSON response
+		c.JSON(http.StatusAccepted, job)
+	}
+
+	getBulkOperation = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get bulk operation")
+
+		id := c.Query("id")
+		if id == "" {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("id is required"))
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		job := manager.getBulkJob(id, user)
+		if job == nil {
+			_ = c.AbortWithError(http.StatusNotFound, fmt.Errorf("bulk operation %s not found", id))
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, job)
+	}
+
+	createProfileChangeRequest = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("create a change request for a ClusterProfile/Profile")
+
+		description := c.Query("description")
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		profile, validationErrors := decodeProfile(body)
+		if validationErrors != nil {
+			c.JSON(http.StatusUnprocessableEntity, ProfileChangeResult{ValidationErrors: validationErrors})
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		profileRef := getKeyFromObject(manager.scheme, profile)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name))
+
+		canGetProfile, err := manager.canGetProfileRef(profileRef, user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this profile"))
+			return
+		}
+
+		changeRequest, err := createChangeRequest(c.Request.Context(), manager.client, profile, description,
+			user, time.Now())
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to create change request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		respondWithChangeRequestDetail(c, manager, changeRequest, user, http.StatusOK)
+	}
+
+	getProfileChangeRequests = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get change requests")
+
+		limit, skip := getLimitAndSkipFromQuery(c)
+		filters, err := getProfileFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		state := c.Query("state")
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("limit %d skip %d kind %q namespace %q name %q state %q",
+			limit, skip, filters.Kind, filters.Namespace, filters.Name, state))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		changeRequests, err := listChangeRequests(c.Request.Context(), manager.client)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to list change requests %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		// Only change requests for profiles the user has access to are reported
+		changeRequests = filterChangeRequests(changeRequests, filters, state,
+			func(profileRef *corev1.ObjectReference) (bool, error) {
+				return manager.canGetProfileRef(profileRef, user)
+			})
+
+		result, err := getSliceInRange(changeRequests, limit, skip)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		response := ChangeRequestsResult{
+			TotalChangeRequests: len(changeRequests),
+			ChangeRequests:      result,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getProfileChangeRequest = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get change request")
+
+		id := c.Query("id")
+		if id == "" {
+			_ = c.AbortWithError(http.StatusBadRequest, errors.New("id is required"))
+			return
+		}
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		changeRequest, _, err := getChangeRequest(c.Request.Context(), manager.client, id)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get change request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		canGetProfile, err := manager.canGetProfileRef(getProfileRefFromChangeRequest(changeRequest), user)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canGetProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this profile"))
+			return
+		}
+
+		respondWithChangeRequestDetail(c, manager, changeRequest, user, http.StatusOK)
+	}
+
+	approveProfileChangeRequest = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("approve change request")
+		reviewChangeRequest(c, true)
+	}
+
+	rejectProfileChangeRequest = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("reject change request")
+		reviewChangeRequest(c, false)
+	}
+
+	getProfileRevisions = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get revisions of a ClusterProfile/Profile")
+
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		if !canGetProfileRevisions(c, manager, profileRef, user) {
+			return
+		}
+
+		revisions := manager.getProfileRevisions(profileRef)
+		response := ProfileRevisionsResult{
+			TotalRevisions: len(revisions),
+			Revisions:      revisions,
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, response)
+	}
+
+	getProfileRevisionsDiff = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("get diff between revisions of a ClusterProfile/Profile")
+
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		from, to, err := getRevisionRangeFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s from %d to %d",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name, from, to))
+
+		user, err := validateToken(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		manager := GetManagerInstance()
+
+		if !canGetProfileRevisions(c, manager, profileRef, user) {
+			return
+		}
+
+		result, err := getProfileRevisionDiff(manager.getProfileRevisions(profileRef), from, to)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusNotFound, err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, result)
+	}
+
+	exportProfileBundleHandler = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("export a ClusterProfile/Profile as a bundle")
+
+		profileRef, err := getProfileRefFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		format, redactSecrets, err := getBundleOptionsFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s format %s redactSecrets %t",
+			profileRef.Kind, profileRef.Namespace, profileRef.Name, format, redactSecrets))
+
+		// Profiles, ConfigMaps and Secrets are read on behalf of the user
+		_, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
+			return
+		}
+
+		bundle, err := exportProfileBundle(c.Request.Context(), impersonatingClient, profileRef, redactSecrets)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to export profile %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		data, err := encodeProfileBundle(bundle, format)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to encode bundle %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
 
-		manager := GetManagerInstance()
+		for i := range bundle.Warnings {
+			c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", bundle.Warnings[i]))
+		}
 
-		canGetCluster, err := manager.canGetCluster(namespace, name, user, clusterType)
+		contentType := "application/yaml"
+		if format == BundleFormatTar {
+			contentType = "application/x-tar"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+			fmt.Sprintf("%s-%s.%s", strings.ToLower(profileRef.Kind), profileRef.Name, format)))
+		c.Data(http.StatusOK, contentType, data)
+	}
+
+	importProfileBundleHandler = func(c *gin.Context) {
+		ginLogger.V(logs.LogDebug).Info("import a bundle of ClusterProfiles/Profiles")
+
+		dryRun := getDryRunFromQuery(c)
+		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("dryRun %t", dryRun))
+
+		body, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
-			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
 			return
 		}
 
-		if !canGetCluster {
-			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this cluster"))
+		// All objects are created/updated on behalf of the user
+		_, impersonatingClient, err := getUserAndImpersonatingClient(c)
+		if err != nil {
 			return
 		}
 
-		resources, err := manager.getResourcesForCluster(c.Request.Context(),
-			namespace, name, clusterType)
-		if err != nil {
-			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
-			_ = c.AbortWithError(http.StatusBadRequest, err)
+		objects, validationErrors := decodeProfileBundle(body)
+		if validationErrors != nil {
+			c.JSON(http.StatusUnprocessableEntity,
+				BundleImportResult{DryRun: dryRun, ValidationErrors: validationErrors})
 			return
 		}
-		sort.Slice(resources, func(i, j int) bool {
-			return sortResources(resources, i, j)
-		})
 
-		result, err := getResourcesInRange(resources, limit, skip)
+		result, err := importProfileBundle(c.Request.Context(), impersonatingClient, objects, dryRun)
 		if err != nil {
-			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
-			_ = c.AbortWithError(http.StatusBadRequest, err)
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to import bundle %s: %v", c.Request.URL, err))
+			if result != nil {
+				// Some objects might have been applied already
+				c.JSON(getStatusCodeFromError(err), result)
+				return
+			}
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
 			return
 		}
 
-		response := ResourceResult{
-			TotalResources: len(resources),
-			Resources:      result,
+		if len(result.ValidationErrors) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, result)
+			return
 		}
 
 		// Return JSON response
-		c.JSON(http.StatusOK, response)
+		c.JSON(http.StatusOK, result)
 	}
 
 	getClusterStatus = func(c *gin.Context) {
@@ -323,7 +2098,12 @@ var (
 	getProfiles = func(c *gin.Context) {
 		ginLogger.V(logs.LogDebug).Info("get managed ClusterProfiles/Profiles")
 
-		filters := getProfileFiltersFromQuery(c)
+		filters, err := getProfileFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
 		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("filters: kind %q namespace %q name %q",
 			filters.Kind, filters.Namespace, filters.Name))
 
@@ -376,7 +2156,12 @@ var (
 	getProfile = func(c *gin.Context) {
 		ginLogger.V(logs.LogDebug).Info("get a managed ClusterProfile/Profile")
 
-		filters := getProfileFiltersFromQuery(c)
+		filters, err := getProfileFiltersFromQuery(c)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
 		ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("filters: kind %q namespace %q name %q",
 			filters.Kind, filters.Namespace, filters.Name))
 
@@ -456,6 +2241,7 @@ var (
 			MatchingClusters: matchingClusters,
 			Dependencies:     transformSetToSlice(profileInfo.Dependencies),
 			Dependents:       transformSetToSlice(profileInfo.Dependents),
+			Provenance:       profileInfo.Provenance,
 		}
 
 		// Return JSON response
@@ -477,6 +2263,90 @@ func (m *instance) start(ctx context.Context, port string, logger logr.Logger) {
 	r.GET("/helmcharts", getDeployedHelmCharts)
 	// Return resources deployed in a given managed cluster
 	r.GET("/resources", getDeployedResources)
+	// Return revision history of a helm release deployed in a given managed cluster
+	r.GET("/helmreleasehistory", getHelmReleaseHistory)
+	// Return differences between add-ons deployed in two managed clusters
+	r.GET("/compareclusters", compareClusters)
+	// Return expected vs deployed add-ons for a managed cluster
+	r.GET("/compliance", getClusterCompliance)
+	// Return expected vs deployed add-ons for all managed clusters
+	r.GET("/fleetcompliance", getFleetCompliance)
+	// Return configuration drifts detected in a managed cluster
+	r.GET("/drifts", getClusterDrifts)
+	// Return resources which drifted the most across all managed clusters
+	r.GET("/mostdriftedresources", getMostDriftedResources)
+	// Return changes DryRun profiles would apply to a managed cluster
+	r.GET("/dryrun", getClusterDryRunReports)
+	// Return changes a DryRun ClusterProfile/Profile would apply to matching clusters
+	r.GET("/profiledryrun", getProfileDryRunReports)
+	// Return existing EventTriggers
+	r.GET("/eventtriggers", getEventTriggers)
+	// Return events matched in a managed cluster and EventTriggers reacting to those
+	r.GET("/clusterevents", getClusterEvents)
+	// Return existing Classifiers and clusters matching those
+	r.GET("/classifiers", getClassifiers)
+	// Return cluster labels more than one Classifier wants to manage
+	r.GET("/classifierconflicts", getClassifierConflicts)
+	// Return Classifiers managing labels of a managed cluster
+	r.GET("/clusterclassifiers", getClusterClassifiers)
+	// Return existing ClusterHealthChecks and referenced HealthChecks
+	r.GET("/clusterhealthchecks", getClusterHealthChecks)
+	// Return health checks outcome and health history of a managed cluster
+	r.GET("/clusterhealth", getClusterHealth)
+	// Return health summary of all managed clusters
+	r.GET("/fleethealth", getFleetHealth)
+	// Return existing RoleRequests and the Roles/ClusterRoles those grant
+	r.GET("/rolerequests", getRoleRequests)
+	// Return tenants with access to a managed cluster
+	r.GET("/clustertenants", getClusterTenants)
+	// Return existing ClusterSets/Sets with matching and selected clusters
+	r.GET("/sets", getSets)
+	// Return SveltosClusters whose connection is degraded or whose token is about to expire
+	r.GET("/fleetconnectivity", getFleetConnectivity)
+	// Probe connectivity to a managed cluster. Results are cached for a short time
+	r.GET("/clusterprobe", probeCluster)
+	// Pause a managed cluster. Sveltos stops deploying add-ons to paused clusters
+	r.POST("/pausecluster", pauseCluster)
+	// Resume a paused managed cluster
+	r.POST("/resumecluster", resumeCluster)
+	// Patch labels of a managed cluster on behalf of the user. Supports dry-run
+	r.PATCH("/clusterlabels", updateClusterLabels)
+	// Register a SveltosCluster from a kubeconfig on behalf of the user
+	r.POST("/sveltoscluster", registerCluster)
+	// Deregister a SveltosCluster, removing its kubeconfig Secret, on behalf of the user
+	r.DELETE("/sveltoscluster", deregisterCluster)
+	// Create a ClusterProfile/Profile on behalf of the user. Server-side dry-run is always run first
+	r.POST("/profile", createProfile)
+	// Update a ClusterProfile/Profile on behalf of the user. Server-side dry-run is always run first
+	r.PUT("/profile", updateProfile)
+	// Delete a ClusterProfile/Profile on behalf of the user. Warns if other profiles depend on it
+	r.DELETE("/profile", deleteProfile)
+	// Trigger redeployment of a ClusterProfile/Profile on a cluster and stream status changes
+	r.POST("/redeploy", redeployProfile)
+	// Change SyncMode of a ClusterProfile/Profile. Moving out of DryRun requires a confirmation token
+	r.PATCH("/profilesyncmode", updateProfileSyncMode)
+	// Run an action (pause, resume, label, resync) on all clusters matching a label selector
+	r.POST("/bulkoperation", startBulkOperation)
+	// Get progress of a bulk operation
+	r.GET("/bulkoperation", getBulkOperation)
+	// Propose a change to a ClusterProfile/Profile spec. Change is applied only once approved
+	r.POST("/changerequest", createProfileChangeRequest)
+	// Return change requests
+	r.GET("/changerequests", getProfileChangeRequests)
+	// Return a change request with its diff against the current profile and the affected clusters
+	r.GET("/changerequest", getProfileChangeRequest)
+	// Approve a change request, applying it on behalf of the approver
+	r.POST("/approvechangerequest", approveProfileChangeRequest)
+	// Reject a change request
+	r.POST("/rejectchangerequest", rejectProfileChangeRequest)
+	// Return the spec revisions of a ClusterProfile/Profile recorded since the backend started
+	r.GET("/profilerevisions", getProfileRevisions)
+	// Return the spec changes between two revisions of a ClusterProfile/Profile
+	r.GET("/profilerevisiondiff", getProfileRevisionsDiff)
+	// Export a ClusterProfile/Profile, its dependencies and referenced ConfigMaps/Secrets as a bundle
+	r.GET("/profilebundle", exportProfileBundleHandler)
+	// Import a bundle on behalf of the user. Server-side dry-run is always run first
+	r.POST("/profilebundle", importProfileBundleHandler)
 	// Return the specified cluster status
 	r.GET("/getClusterStatus", getClusterStatus)
 	// Return existing ClusterProfiles/Profiles
@@ -510,6 +2380,22 @@ func getManagedClusterData(clusters map[corev1.ObjectReference]ClusterInfo, filt
 ) ManagedClusters {
 
 	data := make(ManagedClusters, 0)
+	for _, k := range getFilteredClusterRefs(clusters, filters) {
+		data = append(data, ManagedCluster{
+			Namespace:   k.Namespace,
+			Name:        k.Name,
+			ClusterInfo: clusters[k],
+		})
+	}
+
+	return data
+}
+
+// getFilteredClusterRefs returns the sorted list of clusters matching filters
+func getFilteredClusterRefs(clusters map[corev1.ObjectReference]ClusterInfo, filters *clusterFilters,
+) []corev1.ObjectReference {
+
+	result := make([]corev1.ObjectReference, 0)
 	for k := range clusters {
 		if filters.Namespace != "" {
 			if !strings.Contains(k.Namespace, filters.Namespace) {
@@ -529,14 +2415,24 @@ func getManagedClusterData(clusters map[corev1.ObjectReference]ClusterInfo, filt
 			}
 		}
 
-		data = append(data, ManagedCluster{
-			Namespace:   k.Namespace,
-			Name:        k.Name,
-			ClusterInfo: clusters[k],
-		})
+		if !hasMatchingCondition(clusters[k].Conditions, filters.ConditionType, filters.ConditionStatus) {
+			continue
+		}
+
+		result = append(result, k)
 	}
 
-	return data
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace == result[j].Namespace {
+			if result[i].Name == result[j].Name {
+				return result[i].Kind < result[j].Kind
+			}
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+
+	return result
 }
 
 // getProfileData groups profiles by tiers. Returns a map of all profiles for a given tier.
@@ -565,6 +2461,12 @@ func getProfileData(profiles map[corev1.ObjectReference]ProfileInfo, filters *pr
 			}
 		}
 
+		if filters.Generated != nil {
+			if *filters.Generated != (profile.Provenance != nil) {
+				continue
+			}
+		}
+
 		_, ok := result[profile.Tier]
 		if !ok {
 			result[profile.Tier] = make(Profiles, 0)
@@ -576,6 +2478,7 @@ func getProfileData(profiles map[corev1.ObjectReference]ProfileInfo, filters *pr
 			Name:         k.Name,
 			Dependencies: transformSetToSlice(profile.Dependencies),
 			Dependents:   transformSetToSlice(profile.Dependents),
+			Provenance:   profile.Provenance,
 		}
 
 		result[profile.Tier] = append(result[profile.Tier], tmpProfile)
@@ -680,6 +2583,60 @@ func getClusterFromQuery(c *gin.Context) (namespace, name string, clusterType li
 	return
 }
 
+func getHelmReleaseFromQuery(c *gin.Context) (namespace, name string, err error) {
+	// Get the values from query parameters
+	namespace = c.Query("releaseNamespace")
+	name = c.Query("releaseName")
+
+	if namespace == "" {
+		return "", "", errors.New("releaseNamespace is required")
+	}
+	if name == "" {
+		return "", "", errors.New("releaseName is required")
+	}
+
+	return namespace, name, nil
+}
+
+// getShowValuesFromQuery returns true if the showValues query parameter is set to true
+func getShowValuesFromQuery(c *gin.Context) bool {
+	showValues, err := strconv.ParseBool(c.Query("showValues"))
+	if err != nil {
+		return false
+	}
+	return showValues
+}
+
+// getComparedClusterFromQuery returns the cluster identified by namespace<suffix>,
+// name<suffix> and type<suffix> query parameters
+func getComparedClusterFromQuery(c *gin.Context, suffix string) (*ComparedCluster, error) {
+	namespace := c.Query("namespace" + suffix)
+	name := c.Query("name" + suffix)
+	queryType := c.Query("type" + suffix)
+
+	if namespace == "" {
+		return nil, fmt.Errorf("namespace%s is required", suffix)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("name%s is required", suffix)
+	}
+
+	var clusterType libsveltosv1beta1.ClusterType
+	if strings.EqualFold(queryType, string(libsveltosv1beta1.ClusterTypeSveltos)) {
+		clusterType = libsveltosv1beta1.ClusterTypeSveltos
+	} else if strings.EqualFold(queryType, string(libsveltosv1beta1.ClusterTypeCapi)) {
+		clusterType = libsveltosv1beta1.ClusterTypeCapi
+	} else {
+		return nil, fmt.Errorf("type%s is incorrect", suffix)
+	}
+
+	return &ComparedCluster{
+		Namespace:   namespace,
+		Name:        name,
+		ClusterType: clusterType,
+	}, nil
+}
+
 func getTokenFromAuthorizationHeader(c *gin.Context) (string, error) {
 	// Get the authorization header value
 	authorizationHeader := c.GetHeader("Authorization")
@@ -709,12 +2666,23 @@ func getTokenFromAuthorizationHeader(c *gin.Context) (string, error) {
 // - validate token. Returns an error if this check fails
 // - get and return user info. Returns an error if getting user from token fails
 func validateToken(c *gin.Context) (string, error) {
+	userInfo, err := validateTokenAndGetUserInfo(c)
+	if err != nil {
+		return "", err
+	}
+
+	return userInfo.Username, nil
+}
+
+// validateTokenAndGetUserInfo validates the token in the authorization header and returns its owner.
+// Request is aborted on failure.
+func validateTokenAndGetUserInfo(c *gin.Context) (*authenticationv1.UserInfo, error) {
 	token, err := getTokenFromAuthorizationHeader(c)
 	if err != nil {
 		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get token from authorization request. Request %s, error %v",
 			c.Request.URL, err))
 		_ = c.AbortWithError(http.StatusUnauthorized, errors.New("failed to get token from authorization request"))
-		return "", err
+		return nil, err
 	}
 
 	manager := GetManagerInstance()
@@ -722,17 +2690,353 @@ func validateToken(c *gin.Context) (string, error) {
 	if err != nil {
 		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to validate token: %v", err))
 		_ = c.AbortWithError(http.StatusUnauthorized, errors.New("failed to validate token"))
-		return "", err
+		return nil, err
 	}
 
-	user, err := manager.getUserFromToken(token)
+	userInfo, err := manager.getUserInfoFromToken(token)
 	if err != nil {
 		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get user from token: %v", err))
 		_ = c.AbortWithError(http.StatusUnauthorized, errors.New("failed to get user from token"))
-		return "", err
+		return nil, err
+	}
+
+	ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("user %s", userInfo.Username))
+
+	return userInfo, nil
+}
+
+// getUserAndImpersonatingClient validates the token and returns its owner along with a client
+// impersonating it, so RBAC is enforced by the API server. Request is aborted on failure.
+func getUserAndImpersonatingClient(c *gin.Context) (*authenticationv1.UserInfo, client.Client, error) {
+	userInfo, err := validateTokenAndGetUserInfo(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manager := GetManagerInstance()
+	impersonatingClient, err := manager.getImpersonatingClient(userInfo)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get client: %v", err))
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil, err
+	}
+
+	return userInfo, impersonatingClient, nil
+}
+
+// updateClusterPause pauses/resumes the cluster specified in the query. User must be
+// allowed to update the cluster.
+func updateClusterPause(c *gin.Context, paused bool) {
+	namespace, name, clusterType := getClusterFromQuery(c)
+	ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s:%s/%s paused %t", clusterType, namespace, name, paused))
+
+	request := &PauseRequest{}
+	if err := c.ShouldBindJSON(request); err != nil && !errors.Is(err, io.EOF) {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if paused && request.Reason == "" {
+		_ = c.AbortWithError(http.StatusBadRequest, errors.New("reason is required"))
+		return
+	}
+	if err := validateClusterAPIPause(clusterType, paused, request.PauseClusterAPI); err != nil {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	userInfo, err := validateTokenAndGetUserInfo(c)
+	if err != nil {
+		return
+	}
+	user := userInfo.Username
+
+	manager := GetManagerInstance()
+
+	canUpdate, err := manager.canUpdateCluster(namespace, name, userInfo, clusterType)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+
+	if !canUpdate {
+		_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to update this cluster"))
+		return
+	}
+
+	err = manager.setClusterPaused(c.Request.Context(), namespace, name, clusterType, paused, user, request.Reason)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to update cluster %s: %v", c.Request.URL, err))
+		if apierrors.IsNotFound(err) {
+			_ = c.AbortWithError(http.StatusNotFound, err)
+			return
+		}
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Return JSON response
+	c.JSON(http.StatusOK, getPauseResult(namespace, name, clusterType, paused))
+}
+
+// getStatusCodeFromError returns the HTTP status code for an error returned by the API server
+// while acting on behalf of the user
+func getStatusCodeFromError(err error) int {
+	switch {
+	case apierrors.IsNotFound(err):
+		return http.StatusNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return http.StatusUnauthorized
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return http.StatusConflict
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return http.StatusBadRequest
+	case apierrors.IsTooManyRequests(err):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// changeProfile creates (or updates) the ClusterProfile/Profile in the request body, which can be
+// either YAML or JSON. Change is applied on behalf of the user.
+func changeProfile(c *gin.Context, update bool) {
+	dryRun := getDryRunFromQuery(c)
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	_, impersonatingClient, err := getUserAndImpersonatingClient(c)
+	if err != nil {
+		return
+	}
+
+	profile, validationErrors := decodeProfile(body)
+	if validationErrors != nil {
+		c.JSON(http.StatusUnprocessableEntity, ProfileChangeResult{DryRun: dryRun, ValidationErrors: validationErrors})
+		return
+	}
+	ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("profile %s %s/%s dryRun %t",
+		profile.GetObjectKind().GroupVersionKind().Kind, profile.GetNamespace(), profile.GetName(), dryRun))
+
+	var result *ProfileChangeResult
+	if update {
+		result, err = updateProfileObject(c.Request.Context(), impersonatingClient, profile, dryRun)
+	} else {
+		result, err = createProfileObject(c.Request.Context(), impersonatingClient, profile, dryRun)
+	}
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to change profile %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(getStatusCodeFromError(err), err)
+		return
+	}
+
+	if len(result.ValidationErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	// Return JSON response
+	c.JSON(http.StatusOK, result)
+}
+
+// respondWithChangeRequestDetail returns the change request along with its diff and affected clusters.
+// Only clusters the user has access to are reported.
+func respondWithChangeRequestDetail(c *gin.Context, manager *instance, changeRequest *ChangeRequest, user string,
+	statusCode int) {
+
+	clusters, err := manager.getAccessibleClusters(c.Request.Context(), user)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+
+	result, err := manager.getChangeRequestDetail(c.Request.Context(), changeRequest, clusters)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get change request detail %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(getStatusCodeFromError(err), err)
+		return
+	}
+
+	// Return JSON response
+	c.JSON(statusCode, result)
+}
+
+// reviewChangeRequest approves (or rejects) the change request identified by the id query parameter.
+// Only users allowed to update the profile can review a change request. The requester can only reject
+// (withdraw) it. Approved changes are applied on behalf of the approver.
+func reviewChangeRequest(c *gin.Context, approve bool) {
+	id := c.Query("id")
+	if id == "" {
+		_ = c.AbortWithError(http.StatusBadRequest, errors.New("id is required"))
+		return
+	}
+
+	review := &ChangeRequestReview{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(review); err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("bad request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+	}
+	ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("change request %s approve %t", id, approve))
+
+	// Approved changes are applied on behalf of the approver
+	userInfo, impersonatingClient, err := getUserAndImpersonatingClient(c)
+	if err != nil {
+		return
+	}
+	user := userInfo.Username
+
+	manager := GetManagerInstance()
+
+	changeRequest, _, err := getChangeRequest(c.Request.Context(), manager.client, id)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get change request %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(getStatusCodeFromError(err), err)
+		return
+	}
+
+	if approve || changeRequest.RequestedBy != user {
+		canUpdateProfile, err := manager.canUpdateProfile(getProfileRefFromChangeRequest(changeRequest), userInfo)
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		if !canUpdateProfile {
+			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to update this profile"))
+			return
+		}
+	}
+
+	if !approve {
+		changeRequest, err = rejectChangeRequest(c.Request.Context(), manager.client, id, user, review.Comment,
+			time.Now())
+		if err != nil {
+			ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to reject change request %s: %v", c.Request.URL, err))
+			_ = c.AbortWithError(getStatusCodeFromError(err), err)
+			return
+		}
+
+		// Return JSON response
+		c.JSON(http.StatusOK, changeRequest)
+		return
+	}
+
+	changeRequest, err = approveChangeRequest(c.Request.Context(), manager.client, impersonatingClient, id, user,
+		review.Comment, time.Now())
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to approve change request %s: %v", c.Request.URL, err))
+		if changeRequest != nil {
+			// Approval was recorded but the change could not be applied (or its outcome could not be saved)
+			c.JSON(getStatusCodeFromError(err), changeRequest)
+			return
+		}
+		_ = c.AbortWithError(getStatusCodeFromError(err), err)
+		return
+	}
+
+	if len(changeRequest.ValidationErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, changeRequest)
+		return
 	}
 
-	ginLogger.V(logs.LogDebug).Info(fmt.Sprintf("user %s", user))
+	// Return JSON response
+	c.JSON(http.StatusOK, changeRequest)
+}
+
+// canGetProfileRevisions verifies user can access the profile. If not, the request is aborted.
+func canGetProfileRevisions(c *gin.Context, manager *instance, profileRef *corev1.ObjectReference,
+	user string) bool {
+
+	canGetProfile, err := manager.canGetProfileRef(profileRef, user)
+	if err != nil {
+		ginLogger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify permissions %s: %v", c.Request.URL, err))
+		_ = c.AbortWithError(http.StatusUnauthorized, err)
+		return false
+	}
+
+	if !canGetProfile {
+		_ = c.AbortWithError(http.StatusUnauthorized, errors.New("no permissions to access this profile"))
+		return false
+	}
+
+	return true
+}
+
+// getBundleOptionsFromQuery returns the bundle format (yaml by default) and whether Secret data
+// must be redacted (true unless redactSecrets=false)
+func getBundleOptionsFromQuery(c *gin.Context) (format string, redactSecrets bool, err error) {
+	format = c.DefaultQuery("format", BundleFormatYAML)
+	if format != BundleFormatYAML && format != BundleFormatTar {
+		return "", false, fmt.Errorf("format must be %q or %q", BundleFormatYAML, BundleFormatTar)
+	}
+
+	// Secret data is exported only when explicitly requested
+	redactSecrets = true
+	if value := c.Query("redactSecrets"); value != "" {
+		redactSecrets, err = strconv.ParseBool(value)
+		if err != nil {
+			return "", false, errors.New("redactSecrets must be a boolean")
+		}
+	}
+
+	return format, redactSecrets, nil
+}
+
+// getRevisionRangeFromQuery returns the generations in the from and to query parameters.
+// Zero is returned for a missing parameter.
+func getRevisionRangeFromQuery(c *gin.Context) (from, to int64, err error) {
+	if value := c.Query("from"); value != "" {
+		from, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || from <= 0 {
+			return 0, 0, errors.New("from must be a positive generation")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		to, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || to <= 0 {
+			return 0, 0, errors.New("to must be a positive generation")
+		}
+	}
+
+	return from, to, nil
+}
+
+// getRedeployProfileFromQuery returns the ClusterProfile/Profile identified by the profileKind and
+// profileName query parameters. A Profile can only be deployed to clusters in its own namespace.
+func getRedeployProfileFromQuery(c *gin.Context, clusterNamespace string) (*corev1.ObjectReference, error) {
+	kind := c.Query("profileKind")
+	name := c.Query("profileName")
+
+	if kind != configv1beta1.ClusterProfileKind && kind != configv1beta1.ProfileKind {
+		return nil, fmt.Errorf("supported profileKind are %q and %q",
+			configv1beta1.ClusterProfileKind, configv1beta1.ProfileKind)
+	}
+	if name == "" {
+		return nil, errors.New("profileName is required")
+	}
+	if clusterNamespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+
+	profileRef := &corev1.ObjectReference{
+		Kind:       kind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+		Name:       name,
+	}
+	if kind == configv1beta1.ProfileKind {
+		profileRef.Namespace = clusterNamespace
+	}
 
-	return user, nil
+	return profileRef, nil
 }
@@ -30,9 +30,11 @@ import (
 	"k8s.io/client-go/rest"
 	certutil "k8s.io/client-go/util/cert"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/controllers"
+	eventv1beta1 "github.com/projectsveltos/event-manager/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
@@ -57,25 +59,54 @@ func (m *instance) getKubernetesRestConfig(token string) (*rest.Config, error) {
 	}, nil
 }
 
-func (m *instance) getUserFromToken(token string) (string, error) {
+// getUserInfoFromToken returns user name, groups and extra attributes of the token owner
+func (m *instance) getUserInfoFromToken(token string) (*authenticationv1.UserInfo, error) {
 	config, err := m.getKubernetesRestConfig(token)
 	if err != nil {
 		m.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get restConfig: %v", err))
-		return "", err
+		return nil, err
 	}
 
 	authV1Client, err := authenticationv1client.NewForConfig(config)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	res, err := authV1Client.SelfSubjectReviews().
 		Create(context.TODO(), &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return res.Status.UserInfo.Username, nil
+	return &res.Status.UserInfo, nil
+}
+
+// getImpersonatingClient returns a client which impersonates the user, so RBAC is enforced
+// by the API server
+func (m *instance) getImpersonatingClient(userInfo *authenticationv1.UserInfo) (client.Client, error) {
+	config := rest.CopyConfig(m.config)
+	config.Impersonate = getImpersonationConfig(userInfo)
+
+	return client.New(config, client.Options{Scheme: m.scheme})
+}
+
+// getImpersonationConfig returns the impersonation config for the user. Extra attributes
+// (e.g. set by OIDC or EKS) are forwarded as webhook authorizers can rely on those.
+func getImpersonationConfig(userInfo *authenticationv1.UserInfo) rest.ImpersonationConfig {
+	impersonationConfig := rest.ImpersonationConfig{
+		UserName: userInfo.Username,
+		UID:      userInfo.UID,
+		Groups:   userInfo.Groups,
+	}
+
+	if len(userInfo.Extra) > 0 {
+		impersonationConfig.Extra = make(map[string][]string, len(userInfo.Extra))
+		for k, v := range userInfo.Extra {
+			impersonationConfig.Extra[k] = []string(v)
+		}
+	}
+
+	return impersonationConfig
 }
 
 // canListSveltosClusters returns true if user can list all SveltosClusters in all namespaces
@@ -400,9 +431,189 @@ func (m *instance) getProfileSpecAndMatchingClusters(ctx context.Context, profil
 				MatchingClusters{
 					Cluster:                 *cluster,
 					ClusterFeatureSummaries: clusterProfileStatuses.Summary,
+					Sets:                    m.getContributingSets(profileRef, spec.SetRefs, cluster),
 				})
 		}
 	}
 
 	return &spec, accessibleMatchingClusters, nil
 }
+
+// canGetProfileRef returns true if user can access the ClusterProfile/Profile
+func (m *instance) canGetProfileRef(profileRef *corev1.ObjectReference, user string) (bool, error) {
+	if profileRef.Kind == configv1beta1.ClusterProfileKind {
+		return m.canGetClusterProfile(profileRef.Name, user)
+	}
+
+	return m.canGetProfile(profileRef.Namespace, profileRef.Name, user)
+}
+
+// canListEventTriggers verifies whether user has permission to view EventTriggers
+func (m *instance) canListEventTriggers(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    eventv1beta1.GroupVersion.Group,
+		Version:  eventv1beta1.GroupVersion.Version,
+		Resource: "eventtriggers",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canGetEventTrigger returns true if user can access EventTrigger
+func (m *instance) canGetEventTrigger(eventTriggerName, user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "get",
+		Group:    eventv1beta1.GroupVersion.Group,
+		Version:  eventv1beta1.GroupVersion.Version,
+		Resource: "eventtriggers",
+		Name:     eventTriggerName,
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canListClassifiers returns true if user can list all Classifiers
+func (m *instance) canListClassifiers(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    libsveltosv1beta1.GroupVersion.Group,
+		Version:  libsveltosv1beta1.GroupVersion.Version,
+		Resource: "classifiers",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canListClusterHealthChecks returns true if user can list all ClusterHealthChecks
+func (m *instance) canListClusterHealthChecks(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    libsveltosv1beta1.GroupVersion.Group,
+		Version:  libsveltosv1beta1.GroupVersion.Version,
+		Resource: "clusterhealthchecks",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canListRoleRequests returns true if user can list all RoleRequests
+func (m *instance) canListRoleRequests(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    libsveltosv1beta1.GroupVersion.Group,
+		Version:  libsveltosv1beta1.GroupVersion.Version,
+		Resource: "rolerequests",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canGetRoleRef returns true if user can access the ConfigMap/Secret referenced by a RoleRequest
+func (m *instance) canGetRoleRef(roleRef *libsveltosv1beta1.PolicyRef, user string) (bool, error) {
+	resource := "configmaps"
+	if roleRef.Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
+		resource = "secrets"
+	}
+
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:      "get",
+		Version:   "v1",
+		Resource:  resource,
+		Namespace: roleRef.Namespace,
+		Name:      roleRef.Name,
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canListClusterSets returns true if user can list all ClusterSets
+func (m *instance) canListClusterSets(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    libsveltosv1beta1.GroupVersion.Group,
+		Version:  libsveltosv1beta1.GroupVersion.Version,
+		Resource: "clustersets",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canListSets returns true if user can list all Sets in all namespaces
+func (m *instance) canListSets(user string) (bool, error) {
+	return m.isAllowed(&authorizationapi.ResourceAttributes{
+		Verb:     "list",
+		Group:    libsveltosv1beta1.GroupVersion.Group,
+		Version:  libsveltosv1beta1.GroupVersion.Version,
+		Resource: "sets",
+	}, &authenticationv1.UserInfo{Username: user})
+}
+
+// canUpdateCluster returns true if user can update the SveltosCluster/ClusterAPI Cluster
+func (m *instance) canUpdateCluster(clusterNamespace, clusterName string, userInfo *authenticationv1.UserInfo,
+	clusterType libsveltosv1beta1.ClusterType) (bool, error) {
+
+	resourceAttributes := &authorizationapi.ResourceAttributes{
+		Verb:      "update",
+		Group:     clusterv1.GroupVersion.Group,
+		Version:   clusterv1.GroupVersion.Version,
+		Resource:  "clusters",
+		Namespace: clusterNamespace,
+		Name:      clusterName,
+	}
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		resourceAttributes.Group = libsveltosv1beta1.GroupVersion.Group
+		resourceAttributes.Version = libsveltosv1beta1.GroupVersion.Version
+		resourceAttributes.Resource = "sveltosclusters"
+	}
+
+	return m.isAllowed(resourceAttributes, userInfo)
+}
+
+// canUpdateProfile verifies whether user has permission to update the ClusterProfile/Profile
+func (m *instance) canUpdateProfile(profileRef *corev1.ObjectReference, userInfo *authenticationv1.UserInfo,
+) (bool, error) {
+	resourceAttributes := &authorizationapi.ResourceAttributes{
+		Verb:     "update",
+		Group:    configv1beta1.GroupVersion.Group,
+		Version:  configv1beta1.GroupVersion.Version,
+		Resource: "clusterprofiles",
+		Name:     profileRef.Name,
+	}
+	if profileRef.Kind == configv1beta1.ProfileKind {
+		resourceAttributes.Resource = "profiles"
+		resourceAttributes.Namespace = profileRef.Namespace
+	}
+
+	return m.isAllowed(resourceAttributes, userInfo)
+}
+
+// isAllowed creates a SubjectAccessReview for the user (including groups, UID and extra attributes)
+// and returns whether access is allowed
+func (m *instance) isAllowed(resourceAttributes *authorizationapi.ResourceAttributes,
+	userInfo *authenticationv1.UserInfo) (bool, error) {
+
+	// Create a Kubernetes clientset
+	clientset, err := kubernetes.NewForConfig(m.config)
+	if err != nil {
+		m.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get clientset: %v", err))
+		return false, err
+	}
+
+	sar := &authorizationapi.SubjectAccessReview{
+		Spec: authorizationapi.SubjectAccessReviewSpec{
+			ResourceAttributes: resourceAttributes,
+			User:               userInfo.Username,
+			Groups:             userInfo.Groups,
+			UID:                userInfo.UID,
+			Extra:              getSubjectAccessReviewExtra(userInfo),
+		},
+	}
+
+	canI, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	if err != nil {
+		m.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to check clientset permissions: %v", err))
+		return false, err
+	}
+
+	return canI.Status.Allowed, nil
+}
+
+// getSubjectAccessReviewExtra returns the user extra attributes in the SubjectAccessReview format
+func getSubjectAccessReviewExtra(userInfo *authenticationv1.UserInfo) map[string]authorizationapi.ExtraValue {
+	if len(userInfo.Extra) == 0 {
+		return nil
+	}
+
+	extra := make(map[string]authorizationapi.ExtraValue, len(userInfo.Extra))
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationapi.ExtraValue(v)
+	}
+	return extra
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// LabelPatch is the body of a cluster labels PATCH request
+type LabelPatch struct {
+	// Labels to add or update. A null value removes the label.
+	Labels map[string]*string `json:"labels"`
+
+	// DryRun, when true, validates the change and computes profile match changes without
+	// committing it
+	DryRun bool `json:"dryRun"`
+}
+
+type LabelPatchResult struct {
+	DryRun bool `json:"dryRun"`
+
+	// Labels are the cluster labels after the change
+	Labels map[string]string `json:"labels"`
+
+	// MatchingProfiles are the ClusterProfiles/Profiles whose ClusterSelector matches the cluster
+	// after the change
+	MatchingProfiles []corev1.ObjectReference `json:"matchingProfiles"`
+
+	// AddedProfiles start matching the cluster because of the change
+	AddedProfiles []corev1.ObjectReference `json:"addedProfiles"`
+
+	// RemovedProfiles stop matching the cluster because of the change
+	RemovedProfiles []corev1.ObjectReference `json:"removedProfiles"`
+}
+
+// patchClusterLabels patches the labels of a SveltosCluster/ClusterAPI Cluster using client c,
+// which impersonates the calling user. Profile match changes are computed from profiles.
+func patchClusterLabels(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, labelPatch *LabelPatch,
+	profiles map[corev1.ObjectReference]ProfileInfo) (*LabelPatchResult, error) {
+
+	if len(labelPatch.Labels) == 0 {
+		return nil, errors.New("no label to patch")
+	}
+
+	var cluster client.Object
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		cluster = &libsveltosv1beta1.SveltosCluster{}
+	} else {
+		cluster = &clusterv1.Cluster{}
+	}
+
+	key := types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}
+	if err := c.Get(ctx, key, cluster); err != nil {
+		return nil, err
+	}
+	// Copy labels as Patch updates cluster with the API server response
+	currentLabels := applyLabelPatch(cluster.GetLabels(), nil)
+
+	// ResourceVersion makes the patch fail if labels changed since profile matches were computed
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": cluster.GetResourceVersion(),
+			"labels":          labelPatch.Labels,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]client.PatchOption, 0)
+	if labelPatch.DryRun {
+		options = append(options, client.DryRunAll)
+	}
+	if err := c.Patch(ctx, cluster, client.RawPatch(types.MergePatchType, data), options...); err != nil {
+		return nil, err
+	}
+
+	newLabels := applyLabelPatch(currentLabels, labelPatch.Labels)
+
+	previousProfiles := getMatchingProfiles(profiles, clusterNamespace, currentLabels)
+	result := &LabelPatchResult{
+		DryRun:           labelPatch.DryRun,
+		Labels:           newLabels,
+		MatchingProfiles: getMatchingProfiles(profiles, clusterNamespace, newLabels),
+	}
+	result.AddedProfiles = getMissingClusterRefs(result.MatchingProfiles, previousProfiles)
+	result.RemovedProfiles = getMissingClusterRefs(previousProfiles, result.MatchingProfiles)
+
+	return result, nil
+}
+
+// applyLabelPatch returns the labels resulting from applying patch (JSON merge patch semantic) to current
+func applyLabelPatch(current map[string]string, patch map[string]*string) map[string]string {
+	result := make(map[string]string, len(current))
+	for k := range current {
+		result[k] = current[k]
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = *v
+	}
+	return result
+}
+
+// getMatchingProfiles returns the ClusterProfiles/Profiles whose ClusterSelector matches a cluster
+// with clusterLabels. Profiles only match clusters in their namespace.
+func getMatchingProfiles(profiles map[corev1.ObjectReference]ProfileInfo, clusterNamespace string,
+	clusterLabels map[string]string) []corev1.ObjectReference {
+
+	result := make([]corev1.ObjectReference, 0)
+	for k := range profiles {
+		if k.Kind == configv1beta1.ProfileKind && k.Namespace != clusterNamespace {
+			continue
+		}
+		selector := profiles[k].ClusterSelector
+		if isSelectorMatching(&selector.LabelSelector, clusterLabels) {
+			result = append(result, k)
+		}
+	}
+
+	sortClusterRefs(result)
+	return result
+}
+
+// isSelectorMatching returns true if selector matches clusterLabels. An empty selector matches
+// no cluster.
+func isSelectorMatching(selector *metav1.LabelSelector, clusterLabels map[string]string) bool {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return false
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+
+	return s.Matches(labels.Set(clusterLabels))
+}
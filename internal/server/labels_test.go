@@ -0,0 +1,147 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Labels", func() {
+	var clusterNamespace string
+	var profiles map[corev1.ObjectReference]server.ProfileInfo
+	var prodProfile, stagingProfile, otherNamespaceProfile, emptySelectorProfile corev1.ObjectReference
+
+	getSelector := func(key, value string) libsveltosv1beta1.Selector {
+		return libsveltosv1beta1.Selector{
+			LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{key: value}},
+		}
+	}
+
+	BeforeEach(func() {
+		clusterNamespace = randomString()
+
+		prodProfile = corev1.ObjectReference{
+			Kind: configv1beta1.ClusterProfileKind, Name: randomString(),
+			APIVersion: configv1beta1.GroupVersion.String(),
+		}
+		stagingProfile = corev1.ObjectReference{
+			Kind: configv1beta1.ProfileKind, Namespace: clusterNamespace, Name: randomString(),
+			APIVersion: configv1beta1.GroupVersion.String(),
+		}
+		otherNamespaceProfile = corev1.ObjectReference{
+			Kind: configv1beta1.ProfileKind, Namespace: randomString(), Name: randomString(),
+			APIVersion: configv1beta1.GroupVersion.String(),
+		}
+		emptySelectorProfile = corev1.ObjectReference{
+			Kind: configv1beta1.ClusterProfileKind, Name: randomString(),
+			APIVersion: configv1beta1.GroupVersion.String(),
+		}
+
+		profiles = map[corev1.ObjectReference]server.ProfileInfo{
+			prodProfile:           {ClusterSelector: getSelector("env", "prod")},
+			stagingProfile:        {ClusterSelector: getSelector("env", "staging")},
+			otherNamespaceProfile: {ClusterSelector: getSelector("env", "staging")},
+			emptySelectorProfile:  {},
+		}
+	})
+
+	It("getMatchingProfiles returns profiles whose ClusterSelector matches the cluster", func() {
+		Expect(server.GetMatchingProfiles(profiles, clusterNamespace, map[string]string{"env": "prod"})).To(
+			Equal([]corev1.ObjectReference{prodProfile}))
+
+		// Profiles match only clusters in their namespace
+		Expect(server.GetMatchingProfiles(profiles, clusterNamespace, map[string]string{"env": "staging"})).To(
+			Equal([]corev1.ObjectReference{stagingProfile}))
+
+		// Profiles with empty selector match no cluster
+		Expect(server.GetMatchingProfiles(profiles, clusterNamespace, nil)).To(BeEmpty())
+	})
+
+	It("patchClusterLabels reports profile match changes and honors dry-run", func() {
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "prod", "region": "eu"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sveltosCluster).Build()
+
+		labelPatch := &server.LabelPatch{
+			Labels: map[string]*string{"env": ptr.To("staging"), "region": nil},
+			DryRun: true,
+		}
+
+		result, err := server.PatchClusterLabels(context.TODO(), c, sveltosCluster.Namespace, sveltosCluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, labelPatch, profiles)
+		Expect(err).To(BeNil())
+		Expect(result.DryRun).To(BeTrue())
+		Expect(result.Labels).To(Equal(map[string]string{"env": "staging"}))
+		Expect(result.MatchingProfiles).To(Equal([]corev1.ObjectReference{stagingProfile}))
+		Expect(result.AddedProfiles).To(Equal([]corev1.ObjectReference{stagingProfile}))
+		Expect(result.RemovedProfiles).To(Equal([]corev1.ObjectReference{prodProfile}))
+
+		key := types.NamespacedName{Namespace: sveltosCluster.Namespace, Name: sveltosCluster.Name}
+		current := &libsveltosv1beta1.SveltosCluster{}
+		Expect(c.Get(context.TODO(), key, current)).To(Succeed())
+		Expect(current.Labels).To(Equal(sveltosCluster.Labels))
+
+		labelPatch.DryRun = false
+		result, err = server.PatchClusterLabels(context.TODO(), c, sveltosCluster.Namespace, sveltosCluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, labelPatch, profiles)
+		Expect(err).To(BeNil())
+		Expect(result.DryRun).To(BeFalse())
+
+		Expect(c.Get(context.TODO(), key, current)).To(Succeed())
+		Expect(current.Labels).To(Equal(map[string]string{"env": "staging"}))
+	})
+
+	It("getImpersonationConfig forwards user, groups and extra attributes", func() {
+		userInfo := &authenticationv1.UserInfo{
+			Username: randomString(),
+			UID:      randomString(),
+			Groups:   []string{randomString()},
+			Extra: map[string]authenticationv1.ExtraValue{
+				"accessKeyId": {randomString()},
+			},
+		}
+
+		impersonationConfig := server.GetImpersonationConfig(userInfo)
+		Expect(impersonationConfig.UserName).To(Equal(userInfo.Username))
+		Expect(impersonationConfig.UID).To(Equal(userInfo.UID))
+		Expect(impersonationConfig.Groups).To(Equal(userInfo.Groups))
+		Expect(impersonationConfig.Extra).To(HaveKeyWithValue("accessKeyId", []string(userInfo.Extra["accessKeyId"])))
+
+		impersonationConfig = server.GetImpersonationConfig(&authenticationv1.UserInfo{Username: randomString()})
+		Expect(impersonationConfig.Extra).To(BeNil())
+	})
+})
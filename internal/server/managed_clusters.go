@@ -18,10 +18,16 @@ package server
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
 type ManagedCluster struct {
@@ -75,6 +81,9 @@ type clusterFilters struct {
 	Namespace     string          `uri:"namespace"`
 	Name          string          `uri:"name"`
 	labelSelector labels.Selector `uri:"labels"`
+	// ConditionType and ConditionStatus, when set, return only clusters with a matching condition
+	ConditionType   string `uri:"conditionType"`
+	ConditionStatus string `uri:"conditionStatus"`
 }
 
 func getClusterFiltersFromQuery(c *gin.Context) (*clusterFilters, error) {
@@ -83,6 +92,15 @@ func getClusterFiltersFromQuery(c *gin.Context) (*clusterFilters, error) {
 	filters.Namespace = c.Query("namespace")
 	filters.Name = c.Query("name")
 	filters.labelSelector = labels.NewSelector()
+	filters.ConditionType = c.Query("conditionType")
+	filters.ConditionStatus = c.Query("conditionStatus")
+
+	switch corev1.ConditionStatus(filters.ConditionStatus) {
+	case "", corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown:
+	default:
+		return nil, fmt.Errorf("conditionStatus must be one of %q, %q, %q",
+			corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown)
+	}
 
 	lbls := c.Query("labels")
 
@@ -99,3 +117,47 @@ func getClusterFiltersFromQuery(c *gin.Context) (*clusterFilters, error) {
 
 	return &filters, nil
 }
+
+// getClusterRef returns the reference used as key for managed clusters
+func getClusterRef(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+) *corev1.ObjectReference {
+
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		return &corev1.ObjectReference{
+			Namespace:  clusterNamespace,
+			Name:       clusterName,
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+	}
+
+	return &corev1.ObjectReference{
+		Namespace:  clusterNamespace,
+		Name:       clusterName,
+		Kind:       clusterv1.ClusterKind,
+		APIVersion: clusterv1.GroupVersion.String(),
+	}
+}
+
+// normalizeClusterRef returns a cluster reference containing only the fields used as key for managed clusters
+func normalizeClusterRef(ref *corev1.ObjectReference) *corev1.ObjectReference {
+	clusterType := libsveltosv1beta1.ClusterTypeCapi
+	if ref.Kind == libsveltosv1beta1.SveltosClusterKind {
+		clusterType = libsveltosv1beta1.ClusterTypeSveltos
+	}
+	return getClusterRef(ref.Namespace, ref.Name, clusterType)
+}
+
+func isSameCluster(ref1, ref2 *corev1.ObjectReference) bool {
+	return ref1.Namespace == ref2.Namespace && ref1.Name == ref2.Name && ref1.Kind == ref2.Kind
+}
+
+func getClusterRefKey(ref *corev1.ObjectReference) string {
+	return ref.Namespace + "/" + ref.Name + "/" + ref.Kind
+}
+
+func sortClusterRefs(refs []corev1.ObjectReference) {
+	sort.Slice(refs, func(i, j int) bool {
+		return getClusterRefKey(&refs[i]) < getClusterRefKey(&refs[j])
+	})
+}
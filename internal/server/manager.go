@@ -20,9 +20,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -40,6 +43,23 @@ type ClusterInfo struct {
 	Version        string            `json:"version"`
 	Ready          bool              `json:"ready"`
 	FailureMessage *string           `json:"failureMessage"`
+
+	// Conditions are the cluster conditions. For ClusterAPI powered clusters both v1beta1 and
+	// v1beta2 conditions are reported. For SveltosClusters conditions are derived from Status.
+	Conditions []ClusterCondition `json:"conditions"`
+
+	// CAPI is set only for ClusterAPI powered clusters
+	CAPI *CAPIClusterInfo `json:"capi,omitempty"`
+
+	// Sveltos is set only for SveltosClusters
+	Sveltos *SveltosClusterInfo `json:"sveltos,omitempty"`
+
+	// Health summarizes the outcome of ClusterHealthChecks/HealthChecks evaluated on the cluster.
+	// Nil if no health check is evaluated on the cluster.
+	Health *ClusterHealthSummary `json:"health,omitempty"`
+
+	// Classifiers are the Classifiers managing labels on the cluster. Nil if none does.
+	Classifiers []ClusterClassifier `json:"classifiers,omitempty"`
 }
 
 type ClusterProfileStatus struct {
@@ -49,12 +69,16 @@ type ClusterProfileStatus struct {
 	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
 	ClusterName string                        `json:"clusterName"`
 	Summary     []ClusterFeatureSummary       `json:"summary"`
+
+	// syncMode is the sync mode of the ClusterProfile/Profile the ClusterSummary was created for
+	syncMode configv1beta1.SyncMode
 }
 
 type ClusterFeatureSummary struct {
-	FeatureID      configv1beta1.FeatureID     `json:"featureID"`
-	Status         configv1beta1.FeatureStatus `json:"status,omitempty"`
-	FailureMessage *string                     `json:"failureMessage,omitempty"`
+	FeatureID       configv1beta1.FeatureID     `json:"featureID"`
+	Status          configv1beta1.FeatureStatus `json:"status,omitempty"`
+	FailureMessage  *string                     `json:"failureMessage,omitempty"`
+	LastAppliedTime *metav1.Time                `json:"lastAppliedTime,omitempty"`
 }
 
 type ProfileInfo struct {
@@ -68,6 +92,9 @@ type ProfileInfo struct {
 
 	// Dependents is the list of ClusterProfile/Profile dependent's names
 	Dependents *libsveltosset.Set `json:"dependents"`
+
+	// Provenance is set only for ClusterProfiles generated by an EventTrigger
+	Provenance *ProfileProvenance `json:"provenance,omitempty"`
 }
 
 type instance struct {
@@ -77,12 +104,71 @@ type instance struct {
 	clusterMux         sync.RWMutex // use a Mutex to update managed Clusters
 	profileMux         sync.RWMutex // use a Mutex to update cached Profiles
 	clusterStatusesMux sync.RWMutex // mutex to update cached ClusterSummary instances
+	driftMux           sync.RWMutex // mutex to update drifts detected via ResourceSummary instances
+	clusterReportMux   sync.RWMutex // mutex to update cached ClusterReport instances
+	eventMux           sync.RWMutex // mutex to update cached EventSource/EventTrigger/EventReport instances
+	classifierMux      sync.RWMutex // mutex to update cached Classifier/ClassifierReport instances
+	healthMux          sync.RWMutex // mutex to update cached ClusterHealthCheck/HealthCheck/HealthCheckReport instances
+	roleRequestMux     sync.RWMutex // mutex to update cached RoleRequest instances
+	setMux             sync.RWMutex // mutex to update cached ClusterSet/Set instances
+	probeMux           sync.RWMutex // mutex to update cached connectivity probe results
+	bulkJobMux         sync.RWMutex // mutex to update bulk operation jobs
+	revisionMux        sync.RWMutex // mutex to update recorded ClusterProfile/Profile revisions
 	logger             logr.Logger
 
 	sveltosClusters      map[corev1.ObjectReference]ClusterInfo
 	capiClusters         map[corev1.ObjectReference]ClusterInfo
 	clusterSummaryReport map[corev1.ObjectReference]ClusterProfileStatus
 	profiles             map[corev1.ObjectReference]ProfileInfo
+
+	// resourceSummaryDrifts contains drifts keyed by the ClusterSummary the ResourceSummary was created for
+	resourceSummaryDrifts map[corev1.ObjectReference]*resourceSummaryDrift
+
+	// clusterReports contains DryRun reports keyed by ClusterReport
+	clusterReports map[corev1.ObjectReference]DryRunReport
+
+	// eventSources and eventTriggers are keyed by name (both are cluster wide resources)
+	eventSources  map[string]EventSourceInfo
+	eventTriggers map[string]EventTriggerInfo
+	eventReports  map[corev1.ObjectReference]eventReportInfo
+
+	// classifiers is keyed by name (Classifier is a cluster wide resource)
+	classifiers       map[string]ClassifierInfo
+	classifierReports map[corev1.ObjectReference]classifierReportInfo
+
+	// clusterHealthChecks and healthChecks are keyed by name (both are cluster wide resources)
+	clusterHealthChecks map[string]ClusterHealthCheckInfo
+	healthChecks        map[string]HealthCheckInfo
+	healthCheckReports  map[corev1.ObjectReference]healthCheckReportInfo
+
+	// healthHistory contains, per cluster, the last health evaluations
+	healthHistory map[corev1.ObjectReference][]HealthEvaluation
+
+	// roleRequests is keyed by name (RoleRequest is a cluster wide resource)
+	roleRequests map[string]RoleRequestInfo
+
+	// sets contains both ClusterSets and Sets
+	sets map[corev1.ObjectReference]SetInfo
+
+	// probes contains the last connectivity probe result per cluster
+	probes map[corev1.ObjectReference]*ProbeResult
+
+	// probesInFlight contains, per cluster being probed, a channel closed once the probe completes
+	probesInFlight map[corev1.ObjectReference]chan struct{}
+
+	// driftSamplingFailures contains, per cluster whose ResourceSummaries could not be read, the time
+	// after which reading them can be attempted again
+	driftSamplingFailures map[corev1.ObjectReference]time.Time
+
+	// bulkJobs contains bulk operation jobs keyed by job ID
+	bulkJobs map[string]*BulkJob
+
+	// profileRevisions contains, per ClusterProfile/Profile, the last spec revisions
+	profileRevisions map[corev1.ObjectReference][]ProfileRevision
+
+	// confirmationKey signs confirmation tokens for high-impact changes. It is generated
+	// at startup, so tokens do not survive a restart.
+	confirmationKey []byte
 }
 
 var (
@@ -99,22 +185,44 @@ func InitializeManagerInstance(ctx context.Context, config *rest.Config, c clien
 		defer lock.Unlock()
 		if managerInstance == nil {
 			managerInstance = &instance{
-				config:               config,
-				client:               c,
-				sveltosClusters:      make(map[corev1.ObjectReference]ClusterInfo),
-				capiClusters:         make(map[corev1.ObjectReference]ClusterInfo),
-				clusterSummaryReport: make(map[corev1.ObjectReference]ClusterProfileStatus),
-				profiles:             make(map[corev1.ObjectReference]ProfileInfo),
-				clusterMux:           sync.RWMutex{},
-				clusterStatusesMux:   sync.RWMutex{},
-				profileMux:           sync.RWMutex{},
-				scheme:               scheme,
-				logger:               logger,
+				config:                config,
+				client:                c,
+				sveltosClusters:       make(map[corev1.ObjectReference]ClusterInfo),
+				capiClusters:          make(map[corev1.ObjectReference]ClusterInfo),
+				clusterSummaryReport:  make(map[corev1.ObjectReference]ClusterProfileStatus),
+				profiles:              make(map[corev1.ObjectReference]ProfileInfo),
+				resourceSummaryDrifts: make(map[corev1.ObjectReference]*resourceSummaryDrift),
+				clusterReports:        make(map[corev1.ObjectReference]DryRunReport),
+				eventSources:          make(map[string]EventSourceInfo),
+				eventTriggers:         make(map[string]EventTriggerInfo),
+				eventReports:          make(map[corev1.ObjectReference]eventReportInfo),
+				classifiers:           make(map[string]ClassifierInfo),
+				classifierReports:     make(map[corev1.ObjectReference]classifierReportInfo),
+				clusterHealthChecks:   make(map[string]ClusterHealthCheckInfo),
+				healthChecks:          make(map[string]HealthCheckInfo),
+				healthCheckReports:    make(map[corev1.ObjectReference]healthCheckReportInfo),
+				healthHistory:         make(map[corev1.ObjectReference][]HealthEvaluation),
+				roleRequests:          make(map[string]RoleRequestInfo),
+				sets:                  make(map[corev1.ObjectReference]SetInfo),
+				probes:                make(map[corev1.ObjectReference]*ProbeResult),
+				probesInFlight:        make(map[corev1.ObjectReference]chan struct{}),
+				driftSamplingFailures: make(map[corev1.ObjectReference]time.Time),
+				bulkJobs:              make(map[string]*BulkJob),
+				profileRevisions:      make(map[corev1.ObjectReference][]ProfileRevision),
+				confirmationKey:       newConfirmationKey(),
+				clusterMux:            sync.RWMutex{},
+				clusterStatusesMux:    sync.RWMutex{},
+				profileMux:            sync.RWMutex{},
+				driftMux:              sync.RWMutex{},
+				scheme:                scheme,
+				logger:                logger,
 			}
 
 			go func() {
 				managerInstance.start(ctx, port, logger)
 			}()
+
+			go managerInstance.sampleDrifts(ctx)
 		}
 	}
 }
@@ -150,15 +258,8 @@ func (m *instance) GetManagedSveltosClusters(ctx context.Context, canListAll boo
 			continue
 		}
 		if ok {
-			info := ClusterInfo{
-				Labels:         sc.Labels,
-				Version:        sc.Status.Version,
-				Ready:          sc.Status.Ready,
-				FailureMessage: sc.Status.FailureMessage,
-			}
-
 			sveltosClusterInfo := getKeyFromObject(m.scheme, sc)
-			result[*sveltosClusterInfo] = info
+			result[*sveltosClusterInfo] = getSveltosClusterInfo(sc, m.getLastHealthyConnection(sveltosClusterInfo))
 		}
 	}
 
@@ -192,20 +293,51 @@ func (m *instance) GetManagedCAPIClusters(ctx context.Context, canListAll bool,
 			continue
 		}
 		if ok {
-			info := ClusterInfo{
-				Labels:         capiCluster.Labels,
-				Ready:          capiCluster.Status.ControlPlaneReady,
-				FailureMessage: examineClusterConditions(capiCluster),
-			}
-
 			capiClusterInfo := getKeyFromObject(m.scheme, capiCluster)
-			result[*capiClusterInfo] = info
+			result[*capiClusterInfo] = m.getCachedCAPIClusterInfo(capiClusterInfo, capiCluster)
 		}
 	}
 
 	return result, nil
 }
 
+// getAccessibleClusters returns all CAPI Clusters and SveltosClusters user has access to
+func (m *instance) getAccessibleClusters(ctx context.Context, user string,
+) (map[corev1.ObjectReference]ClusterInfo, error) {
+
+	canListAllCAPIClusters, err := m.canListCAPIClusters(user)
+	if err != nil {
+		return nil, err
+	}
+	capiClusters, err := m.GetManagedCAPIClusters(ctx, canListAllCAPIClusters, user)
+	if err != nil && !meta.IsNoMatchError(err) {
+		// NoMatch error means ClusterAPI is not installed
+		return nil, err
+	}
+
+	canListAllSveltosClusters, err := m.canListSveltosClusters(user)
+	if err != nil {
+		return nil, err
+	}
+	sveltosClusters, err := m.GetManagedSveltosClusters(ctx, canListAllSveltosClusters, user)
+	if err != nil {
+		return nil, err
+	}
+
+	m.clusterMux.RLock()
+	defer m.clusterMux.RUnlock()
+
+	result := make(map[corev1.ObjectReference]ClusterInfo, len(capiClusters)+len(sveltosClusters))
+	for k := range capiClusters {
+		result[k] = capiClusters[k]
+	}
+	for k := range sveltosClusters {
+		result[k] = sveltosClusters[k]
+	}
+
+	return result, nil
+}
+
 func (m *instance) GetClusterProfileStatuses() map[corev1.ObjectReference]ClusterProfileStatus {
 	m.clusterStatusesMux.RLock()
 	defer m.clusterStatusesMux.RUnlock()
@@ -234,18 +366,19 @@ func (m *instance) GetClusterProfileStatusesByCluster(
 }
 
 func (m *instance) AddSveltosCluster(sveltosCluster *libsveltosv1beta1.SveltosCluster) {
-	info := ClusterInfo{
-		Labels:         sveltosCluster.Labels,
-		Version:        sveltosCluster.Status.Version,
-		Ready:          sveltosCluster.Status.Ready,
-		FailureMessage: sveltosCluster.Status.FailureMessage,
-	}
-
 	sveltosClusterInfo := getKeyFromObject(m.scheme, sveltosCluster)
 
 	m.clusterMux.Lock()
 	defer m.clusterMux.Unlock()
 
+	// A healthy connection is observed only when the SveltosCluster is reconciled
+	lastHealthyConnection := getLastHealthyConnection(m.sveltosClusters, sveltosClusterInfo)
+	if isConnectionHealthy(sveltosCluster) {
+		now := metav1.Now()
+		lastHealthyConnection = &now
+	}
+	info := getSveltosClusterInfo(sveltosCluster, lastHealthyConnection)
+
 	delete(m.sveltosClusters, *sveltosClusterInfo)
 	m.sveltosClusters[*sveltosClusterInfo] = info
 }
@@ -257,21 +390,19 @@ func (m *instance) RemoveSveltosCluster(sveltosClusterNamespace, sveltosClusterN
 		Kind:       libsveltosv1beta1.SveltosClusterKind,
 		APIVersion: libsveltosv1beta1.GroupVersion.String(),
 	}
+	m.removeHealthHistory(sveltosClusterInfo)
+	m.removeProbe(sveltosClusterInfo)
+
 	m.clusterMux.Lock()
 	defer m.clusterMux.Unlock()
 
 	delete(m.sveltosClusters, *sveltosClusterInfo)
 }
 
-func (m *instance) AddCAPICluster(cluster *clusterv1.Cluster) {
-	info := ClusterInfo{
-		Labels:         cluster.Labels,
-		Ready:          cluster.Status.ControlPlaneReady,
-		FailureMessage: examineClusterConditions(cluster),
-	}
-	if cluster.Spec.Topology != nil {
-		info.Version = cluster.Spec.Topology.Version
-	}
+// AddCAPICluster caches a ClusterAPI powered cluster. resources contains the related
+// ClusterAPI resources (control plane, MachineDeployments and MachinePools) and can be nil.
+func (m *instance) AddCAPICluster(cluster *clusterv1.Cluster, resources *CAPIClusterResources) {
+	info := getCAPIClusterInfo(cluster, resources)
 
 	clusterInfo := getKeyFromObject(m.scheme, cluster)
 
@@ -282,6 +413,21 @@ func (m *instance) AddCAPICluster(cluster *clusterv1.Cluster) {
 	m.capiClusters[*clusterInfo] = info
 }
 
+// getCachedCAPIClusterInfo returns the cached ClusterInfo when available, as it contains
+// details collected from related ClusterAPI resources
+func (m *instance) getCachedCAPIClusterInfo(clusterRef *corev1.ObjectReference, cluster *clusterv1.Cluster,
+) ClusterInfo {
+
+	m.clusterMux.RLock()
+	defer m.clusterMux.RUnlock()
+
+	if info, ok := m.capiClusters[*clusterRef]; ok {
+		return info
+	}
+
+	return getCAPIClusterInfo(cluster, nil)
+}
+
 func (m *instance) RemoveCAPICluster(clusterNamespace, clusterName string) {
 	clusterInfo := &corev1.ObjectReference{
 		Namespace:  clusterNamespace,
@@ -289,6 +435,9 @@ func (m *instance) RemoveCAPICluster(clusterNamespace, clusterName string) {
 		Kind:       clusterv1.ClusterKind,
 		APIVersion: clusterv1.GroupVersion.String(),
 	}
+	m.removeHealthHistory(clusterInfo)
+	m.removeProbe(clusterInfo)
+
 	m.clusterMux.Lock()
 	defer m.clusterMux.Unlock()
 
@@ -317,6 +466,7 @@ func (m *instance) AddClusterProfileStatus(summary *configv1beta1.ClusterSummary
 		ClusterType: summary.Spec.ClusterType,
 		ClusterName: summary.Spec.ClusterName,
 		Summary:     clusterFeatureSummaries,
+		syncMode:    summary.Spec.ClusterProfileSpec.SyncMode,
 	}
 
 	m.clusterStatusesMux.Lock()
@@ -388,9 +538,10 @@ func MapToClusterFeatureSummaries(featureSummaries *[]configv1beta1.FeatureSumma
 	clusterFeatureSummaries := make([]ClusterFeatureSummary, 0, len(*featureSummaries))
 	for _, featureSummary := range *featureSummaries {
 		clusterFeatureSummary := ClusterFeatureSummary{
-			FeatureID:      featureSummary.FeatureID,
-			Status:         featureSummary.Status,
-			FailureMessage: featureSummary.FailureMessage,
+			FeatureID:       featureSummary.FeatureID,
+			Status:          featureSummary.Status,
+			FailureMessage:  featureSummary.FailureMessage,
+			LastAppliedTime: featureSummary.LastAppliedTime,
 		}
 		clusterFeatureSummaries = append(clusterFeatureSummaries, clusterFeatureSummary)
 	}
@@ -443,7 +594,7 @@ func (m *instance) GetProfiles(ctx context.Context, canListClusterProfiles, canL
 }
 
 func (m *instance) AddProfile(profile *corev1.ObjectReference, selector libsveltosv1beta1.Selector,
-	tier int32, dependencies *libsveltosset.Set) {
+	tier int32, dependencies *libsveltosset.Set, provenance *ProfileProvenance) {
 
 	if dependencies == nil {
 		dependencies = &libsveltosset.Set{}
@@ -471,6 +622,7 @@ func (m *instance) AddProfile(profile *corev1.ObjectReference, selector libsvelt
 		ClusterSelector: selector,
 		Dependencies:    dependencies,
 		Dependents:      profileInfo.Dependents,
+		Provenance:      provenance,
 	}
 }
 
@@ -489,6 +641,8 @@ func (m *instance) RemoveProfile(profile *corev1.ObjectReference) {
 	}
 
 	delete(m.profiles, *profile)
+
+	m.removeProfileRevisions(profile)
 }
 
 func (m *instance) GetProfile(profile *corev1.ObjectReference) ProfileInfo {
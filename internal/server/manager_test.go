@@ -192,6 +192,8 @@ var _ = Describe("Manager", func() {
 			Ready:          sveltosCluster.Status.Ready,
 			Version:        sveltosCluster.Status.Version,
 			FailureMessage: sveltosCluster.Status.FailureMessage,
+			Conditions:     server.GetSveltosClusterConditions(sveltosCluster),
+			Sveltos:        &server.SveltosClusterInfo{},
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -208,6 +210,41 @@ var _ = Describe("Manager", func() {
 		Expect(reflect.DeepEqual(v, clusterInfo)).To(BeTrue())
 	})
 
+	It("AddSveltosCluster records the last healthy connection", func() {
+		clusterRef := &corev1.ObjectReference{
+			Namespace:  sveltosCluster.Namespace,
+			Name:       sveltosCluster.Name,
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		server.InitializeManagerInstance(ctx, nil, c, scheme, randomPort(), logger)
+		manager := server.GetManagerInstance()
+
+		sveltosCluster.Status.ConnectionStatus = libsveltosv1beta1.ConnectionHealthy
+		manager.AddSveltosCluster(sveltosCluster)
+
+		clusters, err := manager.GetManagedSveltosClusters(context.TODO(), true, randomString())
+		Expect(err).To(BeNil())
+		lastHealthyConnection := clusters[*clusterRef].Sveltos.LastHealthyConnection
+		Expect(lastHealthyConnection).ToNot(BeNil())
+
+		// Reads return the recorded value
+		clusters, err = manager.GetManagedSveltosClusters(context.TODO(), true, randomString())
+		Expect(err).To(BeNil())
+		Expect(clusters[*clusterRef].Sveltos.LastHealthyConnection).To(Equal(lastHealthyConnection))
+
+		// Connection is down, last healthy connection is kept
+		sveltosCluster.Status.ConnectionStatus = libsveltosv1beta1.ConnectionDown
+		manager.AddSveltosCluster(sveltosCluster)
+		clusters, err = manager.GetManagedSveltosClusters(context.TODO(), true, randomString())
+		Expect(err).To(BeNil())
+		Expect(clusters[*clusterRef].Sveltos.LastHealthyConnection).To(Equal(lastHealthyConnection))
+	})
+
 	It("RemoveSveltosCluster removes SveltosCluster from list of managed clusters", func() {
 		clusterRef := &corev1.ObjectReference{
 			Namespace:  sveltosCluster.Namespace,
@@ -255,6 +292,12 @@ var _ = Describe("Manager", func() {
 			Ready:          cluster.Status.ControlPlaneReady,
 			Version:        cluster.Spec.Topology.Version,
 			FailureMessage: server.ExamineClusterConditions(cluster),
+			Conditions:     server.GetCAPIClusterConditions(cluster),
+			CAPI: &server.CAPIClusterInfo{
+				Phase:              cluster.Status.Phase,
+				MachineDeployments: []server.MachineDeploymentInfo{},
+				MachinePools:       []server.MachinePoolInfo{},
+			},
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -262,7 +305,7 @@ var _ = Describe("Manager", func() {
 
 		server.InitializeManagerInstance(ctx, nil, c, scheme, randomPort(), logger)
 		manager := server.GetManagerInstance()
-		manager.AddCAPICluster(cluster)
+		manager.AddCAPICluster(cluster, nil)
 
 		clusters, err := manager.GetManagedCAPIClusters(context.TODO(), true, randomString())
 		Expect(err).To(BeNil())
@@ -284,7 +327,7 @@ var _ = Describe("Manager", func() {
 
 		server.InitializeManagerInstance(ctx, nil, c, scheme, randomPort(), logger)
 		manager := server.GetManagerInstance()
-		manager.AddCAPICluster(cluster)
+		manager.AddCAPICluster(cluster, nil)
 
 		clusters, err := manager.GetManagedCAPIClusters(context.TODO(), true, randomString())
 		Expect(err).To(BeNil())
@@ -424,7 +467,7 @@ var _ = Describe("Manager", func() {
 
 		// test it has been added
 		tier := int32(10)
-		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil)
+		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil, nil)
 
 		profiles, err := manager.GetProfiles(context.TODO(), true, true, randomString())
 		Expect(err).To(BeNil())
@@ -447,7 +490,7 @@ var _ = Describe("Manager", func() {
 		// Make profile1 depend on profile
 		dependecies.Insert(profile0) // profile1 depends on profile0
 
-		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies)
+		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies, nil)
 
 		profiles, err = manager.GetProfiles(context.TODO(), true, true, randomString())
 		Expect(err).To(BeNil())
@@ -467,7 +510,7 @@ var _ = Describe("Manager", func() {
 		Expect(profileInfo.Dependents.Len()).To(Equal(0))
 
 		// Remove profile1 dependency on profile0
-		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, nil)
+		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, nil, nil)
 
 		profiles, err = manager.GetProfiles(context.TODO(), true, true, randomString())
 		Expect(err).To(BeNil())
@@ -503,7 +546,7 @@ var _ = Describe("Manager", func() {
 
 		// test it has been added
 		tier := int32(10)
-		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil)
+		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil, nil)
 
 		profile1 := &corev1.ObjectReference{
 			Namespace:  namespace,
@@ -514,7 +557,7 @@ var _ = Describe("Manager", func() {
 		dependecies := &libsveltosset.Set{}
 		// Make profile1 depend on profile0
 		dependecies.Insert(profile0) // profile1 depends on profile0
-		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies)
+		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies, nil)
 
 		profile2 := &corev1.ObjectReference{
 			Namespace:  namespace,
@@ -525,7 +568,7 @@ var _ = Describe("Manager", func() {
 		dependecies = &libsveltosset.Set{}
 		// Make profile2 depend on profile1
 		dependecies.Insert(profile1) // profile2 depends on profile1
-		manager.AddProfile(profile2, libsveltosv1beta1.Selector{}, tier, dependecies)
+		manager.AddProfile(profile2, libsveltosv1beta1.Selector{}, tier, dependecies, nil)
 
 		profiles, err := manager.GetProfiles(context.TODO(), true, true, randomString())
 		Expect(err).To(BeNil())
@@ -570,7 +613,7 @@ var _ = Describe("Manager", func() {
 
 		// test it has been added
 		tier := int32(10)
-		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil)
+		manager.AddProfile(profile0, libsveltosv1beta1.Selector{}, tier, nil, nil)
 
 		profile1 := &corev1.ObjectReference{
 			Namespace:  namespace,
@@ -581,7 +624,7 @@ var _ = Describe("Manager", func() {
 		dependecies := &libsveltosset.Set{}
 		// Make profile1 depend on profile0
 		dependecies.Insert(profile0) // profile1 depends on profile0
-		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies)
+		manager.AddProfile(profile1, libsveltosv1beta1.Selector{}, tier, dependecies, nil)
 
 		profiles, err := manager.GetProfiles(context.TODO(), true, true, randomString())
 		Expect(err).To(BeNil())
@@ -0,0 +1,151 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// pausedByAnnotation and pauseReasonAnnotation record who paused a cluster and why
+	pausedByAnnotation    = "ui.projectsveltos.io/paused-by"
+	pauseReasonAnnotation = "ui.projectsveltos.io/pause-reason"
+
+	// resumedByAnnotation and resumeReasonAnnotation record who resumed a cluster and why
+	resumedByAnnotation    = "ui.projectsveltos.io/resumed-by"
+	resumeReasonAnnotation = "ui.projectsveltos.io/resume-reason"
+)
+
+// PauseRequest is the body of pause/resume requests
+type PauseRequest struct {
+	// Reason is required when pausing a cluster
+	Reason string `json:"reason"`
+
+	// PauseClusterAPI must be true to pause a ClusterAPI powered cluster: Spec.Paused
+	// also stops ClusterAPI from reconciling the cluster
+	PauseClusterAPI bool `json:"pauseClusterAPI,omitempty"`
+}
+
+type PauseResult struct {
+	Namespace   string                        `json:"namespace"`
+	Name        string                        `json:"name"`
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType"`
+	Paused      bool                          `json:"paused"`
+
+	// ClusterAPIPaused is true when ClusterAPI reconciliation of the cluster is paused as well
+	ClusterAPIPaused bool `json:"clusterAPIPaused,omitempty"`
+
+	// Warning describes the side effects of pausing the cluster
+	Warning string `json:"warning,omitempty"`
+}
+
+// validateClusterAPIPause verifies pausing a ClusterAPI powered cluster was explicitly requested.
+// Setting Spec.Paused on a ClusterAPI Cluster does not only stop Sveltos from deploying add-ons,
+// it also stops ClusterAPI controllers from reconciling the cluster.
+func validateClusterAPIPause(clusterType libsveltosv1beta1.ClusterType, paused, pauseClusterAPI bool) error {
+	if paused && clusterType == libsveltosv1beta1.ClusterTypeCapi && !pauseClusterAPI {
+		return errors.New("pausing a ClusterAPI powered cluster also pauses ClusterAPI reconciliation: " +
+			"set pauseClusterAPI to true to confirm")
+	}
+
+	return nil
+}
+
+// getPauseResult returns the outcome of pausing/resuming a cluster
+func getPauseResult(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+	paused bool) *PauseResult {
+
+	result := &PauseResult{
+		Namespace:   clusterNamespace,
+		Name:        clusterName,
+		ClusterType: clusterType,
+		Paused:      paused,
+	}
+	if paused && clusterType == libsveltosv1beta1.ClusterTypeCapi {
+		result.ClusterAPIPaused = true
+		result.Warning = "ClusterAPI controllers do not reconcile this cluster (scaling, upgrades, " +
+			"remediation) until it is resumed"
+	}
+
+	return result
+}
+
+// setClusterPaused sets Spec.Paused on a SveltosCluster or ClusterAPI Cluster and records
+// user and reason in annotations. Sveltos does not deploy add-ons to paused clusters.
+func (m *instance) setClusterPaused(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, paused bool, user, reason string) error {
+
+	key := types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}
+
+	var cluster client.Object
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		cluster = &libsveltosv1beta1.SveltosCluster{}
+	} else {
+		cluster = &clusterv1.Cluster{}
+	}
+
+	if err := m.client.Get(ctx, key, cluster); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopyObject().(client.Object))
+
+	switch c := cluster.(type) {
+	case *libsveltosv1beta1.SveltosCluster:
+		c.Spec.Paused = paused
+	case *clusterv1.Cluster:
+		// Sveltos considers a ClusterAPI Cluster paused when Spec.Paused is set. This pauses
+		// ClusterAPI controllers as well.
+		c.Spec.Paused = paused
+	}
+	setPauseAnnotations(cluster, paused, user, reason)
+
+	return m.client.Patch(ctx, cluster, patch)
+}
+
+// setPauseAnnotations records who paused/resumed a cluster and why. Annotations of
+// the previous pause/resume are removed.
+func setPauseAnnotations(obj metav1.Object, paused bool, user, reason string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	byAnnotation, reasonAnnotation := pausedByAnnotation, pauseReasonAnnotation
+	delete(annotations, resumedByAnnotation)
+	delete(annotations, resumeReasonAnnotation)
+	if !paused {
+		byAnnotation, reasonAnnotation = resumedByAnnotation, resumeReasonAnnotation
+		delete(annotations, pausedByAnnotation)
+		delete(annotations, pauseReasonAnnotation)
+	}
+
+	annotations[byAnnotation] = user
+	if reason != "" {
+		annotations[reasonAnnotation] = reason
+	}
+
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Pause", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+	})
+
+	It("setPauseAnnotations records user and reason", func() {
+		user := randomString()
+		reason := randomString()
+
+		cluster := &libsveltosv1beta1.SveltosCluster{}
+		server.SetPauseAnnotations(cluster, true, user, reason)
+		Expect(cluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/paused-by", user))
+		Expect(cluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/pause-reason", reason))
+
+		// Resume without reason removes pause annotations
+		server.SetPauseAnnotations(cluster, false, user, "")
+		Expect(cluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/resumed-by", user))
+		Expect(cluster.Annotations).ToNot(HaveKey("ui.projectsveltos.io/resume-reason"))
+		Expect(cluster.Annotations).ToNot(HaveKey("ui.projectsveltos.io/paused-by"))
+		Expect(cluster.Annotations).ToNot(HaveKey("ui.projectsveltos.io/pause-reason"))
+	})
+
+	It("validateClusterAPIPause requires pauseClusterAPI to pause ClusterAPI powered clusters", func() {
+		Expect(server.ValidateClusterAPIPause(libsveltosv1beta1.ClusterTypeCapi, true, false)).ToNot(Succeed())
+		Expect(server.ValidateClusterAPIPause(libsveltosv1beta1.ClusterTypeCapi, true, true)).To(Succeed())
+		Expect(server.ValidateClusterAPIPause(libsveltosv1beta1.ClusterTypeCapi, false, false)).To(Succeed())
+		Expect(server.ValidateClusterAPIPause(libsveltosv1beta1.ClusterTypeSveltos, true, false)).To(Succeed())
+
+		result := server.GetPauseResult(randomString(), randomString(), libsveltosv1beta1.ClusterTypeCapi, true)
+		Expect(result.ClusterAPIPaused).To(BeTrue())
+		Expect(result.Warning).ToNot(BeEmpty())
+
+		result = server.GetPauseResult(randomString(), randomString(), libsveltosv1beta1.ClusterTypeSveltos, true)
+		Expect(result.ClusterAPIPaused).To(BeFalse())
+		Expect(result.Warning).To(BeEmpty())
+	})
+
+	It("setClusterPaused pauses and resumes SveltosClusters and ClusterAPI Clusters", func() {
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   randomString(),
+				Name:        randomString(),
+				Annotations: map[string]string{randomString(): randomString()},
+			},
+		}
+		cluster := createTestCAPICluster(randomString(), randomString())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sveltosCluster, cluster).Build()
+		manager := server.NewManagerInstance(c, scheme, logger)
+
+		user := randomString()
+		reason := randomString()
+
+		Expect(manager.SetClusterPaused(context.TODO(), sveltosCluster.Namespace, sveltosCluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, true, user, reason)).To(Succeed())
+
+		currentSveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: sveltosCluster.Namespace, Name: sveltosCluster.Name},
+			currentSveltosCluster)).To(Succeed())
+		Expect(currentSveltosCluster.Spec.Paused).To(BeTrue())
+		Expect(currentSveltosCluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/paused-by", user))
+		Expect(currentSveltosCluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/pause-reason", reason))
+		// Existing annotations are preserved
+		Expect(len(currentSveltosCluster.Annotations)).To(Equal(3))
+
+		Expect(manager.SetClusterPaused(context.TODO(), cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeCapi, true, user, reason)).To(Succeed())
+		currentCluster := &clusterv1.Cluster{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name},
+			currentCluster)).To(Succeed())
+		Expect(currentCluster.Spec.Paused).To(BeTrue())
+
+		Expect(manager.SetClusterPaused(context.TODO(), cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeCapi, false, user, reason)).To(Succeed())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name},
+			currentCluster)).To(Succeed())
+		Expect(currentCluster.Spec.Paused).To(BeFalse())
+		Expect(currentCluster.Annotations).To(HaveKeyWithValue("ui.projectsveltos.io/resumed-by", user))
+		Expect(currentCluster.Annotations).ToNot(HaveKey("ui.projectsveltos.io/paused-by"))
+
+		err := manager.SetClusterPaused(context.TODO(), randomString(), randomString(),
+			libsveltosv1beta1.ClusterTypeSveltos, true, user, reason)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
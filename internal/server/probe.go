@@ -0,0 +1,279 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+const (
+	// probeCacheTTL is how long a probe result is reused before the cluster is probed again
+	probeCacheTTL = 30 * time.Second
+
+	// probeTimeout is the maximum time a single request to the managed cluster can take
+	probeTimeout = 10 * time.Second
+)
+
+// ProbeResult is the outcome of a connectivity probe against a managed cluster
+type ProbeResult struct {
+	ProbeTime metav1.Time `json:"probeTime"`
+
+	// Cached is true when the result of a recent probe is returned
+	Cached bool `json:"cached"`
+
+	// Reachable is true if the API server answered
+	Reachable bool `json:"reachable"`
+
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// LatencyMilliseconds is the time taken by the API server to answer the version request
+	LatencyMilliseconds int64 `json:"latencyMilliseconds"`
+
+	// CredentialsAccepted is nil when it could not be determined
+	CredentialsAccepted *bool `json:"credentialsAccepted,omitempty"`
+
+	// ServerCertificateExpiration is the expiration of the certificate presented by the API server
+	ServerCertificateExpiration *metav1.Time `json:"serverCertificateExpiration,omitempty"`
+
+	// ClientCertificateExpiration is set only if the kubeconfig authenticates with a client certificate
+	ClientCertificateExpiration *metav1.Time `json:"clientCertificateExpiration,omitempty"`
+
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+// probeCluster probes a managed cluster using the kubeconfig Sveltos uses to manage it.
+// A result younger than probeCacheTTL is returned instead of probing the cluster again.
+// Concurrent calls for the same cluster share a single probe.
+func (m *instance) probeCluster(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *ProbeResult {
+
+	clusterRef := getClusterRef(clusterNamespace, clusterName, clusterType)
+
+	m.probeMux.Lock()
+	cached, ok := m.probes[*clusterRef]
+	if ok && time.Since(cached.ProbeTime.Time) < probeCacheTTL {
+		result := *cached
+		m.probeMux.Unlock()
+		result.Cached = true
+		return &result
+	}
+
+	if inFlight, ok := m.probesInFlight[*clusterRef]; ok {
+		m.probeMux.Unlock()
+		return m.waitForProbe(ctx, clusterRef, inFlight)
+	}
+
+	done := make(chan struct{})
+	m.probesInFlight[*clusterRef] = done
+	m.probeMux.Unlock()
+
+	// The probe is shared with concurrent callers, so it must not be cancelled when the
+	// request of the caller running it goes away.
+	probeCtx, cancel := context.WithTimeout(context.Background(), 2*probeTimeout)
+	defer cancel()
+
+	var result *ProbeResult
+	restConfig, err := clusterproxy.GetKubernetesRestConfig(probeCtx, m.client, clusterNamespace, clusterName,
+		"", "", clusterType, m.logger)
+	if err != nil {
+		msg := fmt.Sprintf("failed to get kubeconfig: %v", err)
+		result = &ProbeResult{ProbeTime: metav1.Now(), FailureMessage: &msg}
+	} else {
+		result = probeRestConfig(probeCtx, restConfig)
+	}
+
+	m.probeMux.Lock()
+	defer m.probeMux.Unlock()
+	m.probes[*clusterRef] = result
+	delete(m.probesInFlight, *clusterRef)
+	close(done)
+
+	return result
+}
+
+// waitForProbe waits for the probe in progress for the cluster and returns its result
+func (m *instance) waitForProbe(ctx context.Context, clusterRef *corev1.ObjectReference,
+	inFlight chan struct{}) *ProbeResult {
+
+	select {
+	case <-inFlight:
+	case <-ctx.Done():
+		msg := fmt.Sprintf("probe in progress did not complete: %v", ctx.Err())
+		return &ProbeResult{ProbeTime: metav1.Now(), FailureMessage: &msg}
+	}
+
+	m.probeMux.RLock()
+	defer m.probeMux.RUnlock()
+
+	cached, ok := m.probes[*clusterRef]
+	if !ok {
+		// Removed while probing
+		msg := "cluster was removed while being probed"
+		return &ProbeResult{ProbeTime: metav1.Now(), FailureMessage: &msg}
+	}
+	result := *cached
+	result.Cached = true
+	return &result
+}
+
+// removeProbe removes the cached probe result for a cluster
+func (m *instance) removeProbe(clusterRef *corev1.ObjectReference) {
+	m.probeMux.Lock()
+	defer m.probeMux.Unlock()
+
+	delete(m.probes, *clusterRef)
+}
+
+// probeRestConfig requests the API server version (reachability, latency, version and
+// server certificate) and then the core API discovery (credentials).
+func probeRestConfig(ctx context.Context, restConfig *rest.Config) *ProbeResult {
+	result := &ProbeResult{ProbeTime: metav1.Now()}
+
+	setFailure := func(msg string) *ProbeResult {
+		result.FailureMessage = &msg
+		return result
+	}
+
+	config := rest.CopyConfig(restConfig)
+	config.Timeout = probeTimeout
+
+	result.ClientCertificateExpiration = getClientCertificateExpiration(config)
+
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return setFailure(fmt.Sprintf("failed to create client: %v", err))
+	}
+
+	baseURL, _, err := rest.DefaultServerUrlFor(config)
+	if err != nil {
+		return setFailure(fmt.Sprintf("invalid server url: %v", err))
+	}
+
+	start := time.Now()
+	resp, err := doProbeRequest(ctx, httpClient, baseURL.JoinPath("version").String())
+	result.LatencyMilliseconds = time.Since(start).Milliseconds()
+	if err != nil {
+		return setFailure(fmt.Sprintf("API server not reachable: %v", err))
+	}
+	result.Reachable = true
+
+	if resp.tlsState != nil && len(resp.tlsState.PeerCertificates) > 0 {
+		result.ServerCertificateExpiration = &metav1.Time{Time: resp.tlsState.PeerCertificates[0].NotAfter}
+	}
+
+	if resp.statusCode == http.StatusOK {
+		info := &version.Info{}
+		if err := json.Unmarshal(resp.body, info); err == nil {
+			result.ServerVersion = info.GitVersion
+		}
+	}
+
+	// Version is usually readable by unauthenticated users, while core API discovery is not
+	resp, err = doProbeRequest(ctx, httpClient, baseURL.JoinPath("api").String())
+	if err != nil {
+		return setFailure(fmt.Sprintf("failed to verify credentials: %v", err))
+	}
+
+	switch resp.statusCode {
+	case http.StatusOK:
+		result.CredentialsAccepted = ptr.To(true)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		result.CredentialsAccepted = ptr.To(false)
+		return setFailure(fmt.Sprintf("credentials rejected by API server: %s", http.StatusText(resp.statusCode)))
+	default:
+		return setFailure(fmt.Sprintf("failed to verify credentials: %s", http.StatusText(resp.statusCode)))
+	}
+
+	return result
+}
+
+type probeResponse struct {
+	statusCode int
+	body       []byte
+	tlsState   *tls.ConnectionState
+}
+
+func doProbeRequest(ctx context.Context, httpClient *http.Client, url string) (*probeResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &probeResponse{statusCode: resp.StatusCode, body: body, tlsState: resp.TLS}, nil
+}
+
+// getClientCertificateExpiration returns the expiration of the client certificate, if any
+func getClientCertificateExpiration(config *rest.Config) *metav1.Time {
+	certData := config.CertData
+	if len(certData) == 0 && config.CertFile != "" {
+		var err error
+		certData, err = os.ReadFile(config.CertFile)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return getCertificateExpiration(certData)
+}
+
+// getCertificateExpiration returns the expiration of the first PEM encoded certificate in data
+func getCertificateExpiration(data []byte) *metav1.Time {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		return &metav1.Time{Time: cert.NotAfter}
+	}
+}
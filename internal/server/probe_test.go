@@ -0,0 +1,173 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Probe", func() {
+	var logger logr.Logger
+	var apiServer *httptest.Server
+	const validToken = "valid-token"
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"major": "1", "minor": "32", "gitVersion": "v1.32.2"}`))
+		})
+		mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"kind": "APIVersions", "versions": ["v1"]}`))
+		})
+		apiServer = httptest.NewTLSServer(mux)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	getRestConfig := func(token string) *rest.Config {
+		return &rest.Config{
+			Host:        apiServer.URL,
+			BearerToken: token,
+			TLSClientConfig: rest.TLSClientConfig{
+				CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: apiServer.Certificate().Raw}),
+			},
+		}
+	}
+
+	It("probeRestConfig reports reachability, version, certificate expiration and accepted credentials", func() {
+		result := server.ProbeRestConfig(context.TODO(), getRestConfig(validToken))
+		Expect(result.FailureMessage).To(BeNil())
+		Expect(result.Reachable).To(BeTrue())
+		Expect(result.ServerVersion).To(Equal("v1.32.2"))
+		Expect(result.CredentialsAccepted).ToNot(BeNil())
+		Expect(*result.CredentialsAccepted).To(BeTrue())
+		Expect(result.ServerCertificateExpiration).ToNot(BeNil())
+		Expect(result.ServerCertificateExpiration.Time.Equal(apiServer.Certificate().NotAfter)).To(BeTrue())
+		Expect(result.ClientCertificateExpiration).To(BeNil())
+	})
+
+	It("probeRestConfig reports rejected credentials", func() {
+		result := server.ProbeRestConfig(context.TODO(), getRestConfig(randomString()))
+		Expect(result.Reachable).To(BeTrue())
+		Expect(result.CredentialsAccepted).ToNot(BeNil())
+		Expect(*result.CredentialsAccepted).To(BeFalse())
+		Expect(result.FailureMessage).ToNot(BeNil())
+	})
+
+	It("probeRestConfig reports unreachable API server", func() {
+		config := getRestConfig(validToken)
+		apiServer.Close()
+
+		result := server.ProbeRestConfig(context.TODO(), config)
+		Expect(result.Reachable).To(BeFalse())
+		Expect(result.CredentialsAccepted).To(BeNil())
+		Expect(result.FailureMessage).ToNot(BeNil())
+	})
+
+	It("getCertificateExpiration returns expiration of PEM encoded certificate", func() {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: apiServer.Certificate().Raw})
+		expiration := server.GetCertificateExpiration(data)
+		Expect(expiration).ToNot(BeNil())
+		Expect(expiration.Time.Equal(apiServer.Certificate().NotAfter)).To(BeTrue())
+
+		Expect(server.GetCertificateExpiration([]byte(randomString()))).To(BeNil())
+	})
+
+	It("probeCluster caches results", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		manager := server.NewManagerInstance(c, scheme, logger)
+
+		namespace := randomString()
+		name := randomString()
+
+		// Kubeconfig does not exist
+		result := manager.ProbeCluster(context.TODO(), namespace, name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(result.Cached).To(BeFalse())
+		Expect(result.Reachable).To(BeFalse())
+		Expect(result.FailureMessage).ToNot(BeNil())
+
+		cached := manager.ProbeCluster(context.TODO(), namespace, name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(cached.Cached).To(BeTrue())
+		Expect(cached.ProbeTime).To(Equal(result.ProbeTime))
+	})
+
+	It("probeCluster shares a probe in progress with concurrent callers", func() {
+		var gets atomic.Int32
+		c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object,
+				opts ...client.GetOption) error {
+
+				if _, ok := obj.(*libsveltosv1beta1.SveltosCluster); ok {
+					gets.Add(1)
+					// Keep the probe in progress while the other callers arrive
+					time.Sleep(100 * time.Millisecond)
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+		manager := server.NewManagerInstance(c, scheme, logger)
+
+		namespace := randomString()
+		name := randomString()
+
+		const callers = 5
+		results := make([]*server.ProbeResult, callers)
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				results[i] = manager.ProbeCluster(context.TODO(), namespace, name, libsveltosv1beta1.ClusterTypeSveltos)
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(gets.Load()).To(Equal(int32(1)))
+		for i := range results {
+			Expect(results[i].FailureMessage).ToNot(BeNil())
+			Expect(results[i].ProbeTime).To(Equal(results[0].ProbeTime))
+		}
+	})
+})
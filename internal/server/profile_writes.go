@@ -0,0 +1,308 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// ValidationError describes why a ClusterProfile/Profile was rejected
+type ValidationError struct {
+	// Field is the path of the invalid field, if known (e.g. spec.clusterSelector)
+	Field string `json:"field,omitempty"`
+
+	// Type is the kind of validation failure (e.g. FieldValueRequired)
+	Type string `json:"type,omitempty"`
+
+	Message string `json:"message"`
+}
+
+type ProfileChangeResult struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	// DryRun is true when the change was only validated
+	DryRun bool `json:"dryRun"`
+
+	// Applied is true when the change was persisted
+	Applied bool `json:"applied"`
+
+	// ValidationErrors lists why the change was rejected. When not empty, nothing was applied.
+	ValidationErrors []ValidationError `json:"validationErrors,omitempty"`
+
+	// Dependents are the ClusterProfiles/Profiles depending on the deleted profile the user can get
+	Dependents []corev1.ObjectReference `json:"dependents,omitempty"`
+
+	// ForceRequired is true when the profile was not deleted because other profiles depend on it
+	ForceRequired bool `json:"forceRequired,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// getDryRunFromQuery returns true if the dryRun query parameter is set to true
+func getDryRunFromQuery(c *gin.Context) bool {
+	dryRun, err := strconv.ParseBool(c.Query("dryRun"))
+	if err != nil {
+		return false
+	}
+	return dryRun
+}
+
+// getForceFromQuery returns true if the force query parameter is set to true
+func getForceFromQuery(c *gin.Context) bool {
+	force, err := strconv.ParseBool(c.Query("force"))
+	if err != nil {
+		return false
+	}
+	return force
+}
+
+// decodeProfile decodes a ClusterProfile/Profile from its YAML or JSON representation.
+// Unknown fields are reported as validation errors.
+func decodeProfile(data []byte) (client.Object, []ValidationError) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, typeMeta); err != nil {
+		return nil, []ValidationError{{Message: err.Error()}}
+	}
+
+	if typeMeta.APIVersion != configv1beta1.GroupVersion.String() {
+		return nil, []ValidationError{{Field: "apiVersion", Type: string(metav1.CauseTypeFieldValueNotSupported),
+			Message: fmt.Sprintf("supported apiVersion is %q", configv1beta1.GroupVersion.String())}}
+	}
+
+	var profile client.Object
+	switch typeMeta.Kind {
+	case configv1beta1.ClusterProfileKind:
+		profile = &configv1beta1.ClusterProfile{}
+	case configv1beta1.ProfileKind:
+		profile = &configv1beta1.Profile{}
+	default:
+		return nil, []ValidationError{{Field: "kind", Type: string(metav1.CauseTypeFieldValueNotSupported),
+			Message: fmt.Sprintf("supported kinds are %q and %q",
+				configv1beta1.ClusterProfileKind, configv1beta1.ProfileKind)}}
+	}
+
+	if err := yaml.UnmarshalStrict(data, profile); err != nil {
+		return nil, []ValidationError{{Message: err.Error()}}
+	}
+
+	var validationErrors []ValidationError
+	if profile.GetName() == "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "metadata.name",
+			Type: string(metav1.CauseTypeFieldValueRequired), Message: "name is required"})
+	}
+	if typeMeta.Kind == configv1beta1.ProfileKind && profile.GetNamespace() == "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "metadata.namespace",
+			Type: string(metav1.CauseTypeFieldValueRequired), Message: "namespace is required for Profile"})
+	}
+	if typeMeta.Kind == configv1beta1.ClusterProfileKind && profile.GetNamespace() != "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "metadata.namespace",
+			Type: string(metav1.CauseTypeFieldValueInvalid), Message: "ClusterProfile is cluster scoped"})
+	}
+
+	if validationErrors != nil {
+		return nil, validationErrors
+	}
+
+	return profile, nil
+}
+
+// getValidationErrors returns the validation errors reported by the API server. Returns nil if
+// err is not a validation failure.
+func getValidationErrors(err error) []ValidationError {
+	if !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err) {
+		return nil
+	}
+
+	var validationErrors []ValidationError
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) && apiStatus.Status().Details != nil {
+		for _, cause := range apiStatus.Status().Details.Causes {
+			validationErrors = append(validationErrors, ValidationError{
+				Field:   cause.Field,
+				Type:    string(cause.Type),
+				Message: cause.Message,
+			})
+		}
+	}
+
+	if validationErrors == nil {
+		validationErrors = []ValidationError{{Message: err.Error()}}
+	}
+
+	return validationErrors
+}
+
+func newProfileChangeResult(profile client.Object, dryRun bool) *ProfileChangeResult {
+	return &ProfileChangeResult{
+		Kind:      profile.GetObjectKind().GroupVersionKind().Kind,
+		Namespace: profile.GetNamespace(),
+		Name:      profile.GetName(),
+		DryRun:    dryRun,
+	}
+}
+
+// applyProfileChange runs change with a server-side dry-run first and then, unless dryRun is
+// set, for real. Validation failures are reported in the result.
+func applyProfileChange(result *ProfileChangeResult, dryRun bool,
+	change func(dryRun bool) error) (*ProfileChangeResult, error) {
+
+	if err := change(true); err != nil {
+		if validationErrors := getValidationErrors(err); validationErrors != nil {
+			result.ValidationErrors = validationErrors
+			return result, nil
+		}
+		return nil, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := change(false); err != nil {
+		if validationErrors := getValidationErrors(err); validationErrors != nil {
+			result.ValidationErrors = validationErrors
+			return result, nil
+		}
+		return nil, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// createProfileObject creates profile using client c, which impersonates the calling user.
+func createProfileObject(ctx context.Context, c client.Client, profile client.Object, dryRun bool,
+) (*ProfileChangeResult, error) {
+
+	result := newProfileChangeResult(profile, dryRun)
+
+	return applyProfileChange(result, dryRun, func(dryRun bool) error {
+		var createOptions []client.CreateOption
+		if dryRun {
+			createOptions = append(createOptions, client.DryRunAll)
+		}
+		return c.Create(ctx, profile.DeepCopyObject().(client.Object), createOptions...)
+	})
+}
+
+// updateProfileObject replaces spec (and labels/annotations, if set) of an existing ClusterProfile/Profile
+// with the ones in profile, using client c, which impersonates the calling user.
+// If profile has a resourceVersion, the update fails should the profile have changed in the meantime.
+func updateProfileObject(ctx context.Context, c client.Client, profile client.Object, dryRun bool,
+) (*ProfileChangeResult, error) {
+
+	result := newProfileChangeResult(profile, dryRun)
+
+	key := types.NamespacedName{Namespace: profile.GetNamespace(), Name: profile.GetName()}
+
+	var current client.Object
+	switch requested := profile.(type) {
+	case *configv1beta1.ClusterProfile:
+		clusterProfile := &configv1beta1.ClusterProfile{}
+		if err := c.Get(ctx, key, clusterProfile); err != nil {
+			return nil, err
+		}
+		clusterProfile.Spec = requested.Spec
+		current = clusterProfile
+	case *configv1beta1.Profile:
+		p := &configv1beta1.Profile{}
+		if err := c.Get(ctx, key, p); err != nil {
+			return nil, err
+		}
+		p.Spec = requested.Spec
+		current = p
+	default:
+		return nil, fmt.Errorf("unsupported type %T", profile)
+	}
+
+	if profile.GetLabels() != nil {
+		current.SetLabels(profile.GetLabels())
+	}
+	if profile.GetAnnotations() != nil {
+		current.SetAnnotations(profile.GetAnnotations())
+	}
+	if profile.GetResourceVersion() != "" {
+		current.SetResourceVersion(profile.GetResourceVersion())
+	}
+
+	return applyProfileChange(result, dryRun, func(dryRun bool) error {
+		var updateOptions []client.UpdateOption
+		if dryRun {
+			updateOptions = append(updateOptions, client.DryRunAll)
+		}
+		return c.Update(ctx, current.DeepCopyObject().(client.Object), updateOptions...)
+	})
+}
+
+// deleteProfileObject deletes the ClusterProfile/Profile using client c, which impersonates the calling user.
+// dependents are the profiles depending on it the user can get, otherDependents the number of the remaining ones.
+// If any profile depends on it, the profile is only deleted when force is set; otherwise the deletion is validated
+// and ForceRequired is set in the result.
+func deleteProfileObject(ctx context.Context, c client.Client, profileRef *corev1.ObjectReference,
+	dependents []corev1.ObjectReference, otherDependents int, dryRun, force bool) (*ProfileChangeResult, error) {
+
+	var profile client.Object
+	if profileRef.Kind == configv1beta1.ClusterProfileKind {
+		profile = &configv1beta1.ClusterProfile{}
+	} else {
+		profile = &configv1beta1.Profile{}
+	}
+	profile.GetObjectKind().SetGroupVersionKind(configv1beta1.GroupVersion.WithKind(profileRef.Kind))
+	profile.SetNamespace(profileRef.Namespace)
+	profile.SetName(profileRef.Name)
+
+	hasDependents := len(dependents)+otherDependents > 0
+	forceRequired := hasDependents && !force && !dryRun
+
+	result := newProfileChangeResult(profile, dryRun || forceRequired)
+	if hasDependents {
+		result.Dependents = dependents
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("%d ClusterProfile(s)/Profile(s) depend on %s %s: they will not be deployed until it is recreated",
+				len(dependents)+otherDependents, profileRef.Kind, profile.GetName()))
+	}
+
+	result, err := applyProfileChange(result, dryRun || forceRequired, func(dryRun bool) error {
+		var deleteOptions []client.DeleteOption
+		if dryRun {
+			deleteOptions = append(deleteOptions, client.DryRunAll)
+		}
+		return c.Delete(ctx, profile.DeepCopyObject().(client.Object), deleteOptions...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.ForceRequired = forceRequired && len(result.ValidationErrors) == 0
+	return result, nil
+}
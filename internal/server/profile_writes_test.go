@@ -0,0 +1,188 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Profile writes", func() {
+	It("decodeProfile decodes YAML and JSON and reports validation errors", func() {
+		name := randomString()
+		profile, validationErrors := server.DecodeProfile([]byte(fmt.Sprintf(`apiVersion: config.projectsveltos.io/v1beta1
+kind: ClusterProfile
+metadata:
+  name: %s
+spec:
+  clusterSelector:
+    matchLabels:
+      env: prod
+`, name)))
+		Expect(validationErrors).To(BeNil())
+		clusterProfile, ok := profile.(*configv1beta1.ClusterProfile)
+		Expect(ok).To(BeTrue())
+		Expect(clusterProfile.Name).To(Equal(name))
+		Expect(clusterProfile.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"env": "prod"}))
+
+		namespace := randomString()
+		profile, validationErrors = server.DecodeProfile([]byte(fmt.Sprintf(
+			`{"apiVersion": "config.projectsveltos.io/v1beta1", "kind": "Profile", "metadata": {"namespace": %q, "name": %q}}`,
+			namespace, name)))
+		Expect(validationErrors).To(BeNil())
+		Expect(profile.GetNamespace()).To(Equal(namespace))
+
+		// Unknown field
+		_, validationErrors = server.DecodeProfile([]byte(fmt.Sprintf(
+			`{"apiVersion": "config.projectsveltos.io/v1beta1", "kind": "ClusterProfile", "metadata": {"name": %q}, "spec": {"foo": 1}}`,
+			name)))
+		Expect(validationErrors).To(HaveLen(1))
+
+		// Unsupported kind
+		_, validationErrors = server.DecodeProfile([]byte(`{"apiVersion": "config.projectsveltos.io/v1beta1", "kind": "Foo"}`))
+		Expect(validationErrors).To(HaveLen(1))
+		Expect(validationErrors[0].Field).To(Equal("kind"))
+
+		// Profile requires namespace and name
+		_, validationErrors = server.DecodeProfile([]byte(`{"apiVersion": "config.projectsveltos.io/v1beta1", "kind": "Profile"}`))
+		Expect(validationErrors).To(HaveLen(2))
+	})
+
+	It("getValidationErrors returns causes reported by the API server", func() {
+		err := apierrors.NewInvalid(schema.GroupKind{Group: configv1beta1.GroupVersion.Group, Kind: configv1beta1.ClusterProfileKind},
+			randomString(), field.ErrorList{field.Required(field.NewPath("spec", "syncMode"), "syncMode is required")})
+
+		validationErrors := server.GetValidationErrors(err)
+		Expect(validationErrors).To(HaveLen(1))
+		Expect(validationErrors[0].Field).To(Equal("spec.syncMode"))
+		Expect(validationErrors[0].Type).To(Equal(string(metav1.CauseTypeFieldValueRequired)))
+
+		Expect(server.GetValidationErrors(apierrors.NewNotFound(schema.GroupResource{}, randomString()))).To(BeNil())
+	})
+
+	It("createProfileObject and updateProfileObject honor dry-run", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ClusterProfileKind, APIVersion: configv1beta1.GroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec:       configv1beta1.Spec{SyncMode: configv1beta1.SyncModeContinuous},
+		}
+
+		result, err := server.CreateProfileObject(context.TODO(), c, clusterProfile, true)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+
+		current := &configv1beta1.ClusterProfile{}
+		err = c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		result, err = server.CreateProfileObject(context.TODO(), c, clusterProfile, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+
+		clusterProfile.Spec.SyncMode = configv1beta1.SyncModeOneTime
+		result, err = server.UpdateProfileObject(context.TODO(), c, clusterProfile, true)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.SyncMode).To(Equal(configv1beta1.SyncModeContinuous))
+
+		result, err = server.UpdateProfileObject(context.TODO(), c, clusterProfile, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name}, current)).To(Succeed())
+		Expect(current.Spec.SyncMode).To(Equal(configv1beta1.SyncModeOneTime))
+
+		// Stale resourceVersion
+		clusterProfile.ResourceVersion = "1"
+		_, err = server.UpdateProfileObject(context.TODO(), c, clusterProfile, false)
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+	})
+
+	It("deleteProfileObject requires force when other profiles depend on the deleted one", func() {
+		profile := &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build()
+
+		profileRef := &corev1.ObjectReference{
+			Kind: configv1beta1.ProfileKind, APIVersion: configv1beta1.GroupVersion.String(),
+			Namespace: profile.Namespace, Name: profile.Name,
+		}
+		dependents := []corev1.ObjectReference{
+			{Kind: configv1beta1.ClusterProfileKind, APIVersion: configv1beta1.GroupVersion.String(), Name: randomString()},
+		}
+
+		result, err := server.DeleteProfileObject(context.TODO(), c, profileRef, dependents, 0, true, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(result.ForceRequired).To(BeFalse())
+		Expect(result.Dependents).To(Equal(dependents))
+		Expect(result.Warnings).To(HaveLen(1))
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name},
+			&configv1beta1.Profile{})).To(Succeed())
+
+		// Dependents, including ones the user cannot get, prevent deletion unless forced
+		result, err = server.DeleteProfileObject(context.TODO(), c, profileRef, nil, 1, false, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeFalse())
+		Expect(result.ForceRequired).To(BeTrue())
+		Expect(result.Dependents).To(BeEmpty())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name},
+			&configv1beta1.Profile{})).To(Succeed())
+
+		result, err = server.DeleteProfileObject(context.TODO(), c, profileRef, dependents, 0, false, true)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.ForceRequired).To(BeFalse())
+		Expect(result.Warnings).To(HaveLen(1))
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name},
+			&configv1beta1.Profile{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(c.Create(context.TODO(), &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: profile.Namespace, Name: profile.Name},
+		})).To(Succeed())
+
+		result, err = server.DeleteProfileObject(context.TODO(), c, profileRef, nil, 0, false, false)
+		Expect(err).To(BeNil())
+		Expect(result.Applied).To(BeTrue())
+		Expect(result.Warnings).To(BeEmpty())
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name},
+			&configv1beta1.Profile{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		_, err = server.DeleteProfileObject(context.TODO(), c, profileRef, nil, 0, false, false)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -17,6 +17,10 @@ limitations under the License.
 package server
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	corev1 "k8s.io/api/core/v1"
 
@@ -26,6 +30,8 @@ import (
 type MatchingClusters struct {
 	Cluster                 corev1.ObjectReference  `json:"cluster"`
 	ClusterFeatureSummaries []ClusterFeatureSummary `json:"clusterFeatureSummaries"`
+	// Sets are the referenced ClusterSets/Sets which selected the cluster
+	Sets []string `json:"sets,omitempty"`
 }
 
 type Profile struct {
@@ -43,6 +49,8 @@ type Profile struct {
 	MatchingClusters []MatchingClusters `json:"matchingClusters"`
 	// Profile's Spec section
 	Spec configv1beta1.Spec `json:"spec"`
+	// Provenance is set only for ClusterProfiles generated by an EventTrigger
+	Provenance *ProfileProvenance `json:"provenance,omitempty"`
 }
 
 type Profiles []Profile
@@ -65,14 +73,58 @@ type profileFilters struct {
 	Namespace string `uri:"namespace"`
 	Name      string `uri:"name"`
 	Kind      string `uri:"kind"`
+	// Generated, when set, returns only generated (true) or only non generated (false) profiles
+	Generated *bool `uri:"generated"`
 }
 
-func getProfileFiltersFromQuery(c *gin.Context) *profileFilters {
+func getProfileFiltersFromQuery(c *gin.Context) (*profileFilters, error) {
 	var filters profileFilters
 	// Get the values from query parameters
 	filters.Namespace = c.Query("namespace")
 	filters.Name = c.Query("name")
 	filters.Kind = c.Query("kind")
+	if value := c.Query("generated"); value != "" {
+		generated, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("generated must be a boolean")
+		}
+		filters.Generated = &generated
+	}
+
+	return &filters, nil
+}
+
+// getProfileRefFromQuery returns the ClusterProfile/Profile identified by the kind, namespace
+// and name query parameters.
+func getProfileRefFromQuery(c *gin.Context) (*corev1.ObjectReference, error) {
+	filters, err := getProfileFiltersFromQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if filters.Kind != configv1beta1.ClusterProfileKind &&
+		filters.Kind != configv1beta1.ProfileKind {
+
+		return nil, fmt.Errorf("supported kinds are %q and %q",
+			configv1beta1.ClusterProfileKind, configv1beta1.ProfileKind)
+	}
+
+	if filters.Kind == configv1beta1.ProfileKind && filters.Namespace == "" {
+		return nil, fmt.Errorf("namespace is required for %q", configv1beta1.ProfileKind)
+	}
+
+	if filters.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	profileRef := &corev1.ObjectReference{
+		Kind:       filters.Kind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+		Name:       filters.Name,
+	}
+	if filters.Kind == configv1beta1.ProfileKind {
+		profileRef.Namespace = filters.Namespace
+	}
 
-	return &filters
+	return profileRef, nil
 }
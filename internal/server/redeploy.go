@@ -0,0 +1,171 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// redeployRequestedAtAnnotation and redeployRequestedByAnnotation are set on a ClusterSummary
+	// to have addon-controller reconcile it right away
+	redeployRequestedAtAnnotation = "ui.projectsveltos.io/redeploy-requested-at"
+	redeployRequestedByAnnotation = "ui.projectsveltos.io/redeploy-requested-by"
+
+	// redeployPollInterval is how often the cached ClusterSummary status is checked after
+	// a redeploy is requested
+	redeployPollInterval = time.Second
+
+	defaultRedeployWatchTimeout = 2 * time.Minute
+	maxRedeployWatchTimeout     = 10 * time.Minute
+)
+
+// RedeployResult is sent once watching a redeploy ends
+type RedeployResult struct {
+	// Completed is true if all features reached a final state (Provisioned or FailedNonRetriable)
+	// before the watch timed out
+	Completed bool `json:"completed"`
+}
+
+// getClusterSummaryRef returns the ClusterSummary created for the ClusterProfile/Profile and cluster pair
+func getClusterSummaryRef(profileRef *corev1.ObjectReference, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *corev1.ObjectReference {
+
+	return &corev1.ObjectReference{
+		Namespace: clusterNamespace,
+		Name: controllers.GetClusterSummaryName(profileRef.Kind, profileRef.Name, clusterName,
+			clusterType == libsveltosv1beta1.ClusterTypeSveltos),
+		Kind:       configv1beta1.ClusterSummaryKind,
+		APIVersion: configv1beta1.GroupVersion.String(),
+	}
+}
+
+// requestRedeploy touches the annotations of the ClusterSummary. addon-controller watches
+// ClusterSummary instances, so this triggers a reconciliation without waiting for the next
+// retry. Features whose configuration did not change and are already provisioned are not redeployed.
+func requestRedeploy(ctx context.Context, c client.Client, clusterSummaryRef *corev1.ObjectReference,
+	user string, now time.Time) error {
+
+	clusterSummary := &configv1beta1.ClusterSummary{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterSummaryRef.Namespace, Name: clusterSummaryRef.Name},
+		clusterSummary)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(clusterSummary.DeepCopy())
+
+	annotations := clusterSummary.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[redeployRequestedAtAnnotation] = now.UTC().Format(time.RFC3339Nano)
+	annotations[redeployRequestedByAnnotation] = user
+	clusterSummary.SetAnnotations(annotations)
+
+	return c.Patch(ctx, clusterSummary, patch)
+}
+
+// getClusterSummaryStatus returns the cached status of a ClusterSummary
+func (m *instance) getClusterSummaryStatus(clusterSummaryRef *corev1.ObjectReference) ClusterProfileStatus {
+	m.clusterStatusesMux.RLock()
+	defer m.clusterStatusesMux.RUnlock()
+
+	return m.clusterSummaryReport[*clusterSummaryRef]
+}
+
+// isRedeployComplete returns true if all features are either provisioned or failed with
+// an error which won't be retried
+func isRedeployComplete(summary []ClusterFeatureSummary) bool {
+	if len(summary) == 0 {
+		return false
+	}
+
+	for i := range summary {
+		if summary[i].Status != configv1beta1.FeatureStatusProvisioned &&
+			summary[i].Status != configv1beta1.FeatureStatusFailedNonRetriable {
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasRedeployStarted returns true if addon-controller reconciled the ClusterSummary after the
+// redeploy was requested: either the summary differs from the one taken before the request or
+// a feature was applied after requestedAt
+func hasRedeployStarted(baseline, summary []ClusterFeatureSummary, requestedAt time.Time) bool {
+	if !reflect.DeepEqual(baseline, summary) {
+		return true
+	}
+
+	for i := range summary {
+		if summary[i].LastAppliedTime != nil && summary[i].LastAppliedTime.After(requestedAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchRedeploy polls getStatus every interval and sends a "status" event each time the status
+// changes (the current status is always sent first). A final "done" event is sent when the
+// redeploy completes or ctx is done. baseline is the status taken before the redeploy was
+// requested at requestedAt, so a status which was already final is not reported as completed.
+func watchRedeploy(ctx context.Context, interval time.Duration, baseline *ClusterProfileStatus,
+	requestedAt time.Time, getStatus func() ClusterProfileStatus, send func(event string, data any)) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	started := false
+	var last *ClusterProfileStatus
+	for {
+		status := getStatus()
+		if last == nil || !reflect.DeepEqual(last.Summary, status.Summary) {
+			send("status", status)
+			last = &status
+		}
+
+		if !started {
+			started = hasRedeployStarted(baseline.Summary, status.Summary, requestedAt)
+		}
+
+		if started && isRedeployComplete(status.Summary) {
+			send("done", RedeployResult{Completed: true})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			send("done", RedeployResult{Completed: false})
+			return
+		case <-ticker.C:
+		}
+	}
+}
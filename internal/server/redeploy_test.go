@@ -0,0 +1,165 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Redeploy", func() {
+	It("requestRedeploy touches ClusterSummary annotations", func() {
+		profileRef := &corev1.ObjectReference{
+			Kind: configv1beta1.ClusterProfileKind, APIVersion: configv1beta1.GroupVersion.String(),
+			Name: randomString(),
+		}
+		clusterNamespace := randomString()
+		clusterName := randomString()
+
+		clusterSummaryRef := server.GetClusterSummaryRef(profileRef, clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(clusterSummaryRef.Namespace).To(Equal(clusterNamespace))
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   clusterSummaryRef.Namespace,
+				Name:        clusterSummaryRef.Name,
+				Annotations: map[string]string{randomString(): randomString()},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterSummary).Build()
+
+		user := randomString()
+		now := time.Now()
+		Expect(server.RequestRedeploy(context.TODO(), c, clusterSummaryRef, user, now)).To(Succeed())
+
+		current := &configv1beta1.ClusterSummary{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			current)).To(Succeed())
+		Expect(current.Annotations).To(HaveLen(3))
+		Expect(current.Annotations["ui.projectsveltos.io/redeploy-requested-by"]).To(Equal(user))
+		Expect(current.Annotations["ui.projectsveltos.io/redeploy-requested-at"]).To(
+			Equal(now.UTC().Format(time.RFC3339Nano)))
+
+		// ClusterSummary does not exist
+		clusterSummaryRef.Name = randomString()
+		Expect(server.RequestRedeploy(context.TODO(), c, clusterSummaryRef, user, now)).ToNot(Succeed())
+	})
+
+	It("isRedeployComplete returns true once all features are in a final state", func() {
+		Expect(server.IsRedeployComplete(nil)).To(BeFalse())
+		Expect(server.IsRedeployComplete([]server.ClusterFeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned},
+			{FeatureID: configv1beta1.FeatureResources, Status: configv1beta1.FeatureStatusFailed},
+		})).To(BeFalse())
+		Expect(server.IsRedeployComplete([]server.ClusterFeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned},
+			{FeatureID: configv1beta1.FeatureResources, Status: configv1beta1.FeatureStatusFailedNonRetriable},
+		})).To(BeTrue())
+	})
+
+	It("watchRedeploy sends status changes until redeploy completes", func() {
+		statuses := []configv1beta1.FeatureStatus{
+			configv1beta1.FeatureStatusFailed,
+			configv1beta1.FeatureStatusFailed,
+			configv1beta1.FeatureStatusProvisioning,
+			configv1beta1.FeatureStatusProvisioned,
+		}
+		calls := 0
+		getStatus := func() server.ClusterProfileStatus {
+			status := statuses[min(calls, len(statuses)-1)]
+			calls++
+			return server.ClusterProfileStatus{
+				Summary: []server.ClusterFeatureSummary{{FeatureID: configv1beta1.FeatureHelm, Status: status}},
+			}
+		}
+
+		var events []string
+		var lastData any
+		send := func(event string, data any) {
+			events = append(events, event)
+			lastData = data
+		}
+
+		baseline := &server.ClusterProfileStatus{
+			Summary: []server.ClusterFeatureSummary{
+				{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusFailed},
+			},
+		}
+		server.WatchRedeploy(context.TODO(), time.Millisecond, baseline, time.Now(), getStatus, send)
+		Expect(events).To(Equal([]string{"status", "status", "status", "done"}))
+		Expect(lastData).To(Equal(server.RedeployResult{Completed: true}))
+
+		// Status never reaches a final state
+		calls = 0
+		statuses = []configv1beta1.FeatureStatus{configv1beta1.FeatureStatusFailed}
+		events = nil
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+		server.WatchRedeploy(ctx, time.Millisecond, baseline, time.Now(), getStatus, send)
+		Expect(events).To(Equal([]string{"status", "done"}))
+		Expect(lastData).To(Equal(server.RedeployResult{Completed: false}))
+
+		// Status was already final before the redeploy and addon-controller has not reconciled yet
+		calls = 0
+		statuses = []configv1beta1.FeatureStatus{configv1beta1.FeatureStatusProvisioned}
+		baseline.Summary[0].Status = configv1beta1.FeatureStatusProvisioned
+		events = nil
+		ctx, cancel = context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+		server.WatchRedeploy(ctx, time.Millisecond, baseline, time.Now(), getStatus, send)
+		Expect(events).To(Equal([]string{"status", "done"}))
+		Expect(lastData).To(Equal(server.RedeployResult{Completed: false}))
+	})
+
+	It("hasRedeployStarted returns true once the ClusterSummary is reconciled after the request", func() {
+		requestedAt := time.Now()
+		before := metav1.NewTime(requestedAt.Add(-time.Minute))
+		after := metav1.NewTime(requestedAt.Add(time.Second))
+
+		baseline := []server.ClusterFeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned,
+				LastAppliedTime: &before},
+		}
+		Expect(server.HasRedeployStarted(baseline, baseline, requestedAt)).To(BeFalse())
+
+		changed := []server.ClusterFeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioning,
+				LastAppliedTime: &before},
+		}
+		Expect(server.HasRedeployStarted(baseline, changed, requestedAt)).To(BeTrue())
+
+		// Baseline already taken after the ClusterSummary was reconciled
+		reapplied := []server.ClusterFeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned,
+				LastAppliedTime: &after},
+		}
+		Expect(server.HasRedeployStarted(reapplied, reapplied, requestedAt)).To(BeTrue())
+	})
+})
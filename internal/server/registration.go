@@ -0,0 +1,216 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// sveltosKubeconfigSecretNamePostfix is the Sveltos convention for the name of the Secret
+	// containing the kubeconfig of a SveltosCluster
+	sveltosKubeconfigSecretNamePostfix = "-sveltos-kubeconfig"
+
+	// kubeconfigSecretKey is the Secret key the kubeconfig is stored under
+	kubeconfigSecretKey = "kubeconfig"
+
+	// registeredByLabel is added to Secrets created during registration. Only those Secrets are
+	// deleted on deregistration.
+	registeredByLabel = "ui.projectsveltos.io/registered-by"
+)
+
+// ClusterRegistration is the body of a SveltosCluster registration request
+type ClusterRegistration struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+
+	// Kubeconfig to access the cluster. It must be self-contained (no file references,
+	// exec or auth provider plugins).
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+type RegistrationResult struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	SecretName string `json:"secretName"`
+
+	// Registered is true when both Secret and SveltosCluster were created
+	Registered bool `json:"registered"`
+
+	// Probe is the outcome of the connectivity check run before registering the cluster
+	Probe *ProbeResult `json:"probe"`
+}
+
+type DeregistrationResult struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// SecretDeleted is false if the kubeconfig Secret was not created during registration
+	// or did not exist
+	SecretDeleted bool `json:"secretDeleted"`
+}
+
+// getRestConfigFromKubeconfig validates kubeconfig and returns the corresponding rest.Config.
+// Kubeconfigs referencing local files or plugins are rejected, as they would be resolved
+// on the backend.
+func getRestConfigFromKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %s: certificate-authority file is not supported, use certificate-authority-data",
+				name)
+		}
+	}
+
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.ClientCertificate != "" || authInfo.ClientKey != "":
+			return nil, fmt.Errorf("user %s: client certificate/key files are not supported, use data fields", name)
+		case authInfo.TokenFile != "":
+			return nil, fmt.Errorf("user %s: token file is not supported, use token", name)
+		case authInfo.Exec != nil || authInfo.AuthProvider != nil:
+			return nil, fmt.Errorf("user %s: exec and auth provider plugins are not supported", name)
+		}
+	}
+
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// registerSveltosCluster probes the cluster and, if reachable with the provided credentials, creates
+// the kubeconfig Secret and the SveltosCluster using client c, which impersonates the calling user.
+func registerSveltosCluster(ctx context.Context, c client.Client, registration *ClusterRegistration,
+	restConfig *rest.Config, user string) (*RegistrationResult, error) {
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   registration.Namespace,
+			Name:        registration.Name + sveltosKubeconfigSecretNamePostfix,
+			Labels:      map[string]string{registeredByLabel: "ui"},
+			Annotations: map[string]string{registeredByLabel: user},
+		},
+		Data: map[string][]byte{
+			kubeconfigSecretKey: []byte(registration.Kubeconfig),
+		},
+	}
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: registration.Namespace,
+			Name:      registration.Name,
+			Labels:    registration.Labels,
+		},
+		Spec: libsveltosv1beta1.SveltosClusterSpec{
+			KubeconfigName:    secret.Name,
+			KubeconfigKeyName: kubeconfigSecretKey,
+		},
+	}
+
+	// Verify user can create both objects before connecting to the cluster
+	for _, obj := range []client.Object{secret, sveltosCluster} {
+		if err := c.Create(ctx, obj.DeepCopyObject().(client.Object), client.DryRunAll); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RegistrationResult{
+		Namespace:  registration.Namespace,
+		Name:       registration.Name,
+		SecretName: secret.Name,
+		Probe:      probeRestConfig(ctx, restConfig),
+	}
+
+	if !result.Probe.Reachable || result.Probe.CredentialsAccepted == nil || !*result.Probe.CredentialsAccepted {
+		return result, nil
+	}
+
+	if err := c.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+
+	if err := c.Create(ctx, sveltosCluster); err != nil {
+		// Do not leave the Secret behind
+		if deleteErr := c.Delete(ctx, secret); deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+			return nil, errors.Join(err, deleteErr)
+		}
+		return nil, err
+	}
+
+	result.Registered = true
+	return result, nil
+}
+
+// deregisterSveltosCluster deletes the SveltosCluster and, if it was created during registration,
+// its kubeconfig Secret using client c, which impersonates the calling user.
+func deregisterSveltosCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+) (*DeregistrationResult, error) {
+
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, sveltosCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Delete(ctx, sveltosCluster); err != nil {
+		return nil, err
+	}
+
+	result := &DeregistrationResult{
+		Namespace: clusterNamespace,
+		Name:      clusterName,
+	}
+
+	secretName := sveltosCluster.Spec.KubeconfigName
+	if secretName == "" {
+		secretName = clusterName + sveltosKubeconfigSecretNamePostfix
+	}
+
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: secretName}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	if _, ok := secret.Labels[registeredByLabel]; !ok {
+		return result, nil
+	}
+
+	if err := c.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	result.SecretDeleted = true
+	return result, nil
+}
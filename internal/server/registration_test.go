@@ -0,0 +1,198 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Registration", func() {
+	var apiServer *httptest.Server
+	const validToken = "valid-token"
+
+	getKubeconfig := func(user string) string {
+		caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: apiServer.Certificate().Raw})
+		return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: context
+  context:
+    cluster: cluster
+    user: user
+current-context: context
+users:
+- name: user
+  user:
+%s
+`, apiServer.URL, base64.StdEncoding.EncodeToString(caData), user)
+	}
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"major": "1", "minor": "32", "gitVersion": "v1.32.2"}`))
+		})
+		mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"kind": "APIVersions", "versions": ["v1"]}`))
+		})
+		apiServer = httptest.NewTLSServer(mux)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	It("getRestConfigFromKubeconfig rejects kubeconfigs referencing local files or plugins", func() {
+		_, err := server.GetRestConfigFromKubeconfig([]byte(getKubeconfig("    token: " + validToken)))
+		Expect(err).To(BeNil())
+
+		_, err = server.GetRestConfigFromKubeconfig([]byte(getKubeconfig(
+			"    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token")))
+		Expect(err).ToNot(BeNil())
+
+		_, err = server.GetRestConfigFromKubeconfig([]byte(getKubeconfig(
+			"    client-certificate: /tmp/cert\n    client-key: /tmp/key")))
+		Expect(err).ToNot(BeNil())
+
+		_, err = server.GetRestConfigFromKubeconfig([]byte(getKubeconfig(
+			"    exec:\n      apiVersion: client.authentication.k8s.io/v1\n      command: /bin/sh")))
+		Expect(err).ToNot(BeNil())
+
+		_, err = server.GetRestConfigFromKubeconfig([]byte(randomString()))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("registerSveltosCluster creates Secret and SveltosCluster only if cluster is reachable", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		registration := &server.ClusterRegistration{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Labels:     map[string]string{"env": "prod"},
+			Kubeconfig: getKubeconfig("    token: " + randomString()),
+		}
+
+		restConfig, err := server.GetRestConfigFromKubeconfig([]byte(registration.Kubeconfig))
+		Expect(err).To(BeNil())
+
+		// Credentials are rejected by the cluster
+		result, err := server.RegisterSveltosCluster(context.TODO(), c, registration, restConfig, randomString())
+		Expect(err).To(BeNil())
+		Expect(result.Registered).To(BeFalse())
+		Expect(result.Probe.Reachable).To(BeTrue())
+
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+		key := types.NamespacedName{Namespace: registration.Namespace, Name: registration.Name}
+		err = c.Get(context.TODO(), key, sveltosCluster)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		registration.Kubeconfig = getKubeconfig("    token: " + validToken)
+		restConfig, err = server.GetRestConfigFromKubeconfig([]byte(registration.Kubeconfig))
+		Expect(err).To(BeNil())
+
+		result, err = server.RegisterSveltosCluster(context.TODO(), c, registration, restConfig, randomString())
+		Expect(err).To(BeNil())
+		Expect(result.Registered).To(BeTrue())
+		Expect(result.SecretName).To(Equal(registration.Name + "-sveltos-kubeconfig"))
+
+		Expect(c.Get(context.TODO(), key, sveltosCluster)).To(Succeed())
+		Expect(sveltosCluster.Labels).To(Equal(registration.Labels))
+		Expect(sveltosCluster.Spec.KubeconfigName).To(Equal(result.SecretName))
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{Namespace: registration.Namespace, Name: result.SecretName}, secret)).To(Succeed())
+		Expect(string(secret.Data[sveltosCluster.Spec.KubeconfigKeyName])).To(Equal(registration.Kubeconfig))
+
+		// Registering the same cluster twice fails
+		_, err = server.RegisterSveltosCluster(context.TODO(), c, registration, restConfig, randomString())
+		Expect(apierrors.IsAlreadyExists(err)).To(BeTrue())
+	})
+
+	It("deregisterSveltosCluster removes SveltosCluster and the Secret created during registration", func() {
+		namespace := randomString()
+
+		registeredCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+		registeredSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      registeredCluster.Name + "-sveltos-kubeconfig",
+				Labels:    map[string]string{"ui.projectsveltos.io/registered-by": "ui"},
+			},
+		}
+
+		// Secret was not created by the registration endpoint
+		otherCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec:       libsveltosv1beta1.SveltosClusterSpec{KubeconfigName: randomString()},
+		}
+		otherSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: otherCluster.Spec.KubeconfigName},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(registeredCluster, registeredSecret,
+			otherCluster, otherSecret).Build()
+
+		result, err := server.DeregisterSveltosCluster(context.TODO(), c, namespace, registeredCluster.Name)
+		Expect(err).To(BeNil())
+		Expect(result.SecretDeleted).To(BeTrue())
+
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: registeredCluster.Name},
+			&libsveltosv1beta1.SveltosCluster{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: registeredSecret.Name},
+			&corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		result, err = server.DeregisterSveltosCluster(context.TODO(), c, namespace, otherCluster.Name)
+		Expect(err).To(BeNil())
+		Expect(result.SecretDeleted).To(BeFalse())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: otherSecret.Name},
+			&corev1.Secret{})).To(Succeed())
+
+		_, err = server.DeregisterSveltosCluster(context.TODO(), c, namespace, otherCluster.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
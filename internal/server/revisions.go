@@ -0,0 +1,186 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+const (
+	// maxProfileRevisions is the maximum number of spec revisions kept per ClusterProfile/Profile
+	maxProfileRevisions = 20
+)
+
+// ProfileRevision is the spec of a ClusterProfile/Profile at a given generation
+type ProfileRevision struct {
+	Generation int64 `json:"generation"`
+
+	// Author is the field manager which last changed the spec (e.g. kubectl-client-side-apply),
+	// as reported by managedFields
+	Author string `json:"author,omitempty"`
+
+	// Time is when the author changed the spec. If not available, when the revision was observed.
+	Time metav1.Time `json:"time"`
+
+	Spec configv1beta1.Spec `json:"spec"`
+}
+
+type ProfileRevisionsResult struct {
+	TotalRevisions int               `json:"totalRevisions"`
+	Revisions      []ProfileRevision `json:"revisions"`
+}
+
+type ProfileRevisionDiff struct {
+	FromGeneration int64        `json:"fromGeneration"`
+	ToGeneration   int64        `json:"toGeneration"`
+	Changes        []SpecChange `json:"changes"`
+}
+
+// AddProfileRevision records the spec of a ClusterProfile/Profile if generation was not recorded yet.
+// Revisions are tracked only since the backend started.
+func (m *instance) AddProfileRevision(profile *corev1.ObjectReference, generation int64,
+	spec *configv1beta1.Spec, managedFields []metav1.ManagedFieldsEntry) {
+
+	revision := ProfileRevision{
+		Generation: generation,
+		Time:       metav1.NewTime(time.Now()),
+		Spec:       *spec.DeepCopy(),
+	}
+	if author, changeTime := getSpecAuthor(managedFields); author != "" {
+		revision.Author = author
+		if changeTime != nil {
+			revision.Time = *changeTime
+		}
+	}
+
+	m.revisionMux.Lock()
+	defer m.revisionMux.Unlock()
+
+	m.profileRevisions[*profile] = appendProfileRevision(m.profileRevisions[*profile], revision)
+}
+
+func (m *instance) removeProfileRevisions(profile *corev1.ObjectReference) {
+	m.revisionMux.Lock()
+	defer m.revisionMux.Unlock()
+
+	delete(m.profileRevisions, *profile)
+}
+
+// appendProfileRevision adds revision to history unless its generation matches the last recorded one.
+// At most maxProfileRevisions are kept.
+func appendProfileRevision(history []ProfileRevision, revision ProfileRevision) []ProfileRevision {
+	if len(history) > 0 && history[len(history)-1].Generation == revision.Generation {
+		return history
+	}
+
+	history = append(history, revision)
+	if len(history) > maxProfileRevisions {
+		history = history[len(history)-maxProfileRevisions:]
+	}
+	return history
+}
+
+// getSpecAuthor returns the field manager which most recently changed the spec, along with
+// the time of the change. Changes to subresources (e.g. status) are ignored.
+func getSpecAuthor(managedFields []metav1.ManagedFieldsEntry) (string, *metav1.Time) {
+	var author string
+	var changeTime *metav1.Time
+
+	for i := range managedFields {
+		entry := &managedFields[i]
+		if entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields["f:spec"]; !ok {
+			continue
+		}
+
+		if author == "" || (entry.Time != nil && (changeTime == nil || entry.Time.After(changeTime.Time))) {
+			author = entry.Manager
+			changeTime = entry.Time
+		}
+	}
+
+	return author, changeTime
+}
+
+// getProfileRevisions returns the recorded revisions of a ClusterProfile/Profile, most recent first
+func (m *instance) getProfileRevisions(profile *corev1.ObjectReference) []ProfileRevision {
+	m.revisionMux.RLock()
+	defer m.revisionMux.RUnlock()
+
+	history := m.profileRevisions[*profile]
+	result := make([]ProfileRevision, len(history))
+	for i := range history {
+		result[len(history)-1-i] = history[i]
+	}
+
+	return result
+}
+
+// getProfileRevisionDiff returns the spec changes between two recorded revisions. When to is zero,
+// the most recent revision is used. When from is zero, the revision preceding to is used.
+func getProfileRevisionDiff(revisions []ProfileRevision, from, to int64) (*ProfileRevisionDiff, error) {
+	// revisions are sorted, most recent first
+	toIndex := -1
+	for i := range revisions {
+		if to == 0 || revisions[i].Generation == to {
+			toIndex = i
+			break
+		}
+	}
+	if toIndex == -1 {
+		return nil, fmt.Errorf("revision %d not found", to)
+	}
+
+	fromIndex := -1
+	for i := range revisions {
+		if (from == 0 && i > toIndex) || (from != 0 && revisions[i].Generation == from) {
+			fromIndex = i
+			break
+		}
+	}
+	if fromIndex == -1 {
+		if from == 0 {
+			return nil, fmt.Errorf("no revision preceding %d", revisions[toIndex].Generation)
+		}
+		return nil, fmt.Errorf("revision %d not found", from)
+	}
+
+	changes, err := diffSpecs(&revisions[fromIndex].Spec, &revisions[toIndex].Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProfileRevisionDiff{
+		FromGeneration: revisions[fromIndex].Generation,
+		ToGeneration:   revisions[toIndex].Generation,
+		Changes:        changes,
+	}, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Profile revisions", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+	})
+
+	It("getSpecAuthor returns the field manager which last changed the spec", func() {
+		earlier := metav1.NewTime(time.Now().Add(-time.Hour))
+		later := metav1.NewTime(time.Now())
+
+		managedFields := []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier,
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:syncMode":{}}}`)}},
+			{Manager: "sveltosctl", Operation: metav1.ManagedFieldsOperationApply, Time: &later,
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:tier":{}}}`)}},
+			// Status and metadata only changes are ignored
+			{Manager: "addon-controller", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later,
+				Subresource: "status", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+			{Manager: "kubectl-label", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later,
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{}}}`)}},
+		}
+
+		author, changeTime := server.GetSpecAuthor(managedFields)
+		Expect(author).To(Equal("sveltosctl"))
+		Expect(changeTime).To(Equal(&later))
+
+		author, changeTime = server.GetSpecAuthor(nil)
+		Expect(author).To(BeEmpty())
+		Expect(changeTime).To(BeNil())
+	})
+
+	It("AddProfileRevision records one revision per generation", func() {
+		manager := server.NewManagerInstance(nil, scheme, logger)
+
+		profileRef := &corev1.ObjectReference{Kind: configv1beta1.ProfileKind, Namespace: randomString(),
+			Name: randomString(), APIVersion: configv1beta1.GroupVersion.String()}
+
+		spec := &configv1beta1.Spec{SyncMode: configv1beta1.SyncModeDryRun}
+		manager.AddProfileRevision(profileRef, 1, spec, nil)
+		// Status updates do not change generation
+		manager.AddProfileRevision(profileRef, 1, spec, nil)
+
+		spec.SyncMode = configv1beta1.SyncModeContinuous
+		manager.AddProfileRevision(profileRef, 2, spec, nil)
+
+		revisions := manager.GetProfileRevisions(profileRef)
+		Expect(revisions).To(HaveLen(2))
+		// Most recent first
+		Expect(revisions[0].Generation).To(Equal(int64(2)))
+		Expect(revisions[0].Spec.SyncMode).To(Equal(configv1beta1.SyncModeContinuous))
+		Expect(revisions[1].Spec.SyncMode).To(Equal(configv1beta1.SyncModeDryRun))
+
+		// At most 20 revisions are kept
+		for i := 3; i <= 30; i++ {
+			manager.AddProfileRevision(profileRef, int64(i), spec, nil)
+		}
+		revisions = manager.GetProfileRevisions(profileRef)
+		Expect(revisions).To(HaveLen(20))
+		Expect(revisions[0].Generation).To(Equal(int64(30)))
+		Expect(revisions[19].Generation).To(Equal(int64(11)))
+
+		manager.RemoveProfile(profileRef)
+		Expect(manager.GetProfileRevisions(profileRef)).To(BeEmpty())
+	})
+
+	It("getProfileRevisionDiff compares two revisions", func() {
+		revisions := []server.ProfileRevision{
+			{Generation: 3, Spec: configv1beta1.Spec{SyncMode: configv1beta1.SyncModeContinuous, Tier: 50}},
+			{Generation: 2, Spec: configv1beta1.Spec{SyncMode: configv1beta1.SyncModeContinuous}},
+			{Generation: 1, Spec: configv1beta1.Spec{SyncMode: configv1beta1.SyncModeDryRun}},
+		}
+
+		// Latest revision against the preceding one
+		diff, err := server.GetProfileRevisionDiff(revisions, 0, 0)
+		Expect(err).To(BeNil())
+		Expect(diff.FromGeneration).To(Equal(int64(2)))
+		Expect(diff.ToGeneration).To(Equal(int64(3)))
+		Expect(diff.Changes).To(HaveLen(1))
+		Expect(diff.Changes[0].Path).To(Equal("spec.tier"))
+
+		diff, err = server.GetProfileRevisionDiff(revisions, 1, 3)
+		Expect(err).To(BeNil())
+		Expect(diff.Changes).To(HaveLen(2))
+
+		_, err = server.GetProfileRevisionDiff(revisions, 0, 1)
+		Expect(err).ToNot(BeNil())
+		_, err = server.GetProfileRevisionDiff(revisions, 5, 0)
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,331 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	roleKind        = "Role"
+	clusterRoleKind = "ClusterRole"
+)
+
+var (
+	yamlSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+)
+
+type RoleRequestInfo struct {
+	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector"`
+
+	// RoleRefs references ConfigMaps/Secrets containing the Roles/ClusterRoles granted to the tenant
+	RoleRefs []libsveltosv1beta1.PolicyRef `json:"roleRefs"`
+
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+
+	// ServiceAccountNamespace and ServiceAccountName identify the tenant admin
+	ServiceAccountNamespace string `json:"serviceAccountNamespace"`
+	ServiceAccountName      string `json:"serviceAccountName"`
+
+	// MatchingClusters are the clusters currently matching the RoleRequest
+	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
+
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+// RoleDefinition is a Role/ClusterRole contained in a ConfigMap/Secret referenced by a RoleRequest
+type RoleDefinition struct {
+	Kind      string              `json:"kind"`
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace,omitempty"`
+	Rules     []rbacv1.PolicyRule `json:"rules"`
+}
+
+// RoleRefContent contains the Roles/ClusterRoles contained in a referenced ConfigMap/Secret
+type RoleRefContent struct {
+	libsveltosv1beta1.PolicyRef `json:",inline"`
+
+	Roles []RoleDefinition `json:"roles"`
+
+	// FailureMessage is set when content could not be read (for instance user has no permission)
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+type RoleRequestResult struct {
+	Name string `json:"name"`
+
+	RoleRequestInfo `json:",inline"`
+
+	RoleRefContents []RoleRefContent `json:"roleRefContents"`
+}
+
+type RoleRequestsResult struct {
+	TotalRoleRequests int                 `json:"totalRoleRequests"`
+	RoleRequests      []RoleRequestResult `json:"roleRequests"`
+}
+
+// Tenant is a tenant admin with access to a cluster
+type Tenant struct {
+	ServiceAccountNamespace string `json:"serviceAccountNamespace"`
+	ServiceAccountName      string `json:"serviceAccountName"`
+
+	// RoleRequests are the RoleRequests granting the tenant access to the cluster
+	RoleRequests []string `json:"roleRequests"`
+}
+
+func (m *instance) AddRoleRequest(roleRequest *libsveltosv1beta1.RoleRequest) {
+	info := RoleRequestInfo{
+		ClusterSelector:         roleRequest.Spec.ClusterSelector,
+		RoleRefs:                roleRequest.Spec.RoleRefs,
+		ExpirationSeconds:       roleRequest.Spec.ExpirationSeconds,
+		ServiceAccountNamespace: roleRequest.Spec.ServiceAccountNamespace,
+		ServiceAccountName:      roleRequest.Spec.ServiceAccountName,
+		MatchingClusters:        make([]corev1.ObjectReference, len(roleRequest.Status.MatchingClusterRefs)),
+		FailureMessage:          roleRequest.Status.FailureMessage,
+	}
+
+	for i := range roleRequest.Status.MatchingClusterRefs {
+		info.MatchingClusters[i] = *normalizeClusterRef(&roleRequest.Status.MatchingClusterRefs[i])
+	}
+
+	m.roleRequestMux.Lock()
+	defer m.roleRequestMux.Unlock()
+
+	m.roleRequests[roleRequest.Name] = info
+}
+
+func (m *instance) RemoveRoleRequest(roleRequestName string) {
+	m.roleRequestMux.Lock()
+	defer m.roleRequestMux.Unlock()
+
+	delete(m.roleRequests, roleRequestName)
+}
+
+// getRoleRequests returns all RoleRequests whose name contains nameFilter, sorted by name.
+// Only clusters present in the clusters map are reported.
+func (m *instance) getRoleRequests(nameFilter string, clusters map[corev1.ObjectReference]ClusterInfo,
+) []RoleRequestResult {
+
+	m.roleRequestMux.RLock()
+	defer m.roleRequestMux.RUnlock()
+
+	result := make([]RoleRequestResult, 0, len(m.roleRequests))
+	for name := range m.roleRequests {
+		if nameFilter != "" && !strings.Contains(name, nameFilter) {
+			continue
+		}
+
+		info := m.roleRequests[name]
+
+		matchingClusters := make([]corev1.ObjectReference, 0, len(info.MatchingClusters))
+		for i := range info.MatchingClusters {
+			if _, ok := clusters[info.MatchingClusters[i]]; ok {
+				matchingClusters = append(matchingClusters, info.MatchingClusters[i])
+			}
+		}
+		sortClusterRefs(matchingClusters)
+		info.MatchingClusters = matchingClusters
+
+		result = append(result, RoleRequestResult{
+			Name:            name,
+			RoleRequestInfo: info,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getClusterTenants returns the tenants with access to a cluster, sorted by service account
+func (m *instance) getClusterTenants(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) []Tenant {
+
+	m.roleRequestMux.RLock()
+	defer m.roleRequestMux.RUnlock()
+
+	return getTenants(m.roleRequests, getClusterRef(clusterNamespace, clusterName, clusterType))
+}
+
+// setRoleRefContents fetches the Roles/ClusterRoles referenced by each RoleRequest.
+// Content of a ConfigMap/Secret is returned only if user can get it.
+func (m *instance) setRoleRefContents(ctx context.Context, roleRequests []RoleRequestResult, user string) {
+	for i := range roleRequests {
+		roleRequests[i].RoleRefContents = make([]RoleRefContent, len(roleRequests[i].RoleRefs))
+		for j := range roleRequests[i].RoleRefs {
+			roleRequests[i].RoleRefContents[j] = m.getRoleRefContent(ctx, &roleRequests[i].RoleRefs[j], user)
+		}
+	}
+}
+
+func (m *instance) getRoleRefContent(ctx context.Context, roleRef *libsveltosv1beta1.PolicyRef, user string,
+) RoleRefContent {
+
+	result := RoleRefContent{
+		PolicyRef: *roleRef,
+		Roles:     make([]RoleDefinition, 0),
+	}
+
+	setFailure := func(msg string) RoleRefContent {
+		result.FailureMessage = &msg
+		return result
+	}
+
+	canGet, err := m.canGetRoleRef(roleRef, user)
+	if err != nil {
+		return setFailure(fmt.Sprintf("failed to verify permissions: %v", err))
+	}
+	if !canGet {
+		return setFailure(fmt.Sprintf("no permissions to access %s %s/%s",
+			roleRef.Kind, roleRef.Namespace, roleRef.Name))
+	}
+
+	var data []string
+	key := types.NamespacedName{Namespace: roleRef.Namespace, Name: roleRef.Name}
+	switch roleRef.Kind {
+	case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+		configMap := &corev1.ConfigMap{}
+		if err := m.client.Get(ctx, key, configMap); err != nil {
+			return setFailure(err.Error())
+		}
+		for k := range configMap.Data {
+			data = append(data, configMap.Data[k])
+		}
+	case string(libsveltosv1beta1.SecretReferencedResourceKind):
+		secret := &corev1.Secret{}
+		if err := m.client.Get(ctx, key, secret); err != nil {
+			return setFailure(err.Error())
+		}
+		for k := range secret.Data {
+			data = append(data, string(secret.Data[k]))
+		}
+	default:
+		return setFailure(fmt.Sprintf("unsupported kind %s", roleRef.Kind))
+	}
+
+	for i := range data {
+		roles, err := getRoleDefinitions(data[i])
+		if err != nil {
+			return setFailure(err.Error())
+		}
+		result.Roles = append(result.Roles, roles...)
+	}
+
+	sort.Slice(result.Roles, func(i, j int) bool {
+		if result.Roles[i].Kind != result.Roles[j].Kind {
+			return result.Roles[i].Kind < result.Roles[j].Kind
+		}
+		if result.Roles[i].Namespace != result.Roles[j].Namespace {
+			return result.Roles[i].Namespace < result.Roles[j].Namespace
+		}
+		return result.Roles[i].Name < result.Roles[j].Name
+	})
+
+	return result
+}
+
+// getRoleDefinitions returns all Roles/ClusterRoles contained in content. Other resources are ignored.
+func getRoleDefinitions(content string) ([]RoleDefinition, error) {
+	result := make([]RoleDefinition, 0)
+	for _, section := range yamlSeparator.Split(content, -1) {
+		if strings.TrimSpace(section) == "" {
+			continue
+		}
+
+		// Role and ClusterRole share the fields reported here
+		role := &rbacv1.ClusterRole{}
+		if err := yaml.Unmarshal([]byte(section), role); err != nil {
+			return nil, fmt.Errorf("failed to parse role: %w", err)
+		}
+
+		if role.Kind != roleKind && role.Kind != clusterRoleKind {
+			continue
+		}
+
+		rules := role.Rules
+		if rules == nil {
+			rules = make([]rbacv1.PolicyRule, 0)
+		}
+
+		result = append(result, RoleDefinition{
+			Kind:      role.Kind,
+			Name:      role.Name,
+			Namespace: role.Namespace,
+			Rules:     rules,
+		})
+	}
+
+	return result, nil
+}
+
+// getTenants returns the tenants which RoleRequests match clusterRef
+func getTenants(roleRequests map[string]RoleRequestInfo, clusterRef *corev1.ObjectReference) []Tenant {
+	tenants := map[types.NamespacedName]*Tenant{}
+	for name := range roleRequests {
+		info := roleRequests[name]
+		matching := false
+		for i := range info.MatchingClusters {
+			if isSameCluster(&info.MatchingClusters[i], clusterRef) {
+				matching = true
+				break
+			}
+		}
+		if !matching {
+			continue
+		}
+
+		key := types.NamespacedName{Namespace: info.ServiceAccountNamespace, Name: info.ServiceAccountName}
+		tenant, ok := tenants[key]
+		if !ok {
+			tenant = &Tenant{
+				ServiceAccountNamespace: info.ServiceAccountNamespace,
+				ServiceAccountName:      info.ServiceAccountName,
+				RoleRequests:            make([]string, 0),
+			}
+			tenants[key] = tenant
+		}
+		tenant.RoleRequests = append(tenant.RoleRequests, name)
+	}
+
+	result := make([]Tenant, 0, len(tenants))
+	for k := range tenants {
+		sort.Strings(tenants[k].RoleRequests)
+		result = append(result, *tenants[k])
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ServiceAccountNamespace != result[j].ServiceAccountNamespace {
+			return result[i].ServiceAccountNamespace < result[j].ServiceAccountNamespace
+		}
+		return result[i].ServiceAccountName < result[j].ServiceAccountName
+	})
+
+	return result
+}
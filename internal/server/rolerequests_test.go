@@ -0,0 +1,116 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+const (
+	roles = `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: edit
+  namespace: eng
+rules:
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list"]
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: eng
+  namespace: eng
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: view-namespaces
+rules:
+- apiGroups: [""]
+  resources: ["namespaces"]
+  verbs: ["get"]
+`
+)
+
+var _ = Describe("RoleRequests", func() {
+	It("getRoleDefinitions returns only Roles and ClusterRoles", func() {
+		definitions, err := server.GetRoleDefinitions(roles)
+		Expect(err).To(BeNil())
+		Expect(len(definitions)).To(Equal(2))
+
+		Expect(definitions[0].Kind).To(Equal("Role"))
+		Expect(definitions[0].Name).To(Equal("edit"))
+		Expect(definitions[0].Namespace).To(Equal("eng"))
+		Expect(len(definitions[0].Rules)).To(Equal(1))
+		Expect(definitions[0].Rules[0].Resources).To(ContainElement("pods"))
+
+		Expect(definitions[1].Kind).To(Equal("ClusterRole"))
+		Expect(definitions[1].Name).To(Equal("view-namespaces"))
+		Expect(definitions[1].Namespace).To(BeEmpty())
+	})
+
+	It("getTenants returns tenants whose RoleRequests match the cluster", func() {
+		cluster := &corev1.ObjectReference{
+			Namespace:  randomString(),
+			Name:       randomString(),
+			Kind:       clusterv1.ClusterKind,
+			APIVersion: clusterv1.GroupVersion.String(),
+		}
+
+		serviceAccountNamespace := randomString()
+		serviceAccountName := randomString()
+
+		roleRequests := map[string]server.RoleRequestInfo{
+			"b": {
+				ServiceAccountNamespace: serviceAccountNamespace,
+				ServiceAccountName:      serviceAccountName,
+				MatchingClusters:        []corev1.ObjectReference{*cluster},
+			},
+			"a": {
+				ServiceAccountNamespace: serviceAccountNamespace,
+				ServiceAccountName:      serviceAccountName,
+				MatchingClusters:        []corev1.ObjectReference{*cluster},
+			},
+			// Same name but SveltosCluster: does not match
+			"c": {
+				ServiceAccountNamespace: randomString(),
+				ServiceAccountName:      randomString(),
+				MatchingClusters: []corev1.ObjectReference{
+					{
+						Namespace: cluster.Namespace, Name: cluster.Name,
+						Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+					},
+				},
+			},
+		}
+
+		tenants := server.GetTenants(roleRequests, cluster)
+		Expect(len(tenants)).To(Equal(1))
+		Expect(tenants[0].ServiceAccountNamespace).To(Equal(serviceAccountNamespace))
+		Expect(tenants[0].ServiceAccountName).To(Equal(serviceAccountName))
+		Expect(tenants[0].RoleRequests).To(Equal([]string{"a", "b"}))
+	})
+})
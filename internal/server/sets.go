@@ -0,0 +1,299 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// maxSelectionChanges is the maximum number of selection changes kept per ClusterSet/Set
+	maxSelectionChanges = 20
+)
+
+type SetInfo struct {
+	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector"`
+	ClusterRefs     []corev1.ObjectReference   `json:"clusterRefs"`
+
+	// MaxReplicas is the maximum number of clusters selected among the matching ones
+	MaxReplicas int `json:"maxReplicas"`
+
+	// MatchingClusters are the clusters currently matching the set
+	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
+
+	// SelectedClusters are the clusters currently selected among the matching ones
+	SelectedClusters []corev1.ObjectReference `json:"selectedClusters"`
+
+	// SelectionHistory contains the last changes to the selected clusters (for instance
+	// a failover), oldest first. Changes are tracked only since the backend started.
+	SelectionHistory []SelectionChange `json:"selectionHistory"`
+}
+
+// SelectionChange represents a change to the clusters selected by a ClusterSet/Set
+type SelectionChange struct {
+	Time metav1.Time `json:"time"`
+
+	// Selected are the clusters which got selected
+	Selected []corev1.ObjectReference `json:"selected"`
+
+	// Deselected are the clusters which are not selected anymore
+	Deselected []corev1.ObjectReference `json:"deselected"`
+}
+
+type SetResult struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	SetInfo `json:",inline"`
+}
+
+type SetsResult struct {
+	TotalSets int         `json:"totalSets"`
+	Sets      []SetResult `json:"sets"`
+}
+
+func (m *instance) AddClusterSet(clusterSet *libsveltosv1beta1.ClusterSet) {
+	m.addSet(getKeyFromObject(m.scheme, clusterSet), &clusterSet.Spec, &clusterSet.Status)
+}
+
+func (m *instance) RemoveClusterSet(clusterSetName string) {
+	clusterSet := &corev1.ObjectReference{
+		Name:       clusterSetName,
+		Kind:       libsveltosv1beta1.ClusterSetKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+
+	m.setMux.Lock()
+	defer m.setMux.Unlock()
+
+	delete(m.sets, *clusterSet)
+}
+
+func (m *instance) AddSet(set *libsveltosv1beta1.Set) {
+	m.addSet(getKeyFromObject(m.scheme, set), &set.Spec, &set.Status)
+}
+
+func (m *instance) RemoveSet(setNamespace, setName string) {
+	set := &corev1.ObjectReference{
+		Namespace:  setNamespace,
+		Name:       setName,
+		Kind:       libsveltosv1beta1.SetKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+
+	m.setMux.Lock()
+	defer m.setMux.Unlock()
+
+	delete(m.sets, *set)
+}
+
+func (m *instance) addSet(setRef *corev1.ObjectReference, spec *libsveltosv1beta1.Spec,
+	status *libsveltosv1beta1.Status) {
+
+	info := SetInfo{
+		ClusterSelector:  spec.ClusterSelector,
+		ClusterRefs:      spec.ClusterRefs,
+		MaxReplicas:      spec.MaxReplicas,
+		MatchingClusters: normalizeClusterRefs(status.MatchingClusterRefs),
+		SelectedClusters: normalizeClusterRefs(status.SelectedClusterRefs),
+	}
+
+	if info.ClusterRefs == nil {
+		info.ClusterRefs = make([]corev1.ObjectReference, 0)
+	}
+
+	m.setMux.Lock()
+	defer m.setMux.Unlock()
+
+	current, ok := m.sets[*setRef]
+	if !ok {
+		// First time set is seen, there is no previous selection to compare to
+		info.SelectionHistory = make([]SelectionChange, 0)
+	} else {
+		now := metav1.Now()
+		info.SelectionHistory = recordSelectionChange(current.SelectionHistory, current.SelectedClusters,
+			info.SelectedClusters, &now)
+	}
+
+	m.sets[*setRef] = info
+}
+
+// getSets returns all ClusterSets/Sets whose kind is in kinds and whose namespace/name contain
+// the given filters. Result is sorted by kind, namespace and name.
+// Only clusters present in the clusters map are reported.
+func (m *instance) getSets(kinds map[string]bool, namespaceFilter, nameFilter string,
+	clusters map[corev1.ObjectReference]ClusterInfo) []SetResult {
+
+	m.setMux.RLock()
+	defer m.setMux.RUnlock()
+
+	result := make([]SetResult, 0, len(m.sets))
+	for k := range m.sets {
+		if !kinds[k.Kind] {
+			continue
+		}
+		if namespaceFilter != "" && !strings.Contains(k.Namespace, namespaceFilter) {
+			continue
+		}
+		if nameFilter != "" && !strings.Contains(k.Name, nameFilter) {
+			continue
+		}
+
+		info := m.sets[k]
+		info.MatchingClusters = filterClusterRefs(info.MatchingClusters, clusters)
+		info.SelectedClusters = filterClusterRefs(info.SelectedClusters, clusters)
+
+		history := make([]SelectionChange, len(info.SelectionHistory))
+		for i := range info.SelectionHistory {
+			history[i] = SelectionChange{
+				Time:       info.SelectionHistory[i].Time,
+				Selected:   filterClusterRefs(info.SelectionHistory[i].Selected, clusters),
+				Deselected: filterClusterRefs(info.SelectionHistory[i].Deselected, clusters),
+			}
+		}
+		info.SelectionHistory = history
+
+		result = append(result, SetResult{
+			Kind:      k.Kind,
+			Namespace: k.Namespace,
+			Name:      k.Name,
+			SetInfo:   info,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// getContributingSets returns, among the sets referenced by a ClusterProfile/Profile,
+// the ones which currently select cluster
+func (m *instance) getContributingSets(profileRef *corev1.ObjectReference, setRefs []string,
+	cluster *corev1.ObjectReference) []string {
+
+	if len(setRefs) == 0 {
+		return nil
+	}
+
+	m.setMux.RLock()
+	defer m.setMux.RUnlock()
+
+	// ClusterProfile references ClusterSets, Profile references Sets in its namespace
+	setRef := &corev1.ObjectReference{
+		Kind:       libsveltosv1beta1.ClusterSetKind,
+		APIVersion: libsveltosv1beta1.GroupVersion.String(),
+	}
+	if profileRef.Kind == configv1beta1.ProfileKind {
+		setRef.Kind = libsveltosv1beta1.SetKind
+		setRef.Namespace = profileRef.Namespace
+	}
+
+	result := make([]string, 0)
+	for i := range setRefs {
+		setRef.Name = setRefs[i]
+		info, ok := m.sets[*setRef]
+		if !ok {
+			continue
+		}
+		for j := range info.SelectedClusters {
+			if isSameCluster(&info.SelectedClusters[j], cluster) {
+				result = append(result, setRefs[i])
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// recordSelectionChange adds to history the difference between previously and currently
+// selected clusters, if any. At most maxSelectionChanges are kept.
+func recordSelectionChange(history []SelectionChange, previous, current []corev1.ObjectReference,
+	now *metav1.Time) []SelectionChange {
+
+	change := SelectionChange{
+		Time:       *now,
+		Selected:   getMissingClusterRefs(current, previous),
+		Deselected: getMissingClusterRefs(previous, current),
+	}
+
+	if len(change.Selected) == 0 && len(change.Deselected) == 0 {
+		return history
+	}
+
+	history = append(history, change)
+	if len(history) > maxSelectionChanges {
+		history = history[len(history)-maxSelectionChanges:]
+	}
+	return history
+}
+
+// getMissingClusterRefs returns the clusters in refs which are not in others
+func getMissingClusterRefs(refs, others []corev1.ObjectReference) []corev1.ObjectReference {
+	result := make([]corev1.ObjectReference, 0)
+	for i := range refs {
+		found := false
+		for j := range others {
+			if isSameCluster(&refs[i], &others[j]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, refs[i])
+		}
+	}
+	return result
+}
+
+func normalizeClusterRefs(refs []corev1.ObjectReference) []corev1.ObjectReference {
+	result := make([]corev1.ObjectReference, len(refs))
+	for i := range refs {
+		result[i] = *normalizeClusterRef(&refs[i])
+	}
+	sortClusterRefs(result)
+	return result
+}
+
+// filterClusterRefs returns the clusters present in the clusters map
+func filterClusterRefs(refs []corev1.ObjectReference, clusters map[corev1.ObjectReference]ClusterInfo,
+) []corev1.ObjectReference {
+
+	result := make([]corev1.ObjectReference, 0, len(refs))
+	for i := range refs {
+		if _, ok := clusters[refs[i]]; ok {
+			result = append(result, refs[i])
+		}
+	}
+	return result
+}
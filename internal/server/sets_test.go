@@ -0,0 +1,105 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Sets", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+	})
+
+	It("recordSelectionChange records selected and deselected clusters", func() {
+		cluster1 := corev1.ObjectReference{
+			Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+		cluster2 := corev1.ObjectReference{
+			Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		now := metav1.Now()
+
+		// No change
+		history := server.RecordSelectionChange(nil, []corev1.ObjectReference{cluster1},
+			[]corev1.ObjectReference{cluster1}, &now)
+		Expect(len(history)).To(Equal(0))
+
+		// Failover from cluster1 to cluster2
+		history = server.RecordSelectionChange(history, []corev1.ObjectReference{cluster1},
+			[]corev1.ObjectReference{cluster2}, &now)
+		Expect(len(history)).To(Equal(1))
+		Expect(history[0].Selected).To(Equal([]corev1.ObjectReference{cluster2}))
+		Expect(history[0].Deselected).To(Equal([]corev1.ObjectReference{cluster1}))
+	})
+
+	It("getContributingSets returns sets selecting the cluster", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		server.InitializeManagerInstance(context.TODO(), nil, c, scheme, randomPort(), logger)
+		manager := server.GetManagerInstance()
+
+		cluster := corev1.ObjectReference{
+			Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		selecting := &libsveltosv1beta1.Set{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: randomString()},
+			Status:     libsveltosv1beta1.Status{SelectedClusterRefs: []corev1.ObjectReference{cluster}},
+		}
+		notSelecting := &libsveltosv1beta1.Set{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: randomString()},
+			Status:     libsveltosv1beta1.Status{MatchingClusterRefs: []corev1.ObjectReference{cluster}},
+		}
+		manager.AddSet(selecting)
+		manager.AddSet(notSelecting)
+
+		profileRef := &corev1.ObjectReference{
+			Kind: configv1beta1.ProfileKind, Namespace: cluster.Namespace, Name: randomString(),
+		}
+
+		sets := manager.GetContributingSets(profileRef, []string{selecting.Name, notSelecting.Name}, &cluster)
+		Expect(sets).To(Equal([]string{selecting.Name}))
+
+		// ClusterProfile references ClusterSets only
+		profileRef.Kind = configv1beta1.ClusterProfileKind
+		profileRef.Namespace = ""
+		sets = manager.GetContributingSets(profileRef, []string{selecting.Name}, &cluster)
+		Expect(len(sets)).To(Equal(0))
+
+		manager.RemoveSet(selecting.Namespace, selecting.Name)
+		manager.RemoveSet(notSelecting.Namespace, notSelecting.Name)
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+type SpecChangeType string
+
+const (
+	SpecChangeAdded    = SpecChangeType("Added")
+	SpecChangeRemoved  = SpecChangeType("Removed")
+	SpecChangeModified = SpecChangeType("Modified")
+)
+
+// SpecChange is a single field difference between two ClusterProfile/Profile specs
+type SpecChange struct {
+	// Path of the changed field (e.g. spec.clusterSelector.matchLabels.env or spec.policyRefs[0].name)
+	Path string         `json:"path"`
+	Type SpecChangeType `json:"type"`
+
+	OldValue any `json:"oldValue,omitempty"`
+	NewValue any `json:"newValue,omitempty"`
+}
+
+// diffSpecs returns the fields which differ between oldSpec and newSpec, sorted by path.
+// Lists with a different length are reported as a single change.
+func diffSpecs(oldSpec, newSpec *configv1beta1.Spec) ([]SpecChange, error) {
+	oldValue, err := toUnstructuredValue(oldSpec)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := toUnstructuredValue(newSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]SpecChange, 0)
+	diffValues("spec", oldValue, newValue, &changes)
+	return changes, nil
+}
+
+// toUnstructuredValue returns the JSON representation of obj as maps, slices and scalars
+func toUnstructuredValue(obj any) (any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func diffValues(path string, oldValue, newValue any, changes *[]SpecChange) {
+	switch {
+	case oldValue == nil && newValue == nil:
+		return
+	case oldValue == nil:
+		*changes = append(*changes, SpecChange{Path: path, Type: SpecChangeAdded, NewValue: newValue})
+		return
+	case newValue == nil:
+		*changes = append(*changes, SpecChange{Path: path, Type: SpecChangeRemoved, OldValue: oldValue})
+		return
+	}
+
+	oldMap, oldIsMap := oldValue.(map[string]any)
+	newMap, newIsMap := newValue.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make(map[string]bool, len(oldMap)+len(newMap))
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, k := range sortedKeys {
+			diffValues(path+"."+k, oldMap[k], newMap[k], changes)
+		}
+		return
+	}
+
+	oldSlice, oldIsSlice := oldValue.([]any)
+	newSlice, newIsSlice := newValue.([]any)
+	if oldIsSlice && newIsSlice && len(oldSlice) == len(newSlice) {
+		for i := range oldSlice {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), oldSlice[i], newSlice[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, SpecChange{Path: path, Type: SpecChangeModified, OldValue: oldValue,
+			NewValue: newValue})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("Spec diff", func() {
+	It("diffSpecs reports added, removed and modified fields", func() {
+		oldSpec := &configv1beta1.Spec{
+			SyncMode: configv1beta1.SyncModeDryRun,
+			ClusterSelector: libsveltosv1beta1.Selector{
+				LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging", "region": "eu"}},
+			},
+			PolicyRefs: []configv1beta1.PolicyRef{
+				{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind), Namespace: "default", Name: "nginx"},
+			},
+		}
+		newSpec := oldSpec.DeepCopy()
+		newSpec.SyncMode = configv1beta1.SyncModeContinuous
+		newSpec.ClusterSelector.MatchLabels = map[string]string{"env": "production", "tier": "web"}
+		newSpec.PolicyRefs[0].Name = "nginx-v2"
+		newSpec.DependsOn = []string{"base"}
+
+		changes, err := server.DiffSpecs(oldSpec, newSpec)
+		Expect(err).To(BeNil())
+		Expect(changes).To(Equal([]server.SpecChange{
+			{Path: "spec.clusterSelector.matchLabels.env", Type: server.SpecChangeModified,
+				OldValue: "staging", NewValue: "production"},
+			{Path: "spec.clusterSelector.matchLabels.region", Type: server.SpecChangeRemoved, OldValue: "eu"},
+			{Path: "spec.clusterSelector.matchLabels.tier", Type: server.SpecChangeAdded, NewValue: "web"},
+			{Path: "spec.dependsOn", Type: server.SpecChangeAdded, NewValue: []any{"base"}},
+			{Path: "spec.policyRefs[0].name", Type: server.SpecChangeModified, OldValue: "nginx", NewValue: "nginx-v2"},
+			{Path: "spec.syncMode", Type: server.SpecChangeModified, OldValue: "DryRun", NewValue: "Continuous"},
+		}))
+
+		changes, err = server.DiffSpecs(oldSpec, oldSpec.DeepCopy())
+		Expect(err).To(BeNil())
+		Expect(changes).To(BeEmpty())
+	})
+})
@@ -0,0 +1,234 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// defaultTokenExpiringWithin is used by the fleet connectivity view when no window is specified
+	defaultTokenExpiringWithin = 24 * time.Hour
+)
+
+// SveltosClusterInfo contains information specific to SveltosClusters
+type SveltosClusterInfo struct {
+	// ConnectionStatus is the status of the connection from the management cluster (Healthy, Down)
+	ConnectionStatus libsveltosv1beta1.ConnectionStatus `json:"connectionStatus"`
+
+	// ConnectionFailures is the number of consecutive failed attempts to connect to the cluster
+	ConnectionFailures int `json:"connectionFailures"`
+
+	// ConsecutiveFailureThreshold is the number of consecutive failures after which connection is Down
+	ConsecutiveFailureThreshold int `json:"consecutiveFailureThreshold,omitempty"`
+
+	// LastHealthyConnection is the last time the backend observed a healthy connection to the cluster.
+	// It is tracked only since the backend started.
+	LastHealthyConnection *metav1.Time `json:"lastHealthyConnection,omitempty"`
+
+	// TokenRenewal is set only when token renewal is configured for the cluster
+	TokenRenewal *TokenRenewalInfo `json:"tokenRenewal,omitempty"`
+
+	Paused      bool         `json:"paused"`
+	NextPause   *metav1.Time `json:"nextPause,omitempty"`
+	NextUnpause *metav1.Time `json:"nextUnpause,omitempty"`
+}
+
+// TokenRenewalInfo contains the token renewal configuration of a SveltosCluster
+type TokenRenewalInfo struct {
+	RenewTokenRequestInterval metav1.Duration `json:"renewTokenRequestInterval"`
+	TokenDuration             metav1.Duration `json:"tokenDuration"`
+	ServiceAccountNamespace   string          `json:"serviceAccountNamespace,omitempty"`
+	ServiceAccountName        string          `json:"serviceAccountName,omitempty"`
+
+	// LastRenewal is the last time the token was renewed. Nil if the token was never renewed
+	// or the time could not be parsed.
+	LastRenewal *metav1.Time `json:"lastRenewal,omitempty"`
+
+	// NextRenewal and Expiration are derived from LastRenewal
+	NextRenewal *metav1.Time `json:"nextRenewal,omitempty"`
+	Expiration  *metav1.Time `json:"expiration,omitempty"`
+}
+
+// ConnectivityIssue is a SveltosCluster whose connection is degraded or whose token is about to expire
+type ConnectivityIssue struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	ConnectionDegraded bool `json:"connectionDegraded"`
+	TokenExpiring      bool `json:"tokenExpiring"`
+
+	SveltosClusterInfo `json:",inline"`
+}
+
+type FleetConnectivityResult struct {
+	TotalClusters           int                 `json:"totalClusters"`
+	DegradedClusters        int                 `json:"degradedClusters"`
+	ExpiringTokenClusters   int                 `json:"expiringTokenClusters"`
+	TotalConnectivityIssues int                 `json:"totalConnectivityIssues"`
+	ConnectivityIssues      []ConnectivityIssue `json:"connectivityIssues"`
+}
+
+// getSveltosClusterInfo returns ClusterInfo for a SveltosCluster. lastHealthyConnection is the
+// last time a healthy connection was observed, if any, and is reported as is.
+func getSveltosClusterInfo(sveltosCluster *libsveltosv1beta1.SveltosCluster, lastHealthyConnection *metav1.Time,
+) ClusterInfo {
+
+	return ClusterInfo{
+		Labels:         sveltosCluster.Labels,
+		Version:        sveltosCluster.Status.Version,
+		Ready:          sveltosCluster.Status.Ready,
+		FailureMessage: sveltosCluster.Status.FailureMessage,
+		Conditions:     getSveltosClusterConditions(sveltosCluster),
+		Sveltos: &SveltosClusterInfo{
+			ConnectionStatus:            sveltosCluster.Status.ConnectionStatus,
+			ConnectionFailures:          sveltosCluster.Status.ConnectionFailures,
+			ConsecutiveFailureThreshold: sveltosCluster.Spec.ConsecutiveFailureThreshold,
+			LastHealthyConnection:       lastHealthyConnection,
+			TokenRenewal:                getTokenRenewalInfo(sveltosCluster),
+			Paused:                      sveltosCluster.Spec.Paused,
+			NextPause:                   sveltosCluster.Status.NextPause,
+			NextUnpause:                 sveltosCluster.Status.NextUnpause,
+		},
+	}
+}
+
+// getLastHealthyConnection returns the last time a healthy connection to the SveltosCluster was observed
+func (m *instance) getLastHealthyConnection(sveltosClusterRef *corev1.ObjectReference) *metav1.Time {
+	m.clusterMux.RLock()
+	defer m.clusterMux.RUnlock()
+
+	return getLastHealthyConnection(m.sveltosClusters, sveltosClusterRef)
+}
+
+func getLastHealthyConnection(sveltosClusters map[corev1.ObjectReference]ClusterInfo,
+	sveltosClusterRef *corev1.ObjectReference) *metav1.Time {
+
+	info, ok := sveltosClusters[*sveltosClusterRef]
+	if !ok || info.Sveltos == nil {
+		return nil
+	}
+	return info.Sveltos.LastHealthyConnection
+}
+
+func isConnectionHealthy(sveltosCluster *libsveltosv1beta1.SveltosCluster) bool {
+	return sveltosCluster.Status.ConnectionStatus == libsveltosv1beta1.ConnectionHealthy &&
+		sveltosCluster.Status.ConnectionFailures == 0
+}
+
+func getTokenRenewalInfo(sveltosCluster *libsveltosv1beta1.SveltosCluster) *TokenRenewalInfo {
+	option := sveltosCluster.Spec.TokenRequestRenewalOption
+	if option == nil {
+		return nil
+	}
+
+	result := &TokenRenewalInfo{
+		RenewTokenRequestInterval: option.RenewTokenRequestInterval,
+		TokenDuration:             option.TokenDuration,
+		ServiceAccountNamespace:   option.SANamespace,
+		ServiceAccountName:        option.SAName,
+	}
+
+	// When not specified, token is valid for RenewTokenRequestInterval
+	if result.TokenDuration.Duration == 0 {
+		result.TokenDuration = result.RenewTokenRequestInterval
+	}
+
+	lastRenewal, err := parseTokenRequestTime(sveltosCluster.Status.LastReconciledTokenRequestAt)
+	if err != nil {
+		return result
+	}
+
+	result.LastRenewal = &metav1.Time{Time: lastRenewal}
+	result.NextRenewal = &metav1.Time{Time: lastRenewal.Add(result.RenewTokenRequestInterval.Duration)}
+	result.Expiration = &metav1.Time{Time: lastRenewal.Add(result.TokenDuration.Duration)}
+
+	return result
+}
+
+// parseTokenRequestTime parses SveltosCluster Status.LastReconciledTokenRequestAt. Both RFC3339
+// and the format produced by time.Time String method are accepted.
+func parseTokenRequestTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("token was never renewed")
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	// Strip monotonic clock reading, if any
+	if index := strings.Index(value, " m="); index != -1 {
+		value = value[:index]
+	}
+	return time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", value)
+}
+
+// evaluateFleetConnectivity returns the SveltosClusters whose connection is degraded or whose token
+// expires within expiringWithin. Clusters which are not SveltosClusters are ignored.
+func evaluateFleetConnectivity(clusters map[corev1.ObjectReference]ClusterInfo, now time.Time,
+	expiringWithin time.Duration) *FleetConnectivityResult {
+
+	result := &FleetConnectivityResult{
+		ConnectivityIssues: make([]ConnectivityIssue, 0),
+	}
+
+	for k := range clusters {
+		info := clusters[k].Sveltos
+		if info == nil {
+			continue
+		}
+		result.TotalClusters++
+
+		issue := ConnectivityIssue{
+			Namespace:          k.Namespace,
+			Name:               k.Name,
+			ConnectionDegraded: info.ConnectionStatus == libsveltosv1beta1.ConnectionDown || info.ConnectionFailures > 0,
+			TokenExpiring: info.TokenRenewal != nil && info.TokenRenewal.Expiration != nil &&
+				info.TokenRenewal.Expiration.Time.Before(now.Add(expiringWithin)),
+			SveltosClusterInfo: *info,
+		}
+
+		if issue.ConnectionDegraded {
+			result.DegradedClusters++
+		}
+		if issue.TokenExpiring {
+			result.ExpiringTokenClusters++
+		}
+		if issue.ConnectionDegraded || issue.TokenExpiring {
+			result.ConnectivityIssues = append(result.ConnectivityIssues, issue)
+		}
+	}
+
+	sort.Slice(result.ConnectivityIssues, func(i, j int) bool {
+		if result.ConnectivityIssues[i].Namespace != result.ConnectivityIssues[j].Namespace {
+			return result.ConnectivityIssues[i].Namespace < result.ConnectivityIssues[j].Namespace
+		}
+		return result.ConnectivityIssues[i].Name < result.ConnectivityIssues[j].Name
+	})
+	result.TotalConnectivityIssues = len(result.ConnectivityIssues)
+
+	return result
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("SveltosClusters", func() {
+	It("parseTokenRequestTime accepts RFC3339 and time.Time String formats", func() {
+		now := time.Now().Truncate(time.Second)
+
+		t, err := server.ParseTokenRequestTime(now.Format(time.RFC3339))
+		Expect(err).To(BeNil())
+		Expect(t.Equal(now)).To(BeTrue())
+
+		// String includes the monotonic clock reading
+		t, err = server.ParseTokenRequestTime(time.Now().String())
+		Expect(err).To(BeNil())
+		Expect(t.IsZero()).To(BeFalse())
+
+		_, err = server.ParseTokenRequestTime("")
+		Expect(err).ToNot(BeNil())
+
+		_, err = server.ParseTokenRequestTime(randomString())
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("getSveltosClusterInfo reports connectivity and token renewal details", func() {
+		lastRenewal := time.Now().Add(-time.Hour).Truncate(time.Second)
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: libsveltosv1beta1.SveltosClusterSpec{
+				Paused:                      true,
+				ConsecutiveFailureThreshold: 5,
+				TokenRequestRenewalOption: &libsveltosv1beta1.TokenRequestRenewalOption{
+					RenewTokenRequestInterval: metav1.Duration{Duration: 2 * time.Hour},
+				},
+			},
+			Status: libsveltosv1beta1.SveltosClusterStatus{
+				ConnectionStatus:             libsveltosv1beta1.ConnectionDown,
+				ConnectionFailures:           6,
+				LastReconciledTokenRequestAt: lastRenewal.Format(time.RFC3339),
+			},
+		}
+
+		previous := metav1.NewTime(time.Now().Add(-time.Minute))
+		info := server.GetSveltosClusterInfo(sveltosCluster, &previous)
+		Expect(info.Sveltos).ToNot(BeNil())
+		Expect(info.Sveltos.ConnectionStatus).To(Equal(libsveltosv1beta1.ConnectionDown))
+		Expect(info.Sveltos.ConnectionFailures).To(Equal(6))
+		Expect(info.Sveltos.ConsecutiveFailureThreshold).To(Equal(5))
+		Expect(info.Sveltos.Paused).To(BeTrue())
+		// Connection is not healthy, previously observed healthy connection is kept
+		Expect(info.Sveltos.LastHealthyConnection).To(Equal(&previous))
+
+		Expect(info.Sveltos.TokenRenewal).ToNot(BeNil())
+		// TokenDuration defaults to RenewTokenRequestInterval
+		Expect(info.Sveltos.TokenRenewal.TokenDuration.Duration).To(Equal(2 * time.Hour))
+		Expect(info.Sveltos.TokenRenewal.LastRenewal.Time.Equal(lastRenewal)).To(BeTrue())
+		Expect(info.Sveltos.TokenRenewal.Expiration.Time.Equal(lastRenewal.Add(2 * time.Hour))).To(BeTrue())
+
+		sveltosCluster.Status.ConnectionStatus = libsveltosv1beta1.ConnectionHealthy
+		sveltosCluster.Status.ConnectionFailures = 0
+		// Reading a SveltosCluster does not count as observing a healthy connection
+		info = server.GetSveltosClusterInfo(sveltosCluster, &previous)
+		Expect(info.Sveltos.LastHealthyConnection).To(Equal(&previous))
+
+		sveltosCluster.Spec.TokenRequestRenewalOption = nil
+		info = server.GetSveltosClusterInfo(sveltosCluster, nil)
+		Expect(info.Sveltos.TokenRenewal).To(BeNil())
+	})
+
+	It("evaluateFleetConnectivity reports degraded clusters and expiring tokens", func() {
+		now := time.Now()
+
+		getRef := func(name string) corev1.ObjectReference {
+			return corev1.ObjectReference{
+				Namespace: "default", Name: name,
+				Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+			}
+		}
+		expiration := func(d time.Duration) *server.TokenRenewalInfo {
+			return &server.TokenRenewalInfo{Expiration: &metav1.Time{Time: now.Add(d)}}
+		}
+
+		clusters := map[corev1.ObjectReference]server.ClusterInfo{
+			getRef("healthy"): {Sveltos: &server.SveltosClusterInfo{
+				ConnectionStatus: libsveltosv1beta1.ConnectionHealthy, TokenRenewal: expiration(48 * time.Hour)}},
+			getRef("down"): {Sveltos: &server.SveltosClusterInfo{
+				ConnectionStatus: libsveltosv1beta1.ConnectionDown, ConnectionFailures: 3}},
+			getRef("failing"): {Sveltos: &server.SveltosClusterInfo{
+				ConnectionStatus: libsveltosv1beta1.ConnectionHealthy, ConnectionFailures: 1}},
+			getRef("expiring"): {Sveltos: &server.SveltosClusterInfo{
+				ConnectionStatus: libsveltosv1beta1.ConnectionHealthy, TokenRenewal: expiration(time.Hour)}},
+			// Not a SveltosCluster
+			{Namespace: "default", Name: "capi"}: {},
+		}
+
+		result := server.EvaluateFleetConnectivity(clusters, now, 24*time.Hour)
+		Expect(result.TotalClusters).To(Equal(4))
+		Expect(result.DegradedClusters).To(Equal(2))
+		Expect(result.ExpiringTokenClusters).To(Equal(1))
+		Expect(result.TotalConnectivityIssues).To(Equal(3))
+		Expect(result.ConnectivityIssues[0].Name).To(Equal("down"))
+		Expect(result.ConnectivityIssues[0].ConnectionDegraded).To(BeTrue())
+		Expect(result.ConnectivityIssues[1].Name).To(Equal("expiring"))
+		Expect(result.ConnectivityIssues[1].TokenExpiring).To(BeTrue())
+		Expect(result.ConnectivityIssues[1].ConnectionDegraded).To(BeFalse())
+		Expect(result.ConnectivityIssues[2].Name).To(Equal("failing"))
+
+		// A wider window also reports the healthy cluster token
+		result = server.EvaluateFleetConnectivity(clusters, now, 72*time.Hour)
+		Expect(result.ExpiringTokenClusters).To(Equal(2))
+		Expect(result.TotalConnectivityIssues).To(Equal(4))
+	})
+})
@@ -0,0 +1,201 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// SyncModeRequest is the body of a sync mode change request
+type SyncModeRequest struct {
+	SyncMode configv1beta1.SyncMode `json:"syncMode"`
+
+	// ConfirmationToken is required when moving a profile out of DryRun. It is returned
+	// by a previous request made without it.
+	ConfirmationToken string `json:"confirmationToken,omitempty"`
+}
+
+type SyncModeResult struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	PreviousSyncMode configv1beta1.SyncMode `json:"previousSyncMode"`
+	SyncMode         configv1beta1.SyncMode `json:"syncMode"`
+
+	// Changed is true when the sync mode was updated
+	Changed bool `json:"changed"`
+
+	// ConfirmationRequired is true when the change was not applied because the confirmation
+	// token was missing or no longer valid. ConfirmationToken and DryRunSummary are set.
+	ConfirmationRequired bool           `json:"confirmationRequired,omitempty"`
+	ConfirmationToken    string         `json:"confirmationToken,omitempty"`
+	DryRunSummary        *DryRunSummary `json:"dryRunSummary,omitempty"`
+}
+
+// DryRunSummary summarizes what addon-controller would do when moving a profile out of DryRun
+type DryRunSummary struct {
+	TotalClusters int `json:"totalClusters"`
+
+	// ReleaseActions counts planned helm release actions (e.g. Install, Upgrade) across clusters
+	ReleaseActions map[string]int `json:"releaseActions"`
+
+	// ResourceActions counts planned resource actions (e.g. Create, Update) across clusters.
+	// Both resources deployed because of PolicyRefs and KustomizationRefs are counted.
+	ResourceActions map[string]int `json:"resourceActions"`
+
+	Clusters []DryRunClusterSummary `json:"clusters"`
+}
+
+type DryRunClusterSummary struct {
+	ClusterNamespace string                        `json:"clusterNamespace"`
+	ClusterName      string                        `json:"clusterName"`
+	ClusterType      libsveltosv1beta1.ClusterType `json:"clusterType"`
+	ReleaseActions   map[string]int                `json:"releaseActions"`
+	ResourceActions  map[string]int                `json:"resourceActions"`
+}
+
+func newConfirmationKey() []byte {
+	key := make([]byte, sha256.Size)
+	// crypto/rand Read never returns an error
+	_, _ = rand.Read(key)
+	return key
+}
+
+func isSupportedSyncMode(syncMode configv1beta1.SyncMode) bool {
+	switch syncMode {
+	case configv1beta1.SyncModeOneTime, configv1beta1.SyncModeContinuous,
+		configv1beta1.SyncModeContinuousWithDriftDetection, configv1beta1.SyncModeDryRun:
+		return true
+	default:
+		return false
+	}
+}
+
+// getSyncModeConfirmationToken returns the token confirming that user wants to move the profile, at
+// the given generation, to syncMode. Any change to the profile spec invalidates the token.
+func getSyncModeConfirmationToken(key []byte, profileRef *corev1.ObjectReference, generation int64,
+	syncMode configv1beta1.SyncMode, user string) string {
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\x00%s\x00%s\x00%d\x00%s\x00%s",
+		profileRef.Kind, profileRef.Namespace, profileRef.Name, generation, syncMode, user)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getDryRunSummary counts planned actions in DryRun reports
+func getDryRunSummary(reports []DryRunReport) *DryRunSummary {
+	summary := &DryRunSummary{
+		TotalClusters:   len(reports),
+		ReleaseActions:  map[string]int{},
+		ResourceActions: map[string]int{},
+		Clusters:        make([]DryRunClusterSummary, len(reports)),
+	}
+
+	for i := range reports {
+		clusterSummary := DryRunClusterSummary{
+			ClusterNamespace: reports[i].ClusterNamespace,
+			ClusterName:      reports[i].ClusterName,
+			ClusterType:      reports[i].ClusterType,
+			ReleaseActions:   map[string]int{},
+			ResourceActions:  map[string]int{},
+		}
+
+		for j := range reports[i].ReleaseReports {
+			clusterSummary.ReleaseActions[reports[i].ReleaseReports[j].Action]++
+			summary.ReleaseActions[reports[i].ReleaseReports[j].Action]++
+		}
+		for _, resourceReports := range [][]configv1beta1.ResourceReport{reports[i].ResourceReports,
+			reports[i].KustomizeResourceReports} {
+
+			for j := range resourceReports {
+				clusterSummary.ResourceActions[resourceReports[j].Action]++
+				summary.ResourceActions[resourceReports[j].Action]++
+			}
+		}
+
+		summary.Clusters[i] = clusterSummary
+	}
+
+	return summary
+}
+
+// setProfileSyncMode changes Spec.SyncMode of a ClusterProfile/Profile. Moving a profile out of DryRun
+// requires a valid confirmation token: when missing, the change is not applied and the result contains
+// the token along with the summary of the DryRun reports.
+func setProfileSyncMode(ctx context.Context, c client.Client, key []byte, profileRef *corev1.ObjectReference,
+	request *SyncModeRequest, user string, dryRunReports []DryRunReport) (*SyncModeResult, error) {
+
+	var profile client.Object
+	var spec *configv1beta1.Spec
+	if profileRef.Kind == configv1beta1.ClusterProfileKind {
+		clusterProfile := &configv1beta1.ClusterProfile{}
+		profile, spec = clusterProfile, &clusterProfile.Spec
+	} else {
+		p := &configv1beta1.Profile{}
+		profile, spec = p, &p.Spec
+	}
+
+	err := c.Get(ctx, types.NamespacedName{Namespace: profileRef.Namespace, Name: profileRef.Name}, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncModeResult{
+		Kind:             profileRef.Kind,
+		Namespace:        profileRef.Namespace,
+		Name:             profileRef.Name,
+		PreviousSyncMode: spec.SyncMode,
+		SyncMode:         request.SyncMode,
+	}
+
+	if spec.SyncMode == request.SyncMode {
+		return result, nil
+	}
+
+	if spec.SyncMode == configv1beta1.SyncModeDryRun {
+		token := getSyncModeConfirmationToken(key, profileRef, profile.GetGeneration(), request.SyncMode, user)
+		if !hmac.Equal([]byte(request.ConfirmationToken), []byte(token)) {
+			result.ConfirmationRequired = true
+			result.ConfirmationToken = token
+			result.DryRunSummary = getDryRunSummary(dryRunReports)
+			return result, nil
+		}
+	}
+
+	// Optimistic lock guarantees profile did not change after the confirmation token was verified
+	patch := client.MergeFromWithOptions(profile.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	spec.SyncMode = request.SyncMode
+	if err := c.Patch(ctx, profile, patch); err != nil {
+		return nil, err
+	}
+
+	result.Changed = true
+	return result, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/ui-backend/internal/server"
+)
+
+var _ = Describe("SyncMode", func() {
+	key := []byte(randomString())
+
+	It("getSyncModeConfirmationToken depends on profile generation, sync mode and user", func() {
+		profileRef := &corev1.ObjectReference{Kind: configv1beta1.ClusterProfileKind, Name: randomString()}
+		user := randomString()
+
+		token := server.GetSyncModeConfirmationToken(key, profileRef, 1, configv1beta1.SyncModeContinuous, user)
+		Expect(server.GetSyncModeConfirmationToken(key, profileRef, 1, configv1beta1.SyncModeContinuous, user)).To(
+			Equal(token))
+		Expect(server.GetSyncModeConfirmationToken(key, profileRef, 2, configv1beta1.SyncModeContinuous, user)).ToNot(
+			Equal(token))
+		Expect(server.GetSyncModeConfirmationToken(key, profileRef, 1, configv1beta1.SyncModeOneTime, user)).ToNot(
+			Equal(token))
+		Expect(server.GetSyncModeConfirmationToken(key, profileRef, 1, configv1beta1.SyncModeContinuous,
+			randomString())).ToNot(Equal(token))
+		Expect(server.GetSyncModeConfirmationToken([]byte(randomString()), profileRef, 1,
+			configv1beta1.SyncModeContinuous, user)).ToNot(Equal(token))
+	})
+
+	It("getDryRunSummary counts planned actions", func() {
+		reports := []server.DryRunReport{
+			{
+				ClusterNamespace: randomString(), ClusterName: randomString(), ClusterType: libsveltosv1beta1.ClusterTypeSveltos,
+				ReleaseReports:           []configv1beta1.ReleaseReport{{Action: "Install"}, {Action: "Upgrade"}},
+				ResourceReports:          []configv1beta1.ResourceReport{{Action: "Create"}},
+				KustomizeResourceReports: []configv1beta1.ResourceReport{{Action: "Create"}, {Action: "Update"}},
+			},
+			{
+				ClusterNamespace: randomString(), ClusterName: randomString(), ClusterType: libsveltosv1beta1.ClusterTypeCapi,
+				ReleaseReports: []configv1beta1.ReleaseReport{{Action: "Install"}},
+			},
+		}
+
+		summary := server.GetDryRunSummary(reports)
+		Expect(summary.TotalClusters).To(Equal(2))
+		Expect(summary.ReleaseActions).To(Equal(map[string]int{"Install": 2, "Upgrade": 1}))
+		Expect(summary.ResourceActions).To(Equal(map[string]int{"Create": 2, "Update": 1}))
+		Expect(summary.Clusters).To(HaveLen(2))
+		Expect(summary.Clusters[1].ReleaseActions).To(Equal(map[string]int{"Install": 1}))
+		Expect(summary.Clusters[1].ResourceActions).To(BeEmpty())
+	})
+
+	It("setProfileSyncMode requires confirmation token when moving out of DryRun", func() {
+		profile := &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString(), Generation: 3},
+			Spec:       configv1beta1.Spec{SyncMode: configv1beta1.SyncModeDryRun},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build()
+
+		profileRef := &corev1.ObjectReference{
+			Kind: configv1beta1.ProfileKind, APIVersion: configv1beta1.GroupVersion.String(),
+			Namespace: profile.Namespace, Name: profile.Name,
+		}
+		user := randomString()
+		request := &server.SyncModeRequest{SyncMode: configv1beta1.SyncModeContinuous}
+
+		result, err := server.SetProfileSyncMode(context.TODO(), c, key, profileRef, request, user, nil)
+		Expect(err).To(BeNil())
+		Expect(result.Changed).To(BeFalse())
+		Expect(result.ConfirmationRequired).To(BeTrue())
+		Expect(result.ConfirmationToken).ToNot(BeEmpty())
+		Expect(result.DryRunSummary).ToNot(BeNil())
+
+		current := &configv1beta1.Profile{}
+		profileKey := types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name}
+		Expect(c.Get(context.TODO(), profileKey, current)).To(Succeed())
+		Expect(current.Spec.SyncMode).To(Equal(configv1beta1.SyncModeDryRun))
+
+		// Token issued for a different user is rejected
+		request.ConfirmationToken = result.ConfirmationToken
+		result, err = server.SetProfileSyncMode(context.TODO(), c, key, profileRef, request, randomString(), nil)
+		Expect(err).To(BeNil())
+		Expect(result.ConfirmationRequired).To(BeTrue())
+
+		result, err = server.SetProfileSyncMode(context.TODO(), c, key, profileRef, request, user, nil)
+		Expect(err).To(BeNil())
+		Expect(result.Changed).To(BeTrue())
+		Expect(result.PreviousSyncMode).To(Equal(configv1beta1.SyncModeDryRun))
+		Expect(c.Get(context.TODO(), profileKey, current)).To(Succeed())
+		Expect(current.Spec.SyncMode).To(Equal(configv1beta1.SyncModeContinuous))
+
+		// Moving between modes other than DryRun does not require confirmation
+		request = &server.SyncModeRequest{SyncMode: configv1beta1.SyncModeContinuousWithDriftDetection}
+		result, err = server.SetProfileSyncMode(context.TODO(), c, key, profileRef, request, user, nil)
+		Expect(err).To(BeNil())
+		Expect(result.Changed).To(BeTrue())
+		Expect(c.Get(context.TODO(), profileKey, current)).To(Succeed())
+		Expect(current.Spec.SyncMode).To(Equal(configv1beta1.SyncModeContinuousWithDriftDetection))
+	})
+})
@@ -20,9 +20,97 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// sveltosClusterReadyCondition and sveltosClusterConnectedCondition are derived from SveltosCluster Status
+	sveltosClusterReadyCondition     = "Ready"
+	sveltosClusterConnectedCondition = "Connected"
 )
 
+// ClusterCondition is a condition of a CAPI Cluster or SveltosCluster
+type ClusterCondition struct {
+	Type     string                 `json:"type"`
+	Status   corev1.ConditionStatus `json:"status"`
+	Severity string                 `json:"severity,omitempty"`
+	Reason   string                 `json:"reason,omitempty"`
+	Message  string                 `json:"message,omitempty"`
+
+	// LastTransitionTime is not set for conditions derived from SveltosCluster Status
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// V1Beta2 is true for ClusterAPI v1beta2 conditions
+	V1Beta2 bool `json:"v1beta2,omitempty"`
+}
+
+// getCAPIClusterConditions returns both v1beta1 and v1beta2 conditions of a ClusterAPI Cluster
+func getCAPIClusterConditions(cluster *clusterv1.Cluster) []ClusterCondition {
+	result := make([]ClusterCondition, 0, len(cluster.Status.Conditions))
+	for i := range cluster.Status.Conditions {
+		c := &cluster.Status.Conditions[i]
+		lastTransitionTime := c.LastTransitionTime
+		result = append(result, ClusterCondition{
+			Type:               string(c.Type),
+			Status:             c.Status,
+			Severity:           string(c.Severity),
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: &lastTransitionTime,
+		})
+	}
+
+	if cluster.Status.V1Beta2 != nil {
+		for i := range cluster.Status.V1Beta2.Conditions {
+			c := &cluster.Status.V1Beta2.Conditions[i]
+			lastTransitionTime := c.LastTransitionTime
+			result = append(result, ClusterCondition{
+				Type:               c.Type,
+				Status:             corev1.ConditionStatus(c.Status),
+				Reason:             c.Reason,
+				Message:            c.Message,
+				LastTransitionTime: &lastTransitionTime,
+				V1Beta2:            true,
+			})
+		}
+	}
+
+	return result
+}
+
+// getSveltosClusterConditions returns conditions derived from SveltosCluster Status
+func getSveltosClusterConditions(sveltosCluster *libsveltosv1beta1.SveltosCluster) []ClusterCondition {
+	ready := ClusterCondition{
+		Type:   sveltosClusterReadyCondition,
+		Status: corev1.ConditionFalse,
+	}
+	if sveltosCluster.Status.Ready {
+		ready.Status = corev1.ConditionTrue
+	}
+	if sveltosCluster.Status.FailureMessage != nil {
+		ready.Message = *sveltosCluster.Status.FailureMessage
+	}
+
+	connected := ClusterCondition{
+		Type:   sveltosClusterConnectedCondition,
+		Status: corev1.ConditionUnknown,
+	}
+	switch sveltosCluster.Status.ConnectionStatus {
+	case libsveltosv1beta1.ConnectionHealthy:
+		connected.Status = corev1.ConditionTrue
+	case libsveltosv1beta1.ConnectionDown:
+		connected.Status = corev1.ConditionFalse
+		connected.Reason = string(libsveltosv1beta1.ConnectionDown)
+		connected.Message = fmt.Sprintf("%d consecutive connection failures", sveltosCluster.Status.ConnectionFailures)
+	}
+
+	return []ClusterCondition{ready, connected}
+}
+
+// examineClusterConditions returns the messages of all v1beta1 conditions with status False
 func examineClusterConditions(cluster *clusterv1.Cluster) *string {
 	if cluster == nil {
 		return nil
@@ -30,9 +118,10 @@ func examineClusterConditions(cluster *clusterv1.Cluster) *string {
 
 	message := ""
 
-	for i := range cluster.Status.Conditions {
-		c := cluster.Status.Conditions[i]
-		if c.Status == corev1.ConditionFalse {
+	conditions := getCAPIClusterConditions(cluster)
+	for i := range conditions {
+		c := &conditions[i]
+		if !c.V1Beta2 && c.Status == corev1.ConditionFalse {
 			message = fmt.Sprintf("%s\n%s", message, c.Message)
 		}
 	}
@@ -43,3 +132,23 @@ func examineClusterConditions(cluster *clusterv1.Cluster) *string {
 
 	return nil
 }
+
+// hasMatchingCondition returns true if a condition matches both conditionType and conditionStatus.
+// An empty filter matches any value.
+func hasMatchingCondition(conditions []ClusterCondition, conditionType, conditionStatus string) bool {
+	if conditionType == "" && conditionStatus == "" {
+		return true
+	}
+
+	for i := range conditions {
+		if conditionType != "" && conditions[i].Type != conditionType {
+			continue
+		}
+		if conditionStatus != "" && string(conditions[i].Status) != conditionStatus {
+			continue
+		}
+		return true
+	}
+
+	return false
+}